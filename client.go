@@ -3,6 +3,7 @@ package goa
 import (
 	"bytes"
 	"crypto/rand"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -13,9 +14,11 @@ import (
 	"net/http/httputil"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 type (
@@ -36,6 +39,19 @@ type (
 		UserAgent string
 		// Dump indicates whether to dump request response.
 		Dump bool
+		// TLSCertFile is the path to the client certificate used for mutual TLS
+		// authentication, TLSKeyFile the path to the corresponding key.
+		TLSCertFile string
+		// TLSKeyFile is the path to the key of the client certificate.
+		TLSKeyFile string
+		// TLSCAFile is the path to the PEM bundle of the certificate authorities used to
+		// verify the server certificate. The system roots are used if empty.
+		TLSCAFile string
+
+		// tlsOnce makes sure the TLS files are loaded once.
+		tlsOnce sync.Once
+		// tlsErr is the error that occurred while loading the TLS files if any.
+		tlsErr error
 	}
 
 	// Signer is the common interface implemented by all signers.
@@ -55,6 +71,22 @@ type (
 		Password string
 	}
 
+	// APIKeySigner implements API key auth.
+	APIKeySigner struct {
+		// In is the location of the API key, either "header" or "query".
+		// The default is "header".
+		In string
+		// Name is the name of the HTTP header or query string parameter that contains the
+		// API key.
+		Name string
+		// Format represents the format used to render the API key.
+		// The default is "%s"
+		Format string
+
+		// key stores the actual API key.
+		key string
+	}
+
 	// JWTSigner implements JSON Web Token auth.
 	JWTSigner struct {
 		// Header is the name of the HTTP header which contains the JWT.
@@ -105,9 +137,31 @@ func NewClient() *Client {
 	}
 }
 
-// Do wraps the underlying http client Do method and adds logging.
+// Do wraps the underlying http client Do method, signs the request using the client signers and
+// adds logging. If the request context contains a span - see Trace and WithSpan - then Do
+// propagates it by setting the trace context headers.
+//
+// The TLS files set in TLSCertFile, TLSKeyFile and TLSCAFile are loaded on the first call.
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.DoSigned(req, c.Signers...)
+}
+
+// DoSigned is like Do but signs the request using the given signers instead of the client
+// signers. The generated clients use it to sign the requests made to actions that require a
+// security scheme with the signer of the scheme.
+func (c *Client) DoSigned(req *http.Request, signers ...Signer) (*http.Response, error) {
+	if c.tlsOnce.Do(c.loadTLS); c.tlsErr != nil {
+		return nil, c.tlsErr
+	}
 	req.Header.Set("User-Agent", c.UserAgent)
+	if span := ContextSpan(req.Context()); span != nil {
+		span.Inject(req.Header)
+	}
+	for _, s := range signers {
+		if err := s.Sign(req); err != nil {
+			return nil, err
+		}
+	}
 	var reqBody []byte
 	startedAt := time.Now()
 	id := shortID()
@@ -129,6 +183,90 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 	return resp, err
 }
 
+// UseTLSConfig makes the client use the given TLS configuration, e.g. to present a client
+// certificate or to trust custom certificate authorities. See also NewClientTLSConfig.
+// The settings of the client transport, or of http.DefaultTransport if the client does not
+// use a *http.Transport, are kept.
+func (c *Client) UseTLSConfig(config *tls.Config) {
+	var hc http.Client
+	if c.Client != nil {
+		hc = *c.Client
+	}
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	t, ok := hc.Transport.(*http.Transport)
+	if !ok {
+		t, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if ok {
+		transport = &http.Transport{
+			Proxy:                 t.Proxy,
+			DialContext:           t.DialContext,
+			Dial:                  t.Dial,
+			DialTLS:               t.DialTLS,
+			DisableKeepAlives:     t.DisableKeepAlives,
+			DisableCompression:    t.DisableCompression,
+			MaxIdleConns:          t.MaxIdleConns,
+			MaxIdleConnsPerHost:   t.MaxIdleConnsPerHost,
+			IdleConnTimeout:       t.IdleConnTimeout,
+			ResponseHeaderTimeout: t.ResponseHeaderTimeout,
+			TLSHandshakeTimeout:   t.TLSHandshakeTimeout,
+			ExpectContinueTimeout: t.ExpectContinueTimeout,
+		}
+	}
+	transport.TLSClientConfig = config
+	hc.Transport = transport
+	c.Client = &hc
+}
+
+// NewClientTLSConfig returns a TLS configuration that presents the client certificate in the
+// given certificate and key files and verifies the server certificate using the certificate
+// authorities in the given PEM bundle. The certificate is not loaded if certFile is empty and
+// the system roots are used if caFile is empty.
+func NewClientTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pool, err := LoadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// loadTLS configures the client TLS using the TLS files if any.
+func (c *Client) loadTLS() {
+	if c.TLSCertFile == "" && c.TLSCAFile == "" {
+		return
+	}
+	config, err := NewClientTLSConfig(c.TLSCertFile, c.TLSKeyFile, c.TLSCAFile)
+	if err != nil {
+		c.tlsErr = err
+		return
+	}
+	c.UseTLSConfig(config)
+}
+
+// RegisterSignerFlags registers the command line flags of the given signer prefixing their names
+// with the given prefix, e.g. "--key" becomes "--admin-key" with the prefix "admin". This makes it
+// possible for a client tool to use more than one signer of the same type.
+func RegisterSignerFlags(app *cobra.Command, signer Signer, prefix string) {
+	var tmp cobra.Command
+	signer.RegisterFlags(&tmp)
+	tmp.Flags().VisitAll(func(f *pflag.Flag) {
+		f.Name = prefix + "-" + f.Name
+		f.Shorthand = ""
+		app.Flags().AddFlag(f)
+	})
+}
+
 // Sign adds the basic auth header to the request.
 func (s *BasicSigner) Sign(req *http.Request) error {
 	if s.Username != "" && s.Password != "" {
@@ -143,8 +281,36 @@ func (s *BasicSigner) RegisterFlags(app *cobra.Command) {
 	app.Flags().StringVar(&s.Password, "pass", "", "Basic Auth password")
 }
 
+// Sign adds the API key header or query string parameter.
+func (s *APIKeySigner) Sign(req *http.Request) error {
+	if s.key == "" {
+		return nil
+	}
+	format := s.Format
+	if format == "" {
+		format = "%s"
+	}
+	key := fmt.Sprintf(format, s.key)
+	if s.In == "query" {
+		values := req.URL.Query()
+		values.Set(s.Name, key)
+		req.URL.RawQuery = values.Encode()
+		return nil
+	}
+	req.Header.Set(s.Name, key)
+	return nil
+}
+
+// RegisterFlags adds the "--key" flag to the client tool.
+func (s *APIKeySigner) RegisterFlags(app *cobra.Command) {
+	app.Flags().StringVar(&s.key, "key", "", "API key")
+}
+
 // Sign adds the JWT auth header.
 func (s *JWTSigner) Sign(req *http.Request) error {
+	if s.token == "" {
+		return nil
+	}
 	header := s.Header
 	if header == "" {
 		header = "Authorization"
@@ -162,8 +328,12 @@ func (s *JWTSigner) RegisterFlags(app *cobra.Command) {
 	app.Flags().StringVar(&s.token, "jwt", "", "JSON web token")
 }
 
-// Sign refreshes the access token if needed and adds the OAuth header.
+// Sign refreshes the access token if needed and adds the OAuth header. It does nothing if no
+// refresh token was provided.
 func (s *OAuth2Signer) Sign(req *http.Request) error {
+	if s.RefreshToken == "" {
+		return nil
+	}
 	if s.expiresAt.Before(time.Now()) {
 		if err := s.Refresh(); err != nil {
 			return fmt.Errorf("failed to refresh OAuth token: %s", err)
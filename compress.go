@@ -0,0 +1,232 @@
+package goa
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// Compress returns a middleware that compresses the response bodies using gzip or deflate
+// depending on the request Accept-Encoding header. Responses whose body is smaller than minSize
+// bytes are not compressed. contentTypes lists the media types of the responses that may be
+// compressed, e.g. "application/json" or "text/*", any response may be compressed if the list is
+// empty. The response Length field keeps recording the size of the uncompressed body while
+// CompressedLength records the number of bytes actually written.
+//
+// Note that request bodies sent with the "gzip" or "deflate" Content-Encoding are decompressed by
+// Service.DecodeRequest whether the middleware is used or not.
+func Compress(minSize int, contentTypes ...string) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			resp := Response(ctx)
+			if resp == nil {
+				return h(ctx, rw, req)
+			}
+			resp.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"))
+			if encoding == "" {
+				return h(ctx, rw, req)
+			}
+			cw := &compressWriter{
+				ResponseWriter: resp.ResponseWriter,
+				resp:           resp,
+				encoding:       encoding,
+				minSize:        minSize,
+				contentTypes:   contentTypes,
+			}
+			orig := resp.SwitchWriter(cw)
+			defer func() {
+				cw.Close()
+				resp.SwitchWriter(orig)
+			}()
+			return h(ctx, rw, req)
+		}
+	}
+}
+
+// compressWriter is the response writer used by the Compress middleware. It buffers the response
+// body until it is big enough to decide whether it should be compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	resp         *ResponseData
+	encoding     string
+	minSize      int
+	contentTypes []string
+
+	status  int
+	buf     []byte
+	decided bool
+	w       io.WriteCloser
+}
+
+// WriteHeader records the status code, the header is written once the body is written.
+func (c *compressWriter) WriteHeader(status int) {
+	if c.decided {
+		c.ResponseWriter.WriteHeader(status)
+		return
+	}
+	c.status = status
+}
+
+// Write compresses the data if needed and writes it to the underlying writer.
+func (c *compressWriter) Write(b []byte) (int, error) {
+	if !c.decided {
+		c.buf = append(c.buf, b...)
+		if len(c.buf) < c.minSize {
+			return len(b), nil
+		}
+		if err := c.decide(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if c.w != nil {
+		return c.w.Write(b)
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+// Close flushes the buffered data and the compressor.
+func (c *compressWriter) Close() error {
+	if !c.decided {
+		if c.status == 0 && len(c.buf) == 0 {
+			// Nothing was written, let the error handler write the response
+			return nil
+		}
+		if err := c.decide(); err != nil {
+			return err
+		}
+	}
+	if c.w != nil {
+		return c.w.Close()
+	}
+	return nil
+}
+
+// decide writes the response header and the buffered data, compressing it if the response is
+// eligible.
+func (c *compressWriter) decide() error {
+	c.decided = true
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	if c.compressible() {
+		header := c.Header()
+		header.Set("Content-Encoding", c.encoding)
+		header.Del("Content-Length")
+		out := &compressedLengthWriter{w: c.ResponseWriter, resp: c.resp}
+		if c.encoding == "gzip" {
+			c.w = gzip.NewWriter(out)
+		} else {
+			c.w = zlib.NewWriter(out)
+		}
+	}
+	c.ResponseWriter.WriteHeader(c.status)
+	buf := c.buf
+	c.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if c.w != nil {
+		_, err := c.w.Write(buf)
+		return err
+	}
+	_, err := c.ResponseWriter.Write(buf)
+	return err
+}
+
+// compressible returns true if the response body should be compressed.
+func (c *compressWriter) compressible() bool {
+	if len(c.buf) < c.minSize || len(c.buf) == 0 {
+		return false
+	}
+	if c.status == http.StatusNoContent || c.status == http.StatusNotModified {
+		return false
+	}
+	if c.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+	if len(c.contentTypes) == 0 {
+		return true
+	}
+	ct := c.Header().Get("Content-Type")
+	if ct == "" {
+		ct = http.DetectContentType(c.buf)
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	for _, t := range c.contentTypes {
+		if t == mediaType {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, t[:len(t)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressedLengthWriter records the number of compressed bytes written to the response.
+type compressedLengthWriter struct {
+	w    io.Writer
+	resp *ResponseData
+}
+
+// Write records the amount of data written and calls the underlying writer.
+func (c *compressedLengthWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.resp.CompressedLength += n
+	return n, err
+}
+
+// negotiateEncoding returns the content coding to use given the value of the Accept-Encoding
+// header, "gzip" or "deflate". It returns an empty string if neither is acceptable.
+func negotiateEncoding(accept string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		coding, q := parseQuality(part)
+		if coding == "x-gzip" {
+			coding = "gzip"
+		}
+		qualities[coding] = q
+	}
+	quality := func(coding string) float64 {
+		if q, ok := qualities[coding]; ok {
+			return q
+		}
+		return qualities["*"]
+	}
+	gz, deflate := quality("gzip"), quality("deflate")
+	switch {
+	case gz > 0 && gz >= deflate:
+		return "gzip"
+	case deflate > 0:
+		return "deflate"
+	}
+	return ""
+}
+
+// parseQuality splits a header value element such as "gzip;q=0.8" into its lower case value and
+// quality. The quality defaults to 1.
+func parseQuality(s string) (string, float64) {
+	q := 1.0
+	parts := strings.Split(s, ";")
+	value := strings.ToLower(strings.TrimSpace(parts[0]))
+	for _, p := range parts[1:] {
+		p = strings.TrimSpace(p)
+		if strings.HasPrefix(p, "q=") {
+			if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+				q = v
+			}
+		}
+	}
+	return value, q
+}
@@ -0,0 +1,185 @@
+package goa_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("Compress", func() {
+	const minSize = 100
+	var contentTypes []string
+	var contentType string
+	var body string
+	var req *http.Request
+	var rw *httptest.ResponseRecorder
+	var resp *goa.ResponseData
+
+	BeforeEach(func() {
+		contentTypes = nil
+		contentType = "application/json"
+		body = `{"data":"` + strings.Repeat("goa", 100) + `"}`
+		var err error
+		req, err = http.NewRequest("GET", "/bottles", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+		rw = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		ctx := goa.NewContext(context.Background(), rw, req, nil)
+		resp = goa.Response(ctx)
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			rw.Header().Set("Content-Type", contentType)
+			rw.WriteHeader(200)
+			rw.Write([]byte(body))
+			return nil
+		}
+		Ω(goa.Compress(minSize, contentTypes...)(h)(ctx, resp, req)).ShouldNot(HaveOccurred())
+	})
+
+	It("compresses the response with gzip", func() {
+		Ω(rw.Code).Should(Equal(200))
+		Ω(rw.Header().Get("Content-Encoding")).Should(Equal("gzip"))
+		Ω(rw.Header().Get("Vary")).Should(Equal("Accept-Encoding"))
+		gz, err := gzip.NewReader(rw.Body)
+		Ω(err).ShouldNot(HaveOccurred())
+		b, err := ioutil.ReadAll(gz)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(b)).Should(Equal(body))
+	})
+
+	It("records the uncompressed and compressed lengths", func() {
+		Ω(resp.Length).Should(Equal(len(body)))
+		Ω(resp.CompressedLength).Should(Equal(rw.Body.Len()))
+		Ω(resp.CompressedLength).Should(BeNumerically("<", len(body)))
+	})
+
+	Context("with a client that prefers deflate", func() {
+		BeforeEach(func() {
+			req.Header.Set("Accept-Encoding", "gzip;q=0.5, deflate")
+		})
+
+		It("compresses the response with deflate", func() {
+			Ω(rw.Header().Get("Content-Encoding")).Should(Equal("deflate"))
+			zr, err := zlib.NewReader(rw.Body)
+			Ω(err).ShouldNot(HaveOccurred())
+			b, err := ioutil.ReadAll(zr)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(b)).Should(Equal(body))
+		})
+	})
+
+	Context("with a client that does not accept compressed responses", func() {
+		BeforeEach(func() {
+			req.Header.Set("Accept-Encoding", "gzip;q=0, identity")
+		})
+
+		It("does not compress the response", func() {
+			Ω(rw.Header().Get("Content-Encoding")).Should(BeEmpty())
+			Ω(rw.Body.String()).Should(Equal(body))
+		})
+	})
+
+	Context("with a small response", func() {
+		BeforeEach(func() {
+			body = `{"data":"goa"}`
+		})
+
+		It("does not compress the response", func() {
+			Ω(rw.Code).Should(Equal(200))
+			Ω(rw.Header().Get("Content-Encoding")).Should(BeEmpty())
+			Ω(rw.Body.String()).Should(Equal(body))
+			Ω(resp.CompressedLength).Should(Equal(0))
+		})
+	})
+
+	Context("with a content type that is not allowed", func() {
+		BeforeEach(func() {
+			contentTypes = []string{"application/json", "text/*"}
+			contentType = "image/png"
+		})
+
+		It("does not compress the response", func() {
+			Ω(rw.Header().Get("Content-Encoding")).Should(BeEmpty())
+			Ω(rw.Body.String()).Should(Equal(body))
+		})
+	})
+
+	Context("with a content type matching a wildcard", func() {
+		BeforeEach(func() {
+			contentTypes = []string{"text/*"}
+			contentType = "text/plain; charset=utf-8"
+		})
+
+		It("compresses the response", func() {
+			Ω(rw.Header().Get("Content-Encoding")).Should(Equal("gzip"))
+		})
+	})
+})
+
+var _ = Describe("DecodeRequest", func() {
+	var service *goa.Service
+	var req *http.Request
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		service.Decoder(goa.NewJSONDecoder, "application/json")
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(`{"name":"goa"}`))
+		gz.Close()
+		var err error
+		req, err = http.NewRequest("POST", "/bottles", &buf)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+	})
+
+	It("decompresses gzip request bodies", func() {
+		var payload map[string]string
+		Ω(service.DecodeRequest(req, &payload)).ShouldNot(HaveOccurred())
+		Ω(payload).Should(Equal(map[string]string{"name": "goa"}))
+	})
+
+	Context("with a body that decompresses beyond the maximum body size", func() {
+		var handled error
+
+		BeforeEach(func() {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			gz.Write([]byte(`{"name":"` + strings.Repeat("a", 1<<20) + `"}`))
+			gz.Close()
+			Ω(buf.Len()).Should(BeNumerically("<", 4096))
+			req, _ = http.NewRequest("POST", "/bottles", &buf)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Encoding", "gzip")
+			service.MaxBodySize = 4096
+			service.ErrorHandler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request, err error) {
+				handled = err
+			}
+		})
+
+		It("fails with a request body too large error", func() {
+			ctrl := service.NewController("bottle")
+			unm := func(ctx context.Context, req *http.Request) error {
+				var payload map[string]string
+				return service.DecodeRequest(req, &payload)
+			}
+			h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error { return nil }
+			ctrl.MuxHandler("create", h, unm)(httptest.NewRecorder(), req, url.Values{})
+			Ω(handled).Should(BeAssignableToTypeOf(&goa.TypedError{}))
+			Ω(handled.(*goa.TypedError).ID).Should(Equal(goa.ErrorID(goa.ErrRequestBodyTooLarge)))
+		})
+	})
+})
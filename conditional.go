@@ -0,0 +1,183 @@
+package goa
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type (
+	// ETagger is implemented by the media types that define an entity tag. The code generated
+	// by goagen implements the interface for the media types whose design use ETag.
+	ETagger interface {
+		// ETag returns the entity tag including the quotes, e.g. `"xyzzy"`. It returns an
+		// empty string if the entity tag is unknown.
+		ETag() string
+	}
+
+	// LastModifier is implemented by the media types that define a last modification time.
+	// The code generated by goagen implements the interface for the media types whose design
+	// use LastModified.
+	LastModifier interface {
+		// LastModified returns the last modification time, the zero value if unknown.
+		LastModified() time.Time
+	}
+)
+
+// NewETag returns the strong entity tag built from the given value, e.g. `"42"` for 42. Pointers
+// are dereferenced, the entity tag of a nil value is the empty string. Values containing
+// characters that are not allowed in entity tags are hashed.
+func NewETag(v interface{}) string {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return ""
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() {
+		return ""
+	}
+	var s string
+	if t, ok := val.Interface().(time.Time); ok {
+		s = t.UTC().Format(time.RFC3339Nano)
+	} else {
+		s = fmt.Sprintf("%v", val.Interface())
+	}
+	for _, c := range []byte(s) {
+		// RFC 7232 section 2.3: etagc = %x21 / %x23-7E / obs-text
+		if c < 0x21 || c == '"' || c == ',' || c == 0x7F {
+			sum := sha1.Sum([]byte(s))
+			s = hex.EncodeToString(sum[:])
+			break
+		}
+	}
+	return `"` + s + `"`
+}
+
+// HashETag returns the strong entity tag computed by hashing the JSON representation of the given
+// value. It returns an empty string if the value cannot be serialized.
+func HashETag(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha1.Sum(b)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// EvaluatePreconditions evaluates the request conditions against the current entity tag and last
+// modification time of the target resource as described in RFC 7232 section 6. An empty etag and
+// a zero modified value mean that the resource does not exist. EvaluatePreconditions returns
+// http.StatusPreconditionFailed if the If-Match or If-Unmodified-Since condition does not hold,
+// http.StatusNotModified if the If-None-Match or If-Modified-Since condition does not hold for a
+// GET or HEAD request and 0 if the request should be handled.
+func EvaluatePreconditions(req *http.Request, etag string, modified time.Time) int {
+	exists := etag != "" || !modified.IsZero()
+	safe := req.Method == "GET" || req.Method == "HEAD"
+	if ifMatch := req.Header.Get("If-Match"); ifMatch != "" {
+		if !matchETag(ifMatch, etag, exists, true) {
+			return http.StatusPreconditionFailed
+		}
+	} else if since, ok := headerTime(req, "If-Unmodified-Since"); ok && !modified.IsZero() {
+		if modified.Truncate(time.Second).After(since) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if matchETag(ifNoneMatch, etag, exists, false) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if since, ok := headerTime(req, "If-Modified-Since"); ok && safe && !modified.IsZero() {
+		if !modified.Truncate(time.Second).After(since) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// CheckPreconditions evaluates the conditions of the request with the given context against the
+// current state of the target resource, see EvaluatePreconditions. The entity tag and last
+// modification time are computed from current if it implements ETagger and LastModifier
+// respectively, current may be nil if the resource does not exist. CheckPreconditions returns a
+// TypedError with id ErrPreconditionFailed if a condition does not hold, nil otherwise. Actions
+// that modify resources should call it before making any change.
+func CheckPreconditions(ctx context.Context, current interface{}) error {
+	req := Request(ctx)
+	if req == nil {
+		return nil
+	}
+	etag, modified := validators(current)
+	if EvaluatePreconditions(req.Request, etag, modified) == http.StatusPreconditionFailed {
+		return preconditionFailed()
+	}
+	return nil
+}
+
+// validators returns the entity tag and last modification time of v.
+func validators(v interface{}) (etag string, modified time.Time) {
+	if t, ok := v.(ETagger); ok {
+		etag = t.ETag()
+	}
+	if m, ok := v.(LastModifier); ok {
+		modified = m.LastModified()
+	}
+	return
+}
+
+// preconditionFailed returns the error produced when a request condition does not hold.
+func preconditionFailed() error {
+	return &TypedError{ID: ErrPreconditionFailed, Mesg: "request conditions do not hold, the resource has changed"}
+}
+
+// matchETag returns true if the value of a If-Match or If-None-Match header matches the given
+// entity tag. The comparison is strong for If-Match and weak for If-None-Match.
+func matchETag(header, etag string, exists, strong bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return exists
+	}
+	if etag == "" {
+		return false
+	}
+	weak := strings.HasPrefix(etag, "W/")
+	if strong && weak {
+		return false
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			if strong {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if tag == opaque {
+			return true
+		}
+	}
+	return false
+}
+
+// headerTime parses the HTTP date contained in the given request header.
+func headerTime(req *http.Request, name string) (time.Time, bool) {
+	h := req.Header.Get(name)
+	if h == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(h)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
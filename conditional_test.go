@@ -0,0 +1,290 @@
+package goa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+// taggedBottle is a media type that implements goa.ETagger and goa.LastModifier.
+type taggedBottle struct {
+	Name     string    `json:"name"`
+	Revision int       `json:"revision"`
+	Updated  time.Time `json:"updated"`
+}
+
+func (b *taggedBottle) ETag() string {
+	if b == nil {
+		return ""
+	}
+	return goa.NewETag(b.Revision)
+}
+
+func (b *taggedBottle) LastModified() time.Time {
+	if b == nil {
+		return time.Time{}
+	}
+	return b.Updated
+}
+
+var _ = Describe("NewETag", func() {
+	It("quotes the value", func() {
+		Ω(goa.NewETag(42)).Should(Equal(`"42"`))
+		Ω(goa.NewETag("xyzzy")).Should(Equal(`"xyzzy"`))
+	})
+
+	It("dereferences pointers", func() {
+		rev := "r1"
+		Ω(goa.NewETag(&rev)).Should(Equal(`"r1"`))
+		Ω(goa.NewETag((*string)(nil))).Should(BeEmpty())
+	})
+
+	It("hashes values that contain invalid characters", func() {
+		etag := goa.NewETag(`a "quoted", spaced value`)
+		Ω(etag).Should(MatchRegexp(`^"[0-9a-f]{40}"$`))
+	})
+})
+
+var _ = Describe("HashETag", func() {
+	It("computes the same entity tag for equal values", func() {
+		etag := goa.HashETag(&bottle{Name: "goa"})
+		Ω(etag).Should(MatchRegexp(`^"[0-9a-f]{40}"$`))
+		Ω(goa.HashETag(&bottle{Name: "goa"})).Should(Equal(etag))
+		Ω(goa.HashETag(&bottle{Name: "goo"})).ShouldNot(Equal(etag))
+	})
+})
+
+var _ = Describe("EvaluatePreconditions", func() {
+	const etag = `"2"`
+	var modified = time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC)
+	var method string
+	var header http.Header
+	var status int
+
+	BeforeEach(func() {
+		method = "GET"
+		header = make(http.Header)
+	})
+
+	JustBeforeEach(func() {
+		req, err := http.NewRequest(method, "/bottles/1", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.Header = header
+		status = goa.EvaluatePreconditions(req, etag, modified)
+	})
+
+	It("lets unconditional requests through", func() {
+		Ω(status).Should(Equal(0))
+	})
+
+	Context("with a matching If-None-Match header", func() {
+		BeforeEach(func() {
+			header.Set("If-None-Match", `"1", W/"2"`)
+		})
+
+		It("returns not modified", func() {
+			Ω(status).Should(Equal(http.StatusNotModified))
+		})
+
+		Context("on a PUT request", func() {
+			BeforeEach(func() {
+				method = "PUT"
+			})
+
+			It("returns precondition failed", func() {
+				Ω(status).Should(Equal(http.StatusPreconditionFailed))
+			})
+		})
+	})
+
+	Context("with a If-Modified-Since header", func() {
+		BeforeEach(func() {
+			header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+		})
+
+		It("returns not modified if the resource did not change", func() {
+			Ω(status).Should(Equal(http.StatusNotModified))
+		})
+
+		Context("and a If-None-Match header that does not match", func() {
+			BeforeEach(func() {
+				header.Set("If-None-Match", `"1"`)
+			})
+
+			It("ignores the If-Modified-Since header", func() {
+				Ω(status).Should(Equal(0))
+			})
+		})
+	})
+
+	Context("with a If-Match header", func() {
+		BeforeEach(func() {
+			method = "PUT"
+			header.Set("If-Match", `"1"`)
+		})
+
+		It("returns precondition failed if the entity tag does not match", func() {
+			Ω(status).Should(Equal(http.StatusPreconditionFailed))
+		})
+
+		Context("that matches", func() {
+			BeforeEach(func() {
+				header.Set("If-Match", etag)
+			})
+
+			It("lets the request through", func() {
+				Ω(status).Should(Equal(0))
+			})
+		})
+
+		Context("with a weak entity tag", func() {
+			BeforeEach(func() {
+				header.Set("If-Match", `W/"2"`)
+			})
+
+			It("uses the strong comparison", func() {
+				Ω(status).Should(Equal(http.StatusPreconditionFailed))
+			})
+		})
+	})
+
+	Context("with a If-Unmodified-Since header older than the resource", func() {
+		BeforeEach(func() {
+			method = "DELETE"
+			header.Set("If-Unmodified-Since", modified.Add(-time.Hour).Format(http.TimeFormat))
+		})
+
+		It("returns precondition failed", func() {
+			Ω(status).Should(Equal(http.StatusPreconditionFailed))
+		})
+	})
+})
+
+var _ = Describe("SendConditional", func() {
+	var method string
+	var header http.Header
+	var body *taggedBottle
+	var rw *httptest.ResponseRecorder
+	var err error
+
+	BeforeEach(func() {
+		method = "GET"
+		header = make(http.Header)
+		body = &taggedBottle{
+			Name:     "goa",
+			Revision: 2,
+			Updated:  time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC),
+		}
+		rw = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		service := goa.New("test")
+		service.Encoder(goa.NewJSONEncoder, "application/json")
+		req, e := http.NewRequest(method, "/bottles/1", nil)
+		Ω(e).ShouldNot(HaveOccurred())
+		req.Header = header
+		ctrl := service.NewController("test")
+		ctx := goa.NewContext(ctrl.Context, rw, req, nil)
+		err = goa.Response(ctx).SendConditional(ctx, 200, body)
+	})
+
+	It("sets the validator headers", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(rw.Code).Should(Equal(200))
+		Ω(rw.Header().Get("ETag")).Should(Equal(`"2"`))
+		Ω(rw.Header().Get("Last-Modified")).Should(Equal("Sat, 02 Jan 2016 15:04:05 GMT"))
+		Ω(rw.Body.String()).ShouldNot(BeEmpty())
+	})
+
+	Context("with a request whose If-None-Match header matches", func() {
+		BeforeEach(func() {
+			header.Set("If-None-Match", `"2"`)
+		})
+
+		It("responds with status 304 and no body", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rw.Code).Should(Equal(304))
+			Ω(rw.Header().Get("ETag")).Should(Equal(`"2"`))
+			Ω(rw.Body.Len()).Should(Equal(0))
+		})
+	})
+
+	Context("with a request whose If-Match header does not match", func() {
+		BeforeEach(func() {
+			header.Set("If-Match", `"1"`)
+		})
+
+		It("returns a precondition failed error", func() {
+			Ω(err).Should(BeAssignableToTypeOf(&goa.TypedError{}))
+			Ω(err.(*goa.TypedError).ID).Should(Equal(goa.ErrorID(goa.ErrPreconditionFailed)))
+			Ω(rw.Body.Len()).Should(Equal(0))
+		})
+	})
+
+	Context("with a PUT request whose If-Match header matches the state prior to the update", func() {
+		BeforeEach(func() {
+			method = "PUT"
+			header.Set("If-Match", `"1"`)
+		})
+
+		It("sends the response", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rw.Code).Should(Equal(200))
+			Ω(rw.Header().Get("ETag")).Should(Equal(`"2"`))
+			Ω(rw.Body.String()).ShouldNot(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("CheckPreconditions", func() {
+	var header http.Header
+	var current *taggedBottle
+	var err error
+
+	BeforeEach(func() {
+		header = make(http.Header)
+		header.Set("If-Match", `"2"`)
+		current = &taggedBottle{Name: "goa", Revision: 2}
+	})
+
+	JustBeforeEach(func() {
+		req, e := http.NewRequest("PUT", "/bottles/1", nil)
+		Ω(e).ShouldNot(HaveOccurred())
+		req.Header = header
+		ctx := goa.NewContext(context.Background(), httptest.NewRecorder(), req, nil)
+		err = goa.CheckPreconditions(ctx, current)
+	})
+
+	It("accepts requests whose conditions hold", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	Context("with a resource that changed", func() {
+		BeforeEach(func() {
+			current.Revision = 3
+		})
+
+		It("returns a precondition failed error", func() {
+			Ω(err).Should(BeAssignableToTypeOf(&goa.TypedError{}))
+			Ω(err.(*goa.TypedError).ID).Should(Equal(goa.ErrorID(goa.ErrPreconditionFailed)))
+			Ω(err.(*goa.TypedError).ID.Status()).Should(Equal(412))
+		})
+	})
+
+	Context("with a resource that does not exist", func() {
+		BeforeEach(func() {
+			current = nil
+			header.Set("If-Match", "*")
+		})
+
+		It("returns a precondition failed error", func() {
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
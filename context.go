@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"strconv"
 
 	"golang.org/x/net/context"
 )
@@ -17,6 +16,12 @@ const (
 	serviceKey
 	logKey
 	logContextKey
+	reqIDKey
+	scopesKey
+	logLevelKey
+	actionKey
+	spanKey
+	credentialKey
 )
 
 type (
@@ -38,6 +43,9 @@ type (
 		Status int
 		// Length is the response body length
 		Length int
+		// CompressedLength is the length of the response body after compression if the
+		// response was compressed by the Compress middleware, zero otherwise.
+		CompressedLength int
 	}
 
 	// key is the type used to store internal values in the context.
@@ -87,6 +95,22 @@ func RequestService(ctx context.Context) *Service {
 	return nil
 }
 
+// ContextController extracts the controller name from the given context.
+func ContextController(ctx context.Context) string {
+	if c := ctx.Value("ctrl"); c != nil {
+		return c.(string)
+	}
+	return "<unknown>"
+}
+
+// ContextAction extracts the action name from the given context.
+func ContextAction(ctx context.Context) string {
+	if a := ctx.Value(actionKey); a != nil {
+		return a.(string)
+	}
+	return "<unknown>"
+}
+
 // SwitchWriter overrides the underlying response writer. It returns the response
 // writer that was previously set.
 func (r *ResponseData) SwitchWriter(rw http.ResponseWriter) http.ResponseWriter {
@@ -101,10 +125,50 @@ func (r *ResponseData) Written() bool {
 }
 
 // Send serializes the given body matching the request Accept header against the service
-// encoders. It uses the default service encoder if no match is found.
+// encoders, see Service.Negotiate. The Content-Type set prior to calling Send is used if the
+// request accepts it. Send falls back to that Content-Type or to the default service encoder if
+// no match is found, use RequireAcceptable to respond with 406 Not Acceptable instead. The
+// negotiated media type is set as the response Content-Type.
 func (r *ResponseData) Send(ctx context.Context, code int, body interface{}) error {
+	service := RequestService(ctx)
+	contentType, p := service.negotiateResponse(ctx)
 	r.WriteHeader(code)
-	return RequestService(ctx).EncodeResponse(ctx, body)
+	return service.encode(ctx, contentType, p, body)
+}
+
+// SendConditional is like Send but evaluates the request conditions first if the response to a
+// GET or HEAD request is successful, see EvaluatePreconditions. The entity tag and last
+// modification time are computed from body if it implements ETagger and LastModifier respectively
+// and set as the ETag and Last-Modified response headers. SendConditional writes a response with
+// status code 304 and no body if the request If-None-Match or If-Modified-Since condition does not
+// hold. It returns a TypedError with id ErrPreconditionFailed if the If-Match or
+// If-Unmodified-Since condition does not hold so that the controller error handler writes a
+// response with status code 412.
+//
+// The conditions of requests made with other methods are not evaluated as the body describes the
+// state of the resource after the change: actions that modify resources must check the conditions
+// before making any change instead, see CheckPreconditions.
+func (r *ResponseData) SendConditional(ctx context.Context, code int, body interface{}) error {
+	etag, modified := validators(body)
+	if etag != "" {
+		r.Header().Set("ETag", etag)
+	}
+	if !modified.IsZero() {
+		r.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	req := Request(ctx)
+	if req == nil || code < 200 || code > 299 || (req.Method != "GET" && req.Method != "HEAD") {
+		return r.Send(ctx, code, body)
+	}
+	switch EvaluatePreconditions(req.Request, etag, modified) {
+	case http.StatusNotModified:
+		r.Header().Del("Content-Type")
+		r.WriteHeader(http.StatusNotModified)
+		return nil
+	case http.StatusPreconditionFailed:
+		return preconditionFailed()
+	}
+	return r.Send(ctx, code, body)
 }
 
 // BadRequest sends a HTTP response with status code 400 and the given error as body.
@@ -121,7 +185,6 @@ func (r *ResponseData) Bug(ctx context.Context, format string, a ...interface{})
 
 // WriteHeader records the response status code and calls the underlying writer.
 func (r *ResponseData) WriteHeader(status int) {
-	go IncrCounter([]string{"goa", "response", strconv.Itoa(status)}, 1.0)
 	r.Status = status
 	r.ResponseWriter.WriteHeader(status)
 }
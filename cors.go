@@ -0,0 +1,46 @@
+package goa
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// MatchOrigin returns true if the given Origin header value matches the origin specification.
+// The specification may be "*" to match any origin, may contain a single "*" wildcard such as
+// "https://*.domain.com" or must otherwise be identical to the origin.
+func MatchOrigin(origin, spec string) bool {
+	if spec == "*" {
+		return true
+	}
+	if origin == "" {
+		return false
+	}
+	idx := strings.IndexByte(spec, '*')
+	if idx < 0 {
+		return origin == spec
+	}
+	prefix, suffix := spec[:idx], spec[idx+1:]
+	return len(origin) >= len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) &&
+		strings.HasSuffix(origin, suffix)
+}
+
+// IsPreflight returns true if the request is a CORS preflight request, that is an OPTIONS
+// request with the Origin and Access-Control-Request-Method headers set.
+func IsPreflight(req *http.Request) bool {
+	return req.Method == "OPTIONS" &&
+		req.Header.Get("Origin") != "" &&
+		req.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// HandlePreflight returns a handler that writes an empty 200 response. The generated code mounts
+// it on all the resource action paths with the OPTIONS method after wrapping it with the resource
+// CORS handler which takes care of writing the Access-Control-* headers.
+func HandlePreflight() Handler {
+	return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		rw.WriteHeader(http.StatusOK)
+		return nil
+	}
+}
@@ -0,0 +1,82 @@
+package goa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("MatchOrigin", func() {
+	var origin, spec string
+
+	Context("with a catch-all specification", func() {
+		BeforeEach(func() {
+			spec = "*"
+		})
+
+		It("matches any origin", func() {
+			Ω(goa.MatchOrigin("http://goa.design", spec)).Should(BeTrue())
+		})
+	})
+
+	Context("with an exact specification", func() {
+		BeforeEach(func() {
+			spec = "http://goa.design"
+		})
+
+		It("matches the identical origin only", func() {
+			Ω(goa.MatchOrigin("http://goa.design", spec)).Should(BeTrue())
+			Ω(goa.MatchOrigin("https://goa.design", spec)).Should(BeFalse())
+			Ω(goa.MatchOrigin("", spec)).Should(BeFalse())
+		})
+	})
+
+	Context("with a wildcard specification", func() {
+		BeforeEach(func() {
+			spec = "https://*.goa.design"
+			origin = "https://swagger.goa.design"
+		})
+
+		It("matches origins with the same prefix and suffix", func() {
+			Ω(goa.MatchOrigin(origin, spec)).Should(BeTrue())
+			Ω(goa.MatchOrigin("http://swagger.goa.design", spec)).Should(BeFalse())
+			Ω(goa.MatchOrigin("https://swagger.goa.design.evil.com", spec)).Should(BeFalse())
+			Ω(goa.MatchOrigin("https://.goa.design", spec)).Should(BeTrue())
+			Ω(goa.MatchOrigin("https://goa.design", spec)).Should(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("IsPreflight", func() {
+	var req *http.Request
+
+	BeforeEach(func() {
+		var err error
+		req, err = http.NewRequest("OPTIONS", "/foo", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.Header.Set("Origin", "http://goa.design")
+	})
+
+	It("requires the Access-Control-Request-Method header", func() {
+		Ω(goa.IsPreflight(req)).Should(BeFalse())
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		Ω(goa.IsPreflight(req)).Should(BeTrue())
+		req.Method = "GET"
+		Ω(goa.IsPreflight(req)).Should(BeFalse())
+	})
+})
+
+var _ = Describe("HandlePreflight", func() {
+	It("writes an empty 200 response", func() {
+		req, err := http.NewRequest("OPTIONS", "/foo", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rw := httptest.NewRecorder()
+		Ω(goa.HandlePreflight()(context.Background(), rw, req)).ShouldNot(HaveOccurred())
+		Ω(rw.Code).Should(Equal(200))
+		Ω(rw.Body.Len()).Should(Equal(0))
+	})
+})
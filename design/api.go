@@ -73,8 +73,10 @@ var (
 	// must be defined first then the definition created by CollectionOf must execute.
 	GeneratedMediaTypes MediaTypeRoot
 
-	// WildcardRegex is the regular expression used to capture path parameters.
-	WildcardRegex = regexp.MustCompile(`/(?::|\*)([a-zA-Z0-9_]+)`)
+	// WildcardRegex is the regular expression used to capture path parameters. The match
+	// includes the parameter regular expression constraint if any, e.g. "/:id([0-9]+)", see
+	// goa.NewTreeMux.
+	WildcardRegex = regexp.MustCompile(`/(?::|\*)([a-zA-Z0-9_]+)(?:\([^/]*\))?`)
 
 	// DefaultDecoders contains the decoding definitions used when no Consumes DSL is found.
 	DefaultDecoders []*EncodingDefinition
@@ -95,6 +97,9 @@ var (
 		"application/x-cbor":    "github.com/goadesign/encoding/cbor",
 		"application/msgpack":   "github.com/goadesign/encoding/msgpack",
 		"application/x-msgpack": "github.com/goadesign/encoding/msgpack",
+
+		"application/x-www-form-urlencoded": "github.com/goadesign/goa",
+		"multipart/form-data":               "github.com/goadesign/goa",
 	}
 
 	// KnownEncoderFunctions contains the list of encoding encoder and decoder functions known
@@ -110,6 +115,10 @@ var (
 		"application/x-cbor":    {"NewEncoder", "NewDecoder"},
 		"application/msgpack":   {"NewEncoder", "NewDecoder"},
 		"application/x-msgpack": {"NewEncoder", "NewDecoder"},
+
+		// Forms can only be decoded.
+		"application/x-www-form-urlencoded": {"", "NewFormDecoder"},
+		"multipart/form-data":               {"", "NewMultipartDecoder"},
 	}
 
 	// JSONContentTypes list the Content-Type header values that cause goa to encode or decode
@@ -123,6 +132,13 @@ var (
 	// GobContentTypes list the Content-Type header values that cause goa to encode or decode
 	// Gob by default.
 	GobContentTypes = []string{"application/gob", "application/x-gob"}
+
+	// FormContentTypes list the Content-Type header values of url-encoded form requests.
+	FormContentTypes = []string{"application/x-www-form-urlencoded"}
+
+	// MultipartContentTypes list the Content-Type header values of multipart form requests,
+	// the only requests that may upload files.
+	MultipartContentTypes = []string{"multipart/form-data"}
 )
 
 func init() {
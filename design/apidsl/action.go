@@ -2,6 +2,7 @@ package apidsl
 
 import (
 	"fmt"
+	"time"
 
 	"bitbucket.org/pkg/inflect"
 	"github.com/goadesign/goa/design"
@@ -23,6 +24,9 @@ import (
 //			URL("http//cellarapi.com/docs/actions/update")
 //		})
 //		Scheme("http")
+//		Timeout(30 * time.Second)			// Maximum duration allowed to handle requests
+//		MaxBodySize(1 << 20)				// Maximum size of request bodies in bytes
+//		Security(JWT, "api:write")			// Security required by the action, see Security
 //		Routing(
 //			PUT("/:id"),				// Full action path is built by appending "/:id" to parent resource base path
 //			PUT("//orgs/:org/accounts/:id"),	// The // prefix indicates an absolute path
@@ -116,6 +120,69 @@ func PATCH(path string) *design.RouteDefinition {
 	return &design.RouteDefinition{Verb: "PATCH", Path: path}
 }
 
+// Timeout sets the maximum duration allowed for handling requests. The generated code derives the
+// request context from a context with the corresponding deadline and the service error handler
+// writes a response with status 503 if the action does not write a response before the deadline.
+// Timeout can be used inside Action or inside Resource in which case it applies to all the
+// resource actions that do not define their own:
+//
+//	Resource("bottle", func() {
+//		Timeout(10 * time.Second)	// Default timeout for all the resource actions
+//		Action("list", func() {
+//			Timeout(time.Minute)	// Overrides the resource default
+//		})
+//	})
+func Timeout(d time.Duration) {
+	if d <= 0 {
+		dslengine.ReportError("invalid timeout %s, must be greater than 0", d)
+		return
+	}
+	if a, ok := actionDefinition(false); ok {
+		a.Timeout = d
+	} else if r, ok := resourceDefinition(true); ok {
+		r.Timeout = d
+	}
+}
+
+// MaxBodySize sets the maximum size in bytes of the request bodies. The generated code responds
+// with status 413 to requests whose body is larger, see goa.Controller.LimitedMuxHandler. The
+// default is the limit set on the service with the MaxBodySize field if any. MaxBodySize can be
+// used inside Action or inside Resource in which case it applies to all the resource actions that
+// do not define their own:
+//
+//	Resource("bottle", func() {
+//		MaxBodySize(1 << 20)		// Default limit for all the resource actions
+//		Action("upload", func() {
+//			MaxBodySize(100 << 20)	// Overrides the resource default
+//		})
+//	})
+func MaxBodySize(n int64) {
+	if n <= 0 {
+		dslengine.ReportError("invalid maximum body size %d, must be greater than 0", n)
+		return
+	}
+	if a, ok := actionDefinition(false); ok {
+		a.MaxBodySize = n
+	} else if r, ok := resourceDefinition(true); ok {
+		r.MaxBodySize = n
+	}
+}
+
+// RequireIfMatch requires the action requests to carry the If-Match header. The generated code
+// responds with status 428 to requests that do not, see goa.RequireIfMatch. Use the generated
+// context Precondition method to compare the header with the entity tag of the resource prior to
+// modifying it. RequireIfMatch may only be used in PUT, PATCH or DELETE actions:
+//
+//	Action("update", func() {
+//		Routing(PUT("/:bottleID"))
+//		RequireIfMatch()
+//	})
+func RequireIfMatch() {
+	if a, ok := actionDefinition(true); ok {
+		a.RequireIfMatch = true
+	}
+}
+
 // Headers implements the DSL for describing HTTP headers. The DSL syntax is identical to the one
 // of Attribute. Here is an example defining a couple of headers with validations:
 //
@@ -129,7 +196,33 @@ func PATCH(path string) *design.RouteDefinition {
 //
 // Headers can be used inside Action to define the action request headers, Response to define the
 // response headers or Resource to define common request headers to all the resource actions.
-func Headers(dsl func()) {
+//
+// Headers can also be used inside Origin to list the headers authorized by the CORS policy:
+//
+//	Origin("http://swagger.goa.design", func() {
+//		Headers("X-Shared-Secret", "X-Api-Version")
+//	})
+func Headers(params ...interface{}) {
+	if cors, ok := corsDefinition(false); ok {
+		for _, p := range params {
+			h, ok := p.(string)
+			if !ok {
+				dslengine.ReportError("invalid CORS header %#v, must be a string", p)
+				return
+			}
+			cors.Headers = append(cors.Headers, h)
+		}
+		return
+	}
+	if len(params) != 1 {
+		dslengine.ReportError("too many arguments given to Headers")
+		return
+	}
+	dsl, ok := params[0].(func())
+	if !ok {
+		dslengine.ReportError("invalid Headers argument, must be a DSL function")
+		return
+	}
 	if a, ok := actionDefinition(false); ok {
 		headers := newAttribute(a.Parent.MediaType)
 		if dslengine.Execute(dsl, headers) {
@@ -2,6 +2,7 @@ package apidsl_test
 
 import (
 	"strconv"
+	"time"
 
 	. "github.com/goadesign/goa/design"
 	. "github.com/goadesign/goa/design/apidsl"
@@ -91,6 +92,98 @@ var _ = Describe("Action", func() {
 		})
 	})
 
+	Context("with a timeout", func() {
+		const timeout = 10 * time.Second
+
+		BeforeEach(func() {
+			name = "foo"
+			dsl = func() {
+				Routing(GET("/:id"))
+				Timeout(timeout)
+			}
+		})
+
+		It("sets the action timeout", func() {
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			Ω(action).ShouldNot(BeNil())
+			Ω(action.Timeout).Should(Equal(timeout))
+		})
+	})
+
+	Context("with a maximum body size", func() {
+		BeforeEach(func() {
+			name = "foo"
+			dsl = func() {
+				Routing(POST("/"))
+				MaxBodySize(1024)
+			}
+		})
+
+		It("sets the action maximum body size", func() {
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			Ω(action).ShouldNot(BeNil())
+			Ω(action.MaxBodySize).Should(Equal(int64(1024)))
+		})
+	})
+
+	Context("with an invalid maximum body size", func() {
+		BeforeEach(func() {
+			name = "foo"
+			dsl = func() {
+				Routing(POST("/"))
+				MaxBodySize(-1)
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
+
+	Context("with a DSL requiring the If-Match header", func() {
+		BeforeEach(func() {
+			name = "foo"
+			dsl = func() {
+				Routing(PUT("/:id"))
+				RequireIfMatch()
+			}
+		})
+
+		It("sets the action RequireIfMatch field", func() {
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			Ω(action).ShouldNot(BeNil())
+			Ω(action.RequireIfMatch).Should(BeTrue())
+		})
+	})
+
+	Context("with a GET action requiring the If-Match header", func() {
+		BeforeEach(func() {
+			name = "foo"
+			dsl = func() {
+				Routing(GET("/:id"))
+				RequireIfMatch()
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
+
+	Context("with an invalid timeout", func() {
+		BeforeEach(func() {
+			name = "foo"
+			dsl = func() {
+				Routing(GET("/:id"))
+				Timeout(0)
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
+
 	Context("with a name and DSL defining a description, route, headers, payload and responses", func() {
 		const typeName = "typeName"
 		const description = "description"
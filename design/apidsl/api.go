@@ -42,6 +42,11 @@ import (
 //		Produces("application/json", func() {   // Custom encoder
 //			Package("github.com/goadesign/encoding/json")
 //		})
+//		Origin("http://swagger.goa.design", func() { // CORS policy, see Origin
+//			Methods("GET", "POST")
+//		})
+//		Security(JWT, "api:read")		// Security required by all API actions, see Security
+//		Error(1000, "bottle is empty", 409)	// Application error id, see Error
 //		ResponseTemplate("static", func() {	// Response template for use by actions
 //			Description("description")
 //			Status(404)
@@ -98,6 +103,8 @@ func Description(d string) {
 		a.Description = d
 	} else if r, ok := responseDefinition(false); ok {
 		r.Description = d
+	} else if s, ok := securitySchemeDefinition(false); ok {
+		s.Description = d
 	} else if do, ok := docsDefinition(true); ok {
 		do.Description = d
 	}
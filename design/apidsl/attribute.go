@@ -16,7 +16,10 @@ import (
 // attributes may include other attributes. At the basic level an attribute has a name,
 // a type and optionally a default value and validation rules. The type of an attribute can be one of:
 //
-// * The primitive types Boolean, Integer, Number or String.
+// * The primitive types Boolean, Integer, Number, String, DateTime or Any.
+//
+// * The File type for files uploaded with multipart/form-data requests, File may only be used to
+// define the attributes of action payloads. The API must consume "multipart/form-data".
 //
 // * A type defined via the Type function.
 //
@@ -193,8 +196,18 @@ func parseAttributeArgs(baseAttr *design.AttributeDefinition, args ...interface{
 	return dataType, description, dsl
 }
 
-// Header is an alias of Attribute.
+// Header is an alias of Attribute. When used in APIKeySecurity or JWTSecurity, Header sets the name
+// of the HTTP header that contains the API key or the token.
 func Header(name string, args ...interface{}) {
+	if s, ok := securitySchemeDefinition(false); ok {
+		if len(args) > 0 {
+			dslengine.ReportError("too many arguments given to Header")
+			return
+		}
+		s.In = "header"
+		s.Name = name
+		return
+	}
 	Attribute(name, args...)
 }
 
@@ -0,0 +1,76 @@
+package apidsl
+
+import (
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/dslengine"
+)
+
+// Origin defines the CORS policy for a given origin. The origin can use a wildcard prefix or suffix
+// such as "https://*.domain.com" or "*" to match any origin. Origin can be used in API or Resource,
+// resource level policies override API level policies with the same origin. Example:
+//
+//	Origin("http://swagger.goa.design", func() { // Define CORS policy, may be prefixed with "*" wildcard
+//		Headers("X-Shared-Secret")           // One or more authorized headers, use "*" to authorize all
+//		Methods("GET", "POST")               // One or more authorized HTTP methods
+//		Expose("X-Time")                     // One or more headers exposed to clients
+//		MaxAge(600)                          // How long to cache a preflight request response
+//		Credentials()                        // Sets Access-Control-Allow-Credentials header
+//	})
+func Origin(origin string, dsl func()) {
+	cors := &design.CORSDefinition{Origin: origin}
+	if !dslengine.Execute(dsl, cors) {
+		return
+	}
+	if a, ok := apiDefinition(false); ok {
+		cors.Parent = a
+		if a.Origins == nil {
+			a.Origins = make(map[string]*design.CORSDefinition)
+		}
+		a.Origins[origin] = cors
+	} else if r, ok := resourceDefinition(true); ok {
+		cors.Parent = r
+		if r.Origins == nil {
+			r.Origins = make(map[string]*design.CORSDefinition)
+		}
+		r.Origins[origin] = cors
+	}
+}
+
+// Methods sets the origin allowed methods. Used in Origin DSL.
+func Methods(vals ...string) {
+	if cors, ok := corsDefinition(true); ok {
+		cors.Methods = append(cors.Methods, vals...)
+	}
+}
+
+// Expose sets the origin exposed headers. Used in Origin DSL.
+func Expose(vals ...string) {
+	if cors, ok := corsDefinition(true); ok {
+		cors.Exposed = append(cors.Exposed, vals...)
+	}
+}
+
+// MaxAge sets the cache expiry for preflight request responses in seconds. Used in Origin DSL.
+func MaxAge(val uint) {
+	if cors, ok := corsDefinition(true); ok {
+		cors.MaxAge = val
+	}
+}
+
+// Credentials sets the allow credentials response header. Used in Origin DSL. Credentials cannot
+// be used with the "*" origin as that would let any site make credentialed requests.
+func Credentials() {
+	if cors, ok := corsDefinition(true); ok {
+		cors.Credentials = true
+	}
+}
+
+// corsDefinition returns true and current context if it is a CORSDefinition,
+// nil and false otherwise.
+func corsDefinition(failIfNotCORS bool) (*design.CORSDefinition, bool) {
+	cors, ok := dslengine.CurrentDefinition().(*design.CORSDefinition)
+	if !ok && failIfNotCORS {
+		dslengine.IncompatibleDSL()
+	}
+	return cors, ok
+}
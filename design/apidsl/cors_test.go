@@ -0,0 +1,120 @@
+package apidsl_test
+
+import (
+	. "github.com/goadesign/goa/design"
+	. "github.com/goadesign/goa/design/apidsl"
+	"github.com/goadesign/goa/dslengine"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Origin", func() {
+	var origin string
+	var dsl func()
+
+	BeforeEach(func() {
+		dslengine.Reset()
+		origin = ""
+		dsl = nil
+	})
+
+	Context("used in API", func() {
+		JustBeforeEach(func() {
+			API("test", func() {
+				Origin(origin, dsl)
+			})
+			dslengine.Run()
+		})
+
+		Context("with a full policy", func() {
+			BeforeEach(func() {
+				origin = "http://*.goa.design"
+				dsl = func() {
+					Methods("GET", "POST")
+					Headers("X-Shared-Secret")
+					Expose("X-Time", "X-Api-Version")
+					MaxAge(600)
+					Credentials()
+				}
+			})
+
+			It("sets the API CORS policy", func() {
+				Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+				Ω(Design.Validate()).ShouldNot(HaveOccurred())
+				Ω(Design.Origins).Should(HaveLen(1))
+				cors := Design.Origins[origin]
+				Ω(cors).ShouldNot(BeNil())
+				Ω(cors.Parent).Should(Equal(Design))
+				Ω(cors.Origin).Should(Equal(origin))
+				Ω(cors.Methods).Should(Equal([]string{"GET", "POST"}))
+				Ω(cors.Headers).Should(Equal([]string{"X-Shared-Secret"}))
+				Ω(cors.Exposed).Should(Equal([]string{"X-Time", "X-Api-Version"}))
+				Ω(cors.MaxAge).Should(Equal(uint(600)))
+				Ω(cors.Credentials).Should(BeTrue())
+			})
+		})
+
+		Context("with an origin containing more than one wildcard", func() {
+			BeforeEach(func() {
+				origin = "http://*.goa.*"
+				dsl = func() {}
+			})
+
+			It("produces an invalid API definition", func() {
+				Ω(dslengine.Errors).Should(HaveOccurred())
+				Ω(Design.Validate()).Should(HaveOccurred())
+			})
+		})
+
+		Context("with a non string header", func() {
+			BeforeEach(func() {
+				origin = "*"
+				dsl = func() {
+					Headers(42)
+				}
+			})
+
+			It("produces an error", func() {
+				Ω(dslengine.Errors).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Context("used in Resource", func() {
+		var res *ResourceDefinition
+
+		JustBeforeEach(func() {
+			res = Resource("res", func() {
+				Origin(origin, dsl)
+			})
+			dslengine.Run()
+		})
+
+		BeforeEach(func() {
+			origin = "*"
+			dsl = func() {
+				Methods("GET")
+			}
+		})
+
+		It("sets the resource CORS policy", func() {
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			Ω(res.Origins).Should(HaveLen(1))
+			Ω(res.Origins[origin].Parent).Should(Equal(res))
+			Ω(res.Origins[origin].Methods).Should(Equal([]string{"GET"}))
+		})
+	})
+
+	Context("CORS DSL used outside of Origin", func() {
+		JustBeforeEach(func() {
+			Resource("res", func() {
+				Methods("GET")
+			})
+			dslengine.Run()
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
+})
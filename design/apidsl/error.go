@@ -0,0 +1,53 @@
+package apidsl
+
+import (
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/dslengine"
+)
+
+// Error declares an application error id given its title, the status code of the responses it
+// produces and optionally the URL of its documentation. Error must appear in API. Application ids
+// must be 1000 or greater to avoid clashing with the ids defined by goa.
+//
+// The code generated by goagen registers the errors in the goa error catalog at initialization
+// and the generated Swagger and JSON schema documents list them. Note that goagen only knows about
+// the errors declared with Error and the errors registered with goa.RegisterError by the design
+// package itself, the errors registered by other application packages are not documented.
+// Example:
+//
+//	API("cellar", func() {
+//		Error(1000, "bottle is empty", 409, "https://cellar.goa.design/errors#empty")
+//	})
+func Error(id int, title string, status int, url ...string) {
+	a, ok := apiDefinition(true)
+	if !ok {
+		return
+	}
+	if id < 1000 {
+		dslengine.ReportError("invalid error id %d, must be 1000 or greater", id)
+		return
+	}
+	if title == "" {
+		dslengine.ReportError("missing title for error id %d", id)
+		return
+	}
+	if status < 100 || status > 599 {
+		dslengine.ReportError("invalid status %d for error id %d", status, id)
+		return
+	}
+	if len(url) > 1 {
+		dslengine.ReportError("too many arguments given to Error")
+		return
+	}
+	for _, def := range a.Errors {
+		if def.ID == id {
+			dslengine.ReportError("error id %d is already declared", id)
+			return
+		}
+	}
+	def := &design.ErrorDefinition{ID: id, Title: title, Status: status}
+	if len(url) == 1 {
+		def.URL = url[0]
+	}
+	a.Errors = append(a.Errors, def)
+}
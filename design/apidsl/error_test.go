@@ -0,0 +1,82 @@
+package apidsl_test
+
+import (
+	. "github.com/goadesign/goa/design"
+	. "github.com/goadesign/goa/design/apidsl"
+	"github.com/goadesign/goa/dslengine"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Error", func() {
+	var dsl func()
+
+	BeforeEach(func() {
+		dslengine.Reset()
+		dsl = nil
+	})
+
+	JustBeforeEach(func() {
+		API("test", dsl)
+		dslengine.Run()
+	})
+
+	Context("with valid errors", func() {
+		BeforeEach(func() {
+			dsl = func() {
+				Error(1000, "bottle is empty", 409, "https://cellar.goa.design/errors#empty")
+				Error(1001, "cellar is full", 422)
+			}
+		})
+
+		It("declares the errors", func() {
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			Ω(Design.Errors).Should(HaveLen(2))
+			Ω(Design.Errors[0]).Should(Equal(&ErrorDefinition{
+				ID:     1000,
+				Title:  "bottle is empty",
+				Status: 409,
+				URL:    "https://cellar.goa.design/errors#empty",
+			}))
+			Ω(Design.Errors[1].URL).Should(BeEmpty())
+		})
+	})
+
+	Context("with an id used by goa", func() {
+		BeforeEach(func() {
+			dsl = func() {
+				Error(1, "bottle is empty", 409)
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
+
+	Context("with an id declared twice", func() {
+		BeforeEach(func() {
+			dsl = func() {
+				Error(1000, "bottle is empty", 409)
+				Error(1000, "cellar is full", 422)
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+			Ω(Design.Errors).Should(HaveLen(1))
+		})
+	})
+
+	Context("with an invalid status", func() {
+		BeforeEach(func() {
+			dsl = func() {
+				Error(1000, "bottle is empty", 42)
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
+})
@@ -316,6 +316,51 @@ func Link(name string, view ...string) {
 	}
 }
 
+// ETag defines the entity tag of the media type instances. The entity tag is the value of the
+// attribute with the given name if any, a hash of the rendered instance otherwise. Views that do
+// not render the attribute also use a hash. The generated response helpers set the ETag header and
+// handle the If-None-Match and If-Match request headers responding with status 304 or 412 as
+// needed. Example:
+//
+//	MediaType("application/vnd.goa.example.bottle", func() {
+//		Attributes(func() {
+//			Attribute("id", Integer)
+//			Attribute("revision", String)
+//			Attribute("updated_at", DateTime)
+//		})
+//		ETag("revision")		// Use ETag() to hash the instances instead
+//		LastModified("updated_at")
+//		View("default", func() {
+//			Attribute("id")
+//			Attribute("revision")
+//			Attribute("updated_at")
+//		})
+//	})
+func ETag(attName ...string) {
+	if len(attName) > 1 {
+		dslengine.ReportError("too many arguments given to ETag")
+		return
+	}
+	if mt, ok := mediaTypeDefinition(true); ok {
+		if len(attName) == 0 {
+			mt.HashETag = true
+			mt.ETag = ""
+		} else {
+			mt.ETag = attName[0]
+			mt.HashETag = false
+		}
+	}
+}
+
+// LastModified sets the name of the DateTime attribute whose value is the last modification time
+// of the media type instances. The generated response helpers set the Last-Modified header and
+// handle the If-Modified-Since and If-Unmodified-Since request headers. See ETag for an example.
+func LastModified(attName string) {
+	if mt, ok := mediaTypeDefinition(true); ok {
+		mt.LastModified = attName
+	}
+}
+
 // CollectionOf creates a collection media type from its element media type. A collection media
 // type represents the content of responses that return a collection of resources such as "list"
 // actions. This function can be called from any place where a media type can be used.
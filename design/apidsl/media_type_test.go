@@ -195,6 +195,107 @@ var _ = Describe("MediaType", func() {
 			Ω(o[viewAtt].Type).Should(Equal(String))
 		})
 	})
+
+	Context("with an entity tag and a last modification time", func() {
+		BeforeEach(func() {
+			name = "application/foo"
+			dslFunc = func() {
+				Attributes(func() {
+					Attribute("id", Integer)
+					Attribute("rev", String)
+					Attribute("updated", DateTime)
+				})
+				ETag("rev")
+				LastModified("updated")
+				View("default", func() {
+					Attribute("rev")
+					Attribute("updated")
+				})
+				View("tiny", func() {
+					Attribute("id")
+				})
+			}
+		})
+
+		It("sets the entity tag and last modification attributes", func() {
+			Ω(mt).ShouldNot(BeNil())
+			Ω(mt.Validate()).ShouldNot(HaveOccurred())
+			Ω(mt.ETag).Should(Equal("rev"))
+			Ω(mt.HashETag).Should(BeFalse())
+			Ω(mt.LastModified).Should(Equal("updated"))
+		})
+
+		It("hashes the views that do not render the entity tag", func() {
+			p, _, err := mt.Project("default")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(p.ETag).Should(Equal("rev"))
+			Ω(p.LastModified).Should(Equal("updated"))
+			p, _, err = mt.Project("tiny")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(p.ETag).Should(BeEmpty())
+			Ω(p.HashETag).Should(BeTrue())
+			Ω(p.LastModified).Should(BeEmpty())
+		})
+	})
+
+	Context("with a hashed entity tag", func() {
+		BeforeEach(func() {
+			name = "application/foo"
+			dslFunc = func() {
+				Attributes(func() {
+					Attribute("id", Integer)
+				})
+				ETag()
+				View("default", func() {
+					Attribute("id")
+				})
+			}
+		})
+
+		It("hashes the instances", func() {
+			Ω(mt.Validate()).ShouldNot(HaveOccurred())
+			Ω(mt.ETag).Should(BeEmpty())
+			Ω(mt.HashETag).Should(BeTrue())
+		})
+	})
+
+	Context("with an unknown entity tag attribute", func() {
+		BeforeEach(func() {
+			name = "application/foo"
+			dslFunc = func() {
+				Attributes(func() {
+					Attribute("id", Integer)
+				})
+				ETag("rev")
+				View("default", func() {
+					Attribute("id")
+				})
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(mt.Validate()).Should(HaveOccurred())
+		})
+	})
+
+	Context("with a last modification attribute that is not a DateTime", func() {
+		BeforeEach(func() {
+			name = "application/foo"
+			dslFunc = func() {
+				Attributes(func() {
+					Attribute("updated", String)
+				})
+				LastModified("updated")
+				View("default", func() {
+					Attribute("updated")
+				})
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(mt.Validate()).Should(HaveOccurred())
+		})
+	})
 })
 
 var _ = Describe("Duplicate media types", func() {
@@ -0,0 +1,72 @@
+package apidsl
+
+import (
+	"time"
+
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/dslengine"
+)
+
+// RateLimit limits the number of requests a client may make to requests per period. RateLimit may
+// be used in API, Resource or Action. The limit is shared by all the actions it applies to: a
+// limit defined in a resource applies to the requests made to any of the resource actions. The
+// action rate limit overrides the resource rate limit which overrides the API rate limit.
+//
+// Clients are identified by IP address by default. The optional key argument may be an API key
+// security scheme in which case clients are identified by API key, or a string in which case the
+// generated code exposes a function to register the corresponding key function. API keys are only
+// used once validated, that is for actions secured with the same scheme, clients are identified by
+// IP address otherwise. Example:
+//
+//	API("cellar", func() {
+//		RateLimit(1000, time.Hour)				// Per client IP
+//	})
+//
+//	Resource("bottle", func() {
+//		RateLimit(100, time.Minute, APIKey)			// Per API key
+//
+//		Action("rate", func() {
+//			RateLimit(10, time.Minute, "user")		// Per user, see UseUserRateLimitKey
+//		})
+//	})
+func RateLimit(requests int, per time.Duration, key ...interface{}) {
+	if requests <= 0 {
+		dslengine.ReportError("invalid rate limit of %d requests, must be greater than 0", requests)
+		return
+	}
+	if per <= 0 {
+		dslengine.ReportError("invalid rate limit period %s, must be greater than 0", per)
+		return
+	}
+	if len(key) > 1 {
+		dslengine.ReportError("too many arguments given to RateLimit")
+		return
+	}
+	def := &design.RateLimitDefinition{Requests: requests, Period: per}
+	if len(key) == 1 {
+		switch actual := key[0].(type) {
+		case *design.SecuritySchemeDefinition:
+			if actual.Kind != design.APIKeySecurityKind {
+				dslengine.ReportError("rate limit key scheme %#v is not an API key scheme", actual.SchemeName)
+				return
+			}
+			def.APIKey = actual
+		case string:
+			if actual == "" {
+				dslengine.ReportError("invalid empty rate limit key name")
+				return
+			}
+			def.KeyName = actual
+		default:
+			dslengine.InvalidArgError("API key security scheme or key name", key[0])
+			return
+		}
+	}
+	if a, ok := actionDefinition(false); ok {
+		a.RateLimit = def
+	} else if r, ok := resourceDefinition(false); ok {
+		r.RateLimit = def
+	} else if a, ok := apiDefinition(true); ok {
+		a.RateLimit = def
+	}
+}
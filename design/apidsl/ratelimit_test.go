@@ -0,0 +1,86 @@
+package apidsl_test
+
+import (
+	"time"
+
+	. "github.com/goadesign/goa/design"
+	. "github.com/goadesign/goa/design/apidsl"
+	"github.com/goadesign/goa/dslengine"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimit", func() {
+	BeforeEach(func() {
+		dslengine.Reset()
+	})
+
+	Context("used in API, Resource and Action", func() {
+		var res *ResourceDefinition
+
+		JustBeforeEach(func() {
+			API("test", func() {
+				RateLimit(1000, time.Hour)
+			})
+			apiKey := APIKeySecurity("api_key", func() {
+				Header("X-Api-Key")
+			})
+			res = Resource("bottle", func() {
+				RateLimit(100, time.Minute, apiKey)
+				Action("show", func() {
+					Routing(GET("/:id"))
+				})
+				Action("rate", func() {
+					Routing(PUT("/:id"))
+					RateLimit(10, time.Minute, "user")
+				})
+			})
+			Resource("account", func() {
+				Action("show", func() {
+					Routing(GET("/:id"))
+				})
+			})
+			dslengine.Run()
+		})
+
+		It("sets the rate limits and inherits them", func() {
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			Ω(Design.RateLimit.Requests).Should(Equal(1000))
+			Ω(Design.RateLimit.Period).Should(Equal(time.Hour))
+			Ω(Design.RateLimit.Key()).Should(Equal("ip"))
+			Ω(res.RateLimit.APIKey.SchemeName).Should(Equal("api_key"))
+			Ω(res.RateLimit.Key()).Should(Equal("apikey"))
+			Ω(res.Actions["show"].RateLimit).Should(Equal(res.RateLimit))
+			Ω(res.Actions["rate"].RateLimit.KeyName).Should(Equal("user"))
+			Ω(res.Actions["rate"].RateLimit.Key()).Should(Equal("custom"))
+			Ω(Design.Resources["account"].Actions["show"].RateLimit).Should(Equal(Design.RateLimit))
+		})
+	})
+
+	Context("with an invalid number of requests", func() {
+		JustBeforeEach(func() {
+			API("test", func() {
+				RateLimit(0, time.Hour)
+			})
+			dslengine.Run()
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
+
+	Context("with a scheme that is not an API key scheme", func() {
+		JustBeforeEach(func() {
+			basic := BasicAuthSecurity("basic")
+			API("test", func() {
+				RateLimit(10, time.Hour, basic)
+			})
+			dslengine.Run()
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
+})
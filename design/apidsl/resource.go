@@ -30,6 +30,12 @@ import (
 //		Parent("account")		// Name of parent resource if any
 //		CanonicalActionName("get")	// Name of action that returns canonical representation if not "show"
 //		UseTrait("Authenticated")	// Included trait if any, can appear more than once
+//		Timeout(10 * time.Second)	// Default action timeout if any
+//		MaxBodySize(1 << 20)		// Default maximum request body size if any
+//		Origin("*", func() {		// CORS policy that applies to all the resource actions
+//			Methods("GET")
+//		})
+//		Security(JWT, "api:read")	// Security required by the resource actions if any
 //
 //		Action("show", func() {		// Action definition, can appear more than once
 //			// ... Action dsl
@@ -1,6 +1,8 @@
 package apidsl_test
 
 import (
+	"time"
+
 	. "github.com/goadesign/goa/design"
 	. "github.com/goadesign/goa/design/apidsl"
 	"github.com/goadesign/goa/dslengine"
@@ -96,6 +98,53 @@ var _ = Describe("Resource", func() {
 		})
 	})
 
+	Context("with a timeout", func() {
+		const timeout = 10 * time.Second
+		const actionTimeout = time.Minute
+
+		BeforeEach(func() {
+			name = "foo"
+			dsl = func() {
+				Timeout(timeout)
+				Action("inherit", func() { Routing(GET("/:id")) })
+				Action("override", func() {
+					Routing(PUT("/:id"))
+					Timeout(actionTimeout)
+				})
+			}
+		})
+
+		It("sets the timeout of the actions that don't define one", func() {
+			Ω(res).ShouldNot(BeNil())
+			Ω(res.Validate()).ShouldNot(HaveOccurred())
+			Ω(res.Timeout).Should(Equal(timeout))
+			Ω(res.Actions["inherit"].Timeout).Should(Equal(timeout))
+			Ω(res.Actions["override"].Timeout).Should(Equal(actionTimeout))
+		})
+	})
+
+	Context("with a maximum body size", func() {
+		BeforeEach(func() {
+			name = "foo"
+			dsl = func() {
+				MaxBodySize(1024)
+				Action("inherit", func() { Routing(POST("/")) })
+				Action("override", func() {
+					Routing(PUT("/:id"))
+					MaxBodySize(2048)
+				})
+			}
+		})
+
+		It("sets the maximum body size of the actions that don't define one", func() {
+			Ω(res).ShouldNot(BeNil())
+			Ω(res.Validate()).ShouldNot(HaveOccurred())
+			Ω(res.MaxBodySize).Should(Equal(int64(1024)))
+			Ω(res.Actions["inherit"].MaxBodySize).Should(Equal(int64(1024)))
+			Ω(res.Actions["override"].MaxBodySize).Should(Equal(int64(2048)))
+		})
+	})
+
 	Context("with a canonical action that does not exist", func() {
 		const can = "can"
 
@@ -0,0 +1,238 @@
+package apidsl
+
+import (
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/dslengine"
+)
+
+// BasicAuthSecurity defines a security scheme that uses HTTP basic auth. The DSL may be used at the
+// top level or in API and may only contain a Description. Example:
+//
+//	var BasicAuth = BasicAuthSecurity("basic_auth", func() {
+//		Description("Use your email and password")
+//	})
+//
+// The returned value can be used with Security to require the scheme, see Security.
+func BasicAuthSecurity(name string, dsl ...func()) *design.SecuritySchemeDefinition {
+	return securityScheme(design.BasicAuthSecurityKind, name, dsl)
+}
+
+// APIKeySecurity defines a security scheme that uses an API key sent either in a HTTP header or in
+// a query string parameter. The DSL may be used at the top level or in API. Example:
+//
+//	var APIKey = APIKeySecurity("api_key", func() {
+//		Description("Use the key provided by the developer portal")
+//		Header("X-Api-Key")	// or Query("api_key")
+//	})
+func APIKeySecurity(name string, dsl ...func()) *design.SecuritySchemeDefinition {
+	return securityScheme(design.APIKeySecurityKind, name, dsl)
+}
+
+// JWTSecurity defines a security scheme that uses JSON Web Tokens. The token is read from the
+// "Authorization" header by default, use Header or Query to change that. The DSL may be used at
+// the top level or in API. Example:
+//
+//	var JWT = JWTSecurity("jwt", func() {
+//		Description("Use the token issued by the signin endpoint")
+//		TokenURL("https://goa.design/signin")
+//		Scope("api:read", "Read access to the API")
+//		Scope("api:write", "Write access to the API")
+//	})
+func JWTSecurity(name string, dsl ...func()) *design.SecuritySchemeDefinition {
+	return securityScheme(design.JWTSecurityKind, name, dsl)
+}
+
+// OAuth2Security defines a security scheme that uses OAuth2 access tokens. The DSL must define the
+// OAuth2 flow using one of AccessCodeFlow, ImplicitFlow, PasswordFlow or ApplicationFlow. The DSL
+// may be used at the top level or in API. Example:
+//
+//	var OAuth2 = OAuth2Security("oauth2", func() {
+//		AccessCodeFlow("https://goa.design/authorize", "https://goa.design/token")
+//		Scope("api:read", "Read access to the API")
+//	})
+func OAuth2Security(name string, dsl ...func()) *design.SecuritySchemeDefinition {
+	return securityScheme(design.OAuth2SecurityKind, name, dsl)
+}
+
+// Security sets the security scheme required by the API, resource or action. The scheme is given
+// either as the value returned by one of the security scheme DSLs or by name. The optional scopes
+// list the JWT or OAuth2 scopes required to make the requests. Security may be used in API,
+// Resource or Action, the action security requirement overrides the resource security requirement
+// which overrides the API security requirement. Example:
+//
+//	Resource("bottle", func() {
+//		Security(JWT, "api:read")
+//
+//		Action("update", func() {
+//			Security("jwt", "api:write")
+//			// ...
+//		})
+//
+//		Action("health", func() {
+//			NoSecurity()
+//			// ...
+//		})
+//	})
+func Security(scheme interface{}, scopes ...string) {
+	var def *design.SecuritySchemeDefinition
+	switch actual := scheme.(type) {
+	case *design.SecuritySchemeDefinition:
+		def = actual
+	case string:
+		for _, s := range design.Design.SecuritySchemes {
+			if s.SchemeName == actual {
+				def = s
+				break
+			}
+		}
+		if def == nil {
+			dslengine.ReportError("unknown security scheme %#v", actual)
+			return
+		}
+	default:
+		dslengine.InvalidArgError("security scheme or security scheme name", scheme)
+		return
+	}
+	if def == nil {
+		dslengine.ReportError("invalid nil security scheme")
+		return
+	}
+	setSecurity(&design.SecurityDefinition{Scheme: def, Scopes: scopes})
+}
+
+// NoSecurity removes the security requirement inherited from the resource or API. It may be used
+// in Resource or Action.
+func NoSecurity() {
+	if _, ok := apiDefinition(false); ok {
+		dslengine.IncompatibleDSL()
+		return
+	}
+	setSecurity(&design.SecurityDefinition{})
+}
+
+// Query sets the name of the query string parameter that contains the API key or the token. It
+// may be used in APIKeySecurity and JWTSecurity.
+func Query(name string) {
+	if s, ok := securitySchemeDefinition(true); ok {
+		s.In = "query"
+		s.Name = name
+	}
+}
+
+// Scope defines a scope that may be required by the actions using the scheme. It may be used in
+// JWTSecurity and OAuth2Security.
+func Scope(name, description string) {
+	if s, ok := securitySchemeDefinition(true); ok {
+		if s.Kind != design.JWTSecurityKind && s.Kind != design.OAuth2SecurityKind {
+			dslengine.ReportError("scopes are only supported by JWT and OAuth2 security schemes")
+			return
+		}
+		if s.Scopes == nil {
+			s.Scopes = make(map[string]string)
+		}
+		s.Scopes[name] = description
+	}
+}
+
+// TokenURL sets the URL of the service that issues the tokens. It may be used in JWTSecurity.
+func TokenURL(url string) {
+	if s, ok := securitySchemeDefinition(true); ok {
+		if s.Kind != design.JWTSecurityKind {
+			dslengine.ReportError("TokenURL is only supported by JWT security schemes, use a flow DSL for OAuth2")
+			return
+		}
+		s.TokenURL = url
+	}
+}
+
+// AccessCodeFlow sets the OAuth2 flow to "accessCode" (a.k.a. authorization code). It may be used
+// in OAuth2Security.
+func AccessCodeFlow(authorizationURL, tokenURL string) {
+	oauth2Flow("accessCode", authorizationURL, tokenURL)
+}
+
+// ImplicitFlow sets the OAuth2 flow to "implicit". It may be used in OAuth2Security.
+func ImplicitFlow(authorizationURL string) {
+	oauth2Flow("implicit", authorizationURL, "")
+}
+
+// PasswordFlow sets the OAuth2 flow to "password" (a.k.a. resource owner password credentials).
+// It may be used in OAuth2Security.
+func PasswordFlow(tokenURL string) {
+	oauth2Flow("password", "", tokenURL)
+}
+
+// ApplicationFlow sets the OAuth2 flow to "application" (a.k.a. client credentials). It may be
+// used in OAuth2Security.
+func ApplicationFlow(tokenURL string) {
+	oauth2Flow("application", "", tokenURL)
+}
+
+// securityScheme creates and registers a security scheme definition.
+func securityScheme(kind design.SecuritySchemeKind, name string, dsl []func()) *design.SecuritySchemeDefinition {
+	if !dslengine.TopLevelDefinition(false) {
+		if _, ok := apiDefinition(true); !ok {
+			return nil
+		}
+	}
+	if len(dsl) > 1 {
+		dslengine.ReportError("too many arguments given to security scheme DSL")
+		return nil
+	}
+	for _, s := range design.Design.SecuritySchemes {
+		if s.SchemeName == name {
+			dslengine.ReportError("security scheme %#v defined twice", name)
+			return nil
+		}
+	}
+	scheme := &design.SecuritySchemeDefinition{Kind: kind, SchemeName: name}
+	if kind == design.JWTSecurityKind {
+		scheme.In = "header"
+		scheme.Name = "Authorization"
+	}
+	if len(dsl) == 1 {
+		if !dslengine.Execute(dsl[0], scheme) {
+			return nil
+		}
+	}
+	if kind == design.BasicAuthSecurityKind && scheme.Name != "" {
+		dslengine.ReportError("basic auth security schemes do not support Header or Query")
+		return nil
+	}
+	design.Design.SecuritySchemes = append(design.Design.SecuritySchemes, scheme)
+	return scheme
+}
+
+// setSecurity sets the security requirement of the current API, resource or action.
+func setSecurity(sec *design.SecurityDefinition) {
+	if a, ok := actionDefinition(false); ok {
+		a.Security = sec
+	} else if r, ok := resourceDefinition(false); ok {
+		r.Security = sec
+	} else if a, ok := apiDefinition(true); ok {
+		a.Security = sec
+	}
+}
+
+// oauth2Flow sets the OAuth2 flow of the current security scheme.
+func oauth2Flow(flow, authorizationURL, tokenURL string) {
+	if s, ok := securitySchemeDefinition(true); ok {
+		if s.Kind != design.OAuth2SecurityKind {
+			dslengine.ReportError("OAuth2 flows can only be used in OAuth2 security schemes")
+			return
+		}
+		s.Flow = flow
+		s.AuthorizationURL = authorizationURL
+		s.TokenURL = tokenURL
+	}
+}
+
+// securitySchemeDefinition returns true and current context if it is a SecuritySchemeDefinition,
+// nil and false otherwise.
+func securitySchemeDefinition(failIfNotSecurity bool) (*design.SecuritySchemeDefinition, bool) {
+	s, ok := dslengine.CurrentDefinition().(*design.SecuritySchemeDefinition)
+	if !ok && failIfNotSecurity {
+		dslengine.IncompatibleDSL()
+	}
+	return s, ok
+}
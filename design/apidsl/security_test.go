@@ -0,0 +1,157 @@
+package apidsl_test
+
+import (
+	. "github.com/goadesign/goa/design"
+	. "github.com/goadesign/goa/design/apidsl"
+	"github.com/goadesign/goa/dslengine"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Security", func() {
+	BeforeEach(func() {
+		dslengine.Reset()
+	})
+
+	Context("with schemes defined at the top level", func() {
+		var basic, key, jwt, oauth2 *SecuritySchemeDefinition
+
+		JustBeforeEach(func() {
+			basic = BasicAuthSecurity("basic", func() {
+				Description("basic auth")
+			})
+			key = APIKeySecurity("key", func() {
+				Query("api_key")
+			})
+			jwt = JWTSecurity("jwt", func() {
+				TokenURL("https://goa.design/token")
+				Scope("api:read", "read access")
+				Scope("api:write", "write access")
+			})
+			oauth2 = OAuth2Security("oauth2", func() {
+				AccessCodeFlow("https://goa.design/authorize", "https://goa.design/token")
+			})
+			dslengine.Run()
+		})
+
+		It("registers the schemes", func() {
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			Ω(Design.SecuritySchemes).Should(Equal([]*SecuritySchemeDefinition{basic, key, jwt, oauth2}))
+			Ω(basic.Description).Should(Equal("basic auth"))
+			Ω(basic.Type()).Should(Equal("basic"))
+			Ω(key.In).Should(Equal("query"))
+			Ω(key.Name).Should(Equal("api_key"))
+			Ω(jwt.In).Should(Equal("header"))
+			Ω(jwt.Name).Should(Equal("Authorization"))
+			Ω(jwt.Type()).Should(Equal("apiKey"))
+			Ω(jwt.Scopes).Should(HaveLen(2))
+			Ω(oauth2.Flow).Should(Equal("accessCode"))
+			Ω(oauth2.TokenURL).Should(Equal("https://goa.design/token"))
+		})
+	})
+
+	Context("with a scheme defined twice", func() {
+		JustBeforeEach(func() {
+			BasicAuthSecurity("basic")
+			BasicAuthSecurity("basic")
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
+
+	Context("with an OAuth2 scheme missing a flow", func() {
+		JustBeforeEach(func() {
+			API("test", func() {
+				OAuth2Security("oauth2")
+			})
+			dslengine.Run()
+		})
+
+		It("produces an invalid API definition", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+			Ω(Design.Validate()).Should(HaveOccurred())
+		})
+	})
+
+	Context("with scopes used with a basic auth scheme", func() {
+		JustBeforeEach(func() {
+			BasicAuthSecurity("basic", func() {
+				Scope("api:read", "read access")
+			})
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
+
+	Context("used in API, Resource and Action", func() {
+		var res *ResourceDefinition
+		var scopes []string
+
+		BeforeEach(func() {
+			scopes = []string{"api:read"}
+		})
+
+		JustBeforeEach(func() {
+			jwt := JWTSecurity("jwt", func() {
+				Scope("api:read", "read access")
+				Scope("api:write", "write access")
+			})
+			API("test", func() {
+				Security(jwt)
+			})
+			res = Resource("res", func() {
+				Security("jwt", scopes...)
+				Action("show", func() {
+					Routing(GET("/"))
+				})
+				Action("update", func() {
+					Routing(PUT("/"))
+					Security(jwt, "api:write")
+				})
+				Action("health", func() {
+					Routing(GET("/health"))
+					NoSecurity()
+				})
+			})
+			dslengine.Run()
+		})
+
+		It("sets the security requirements", func() {
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			Ω(Design.Validate()).ShouldNot(HaveOccurred())
+			Ω(Design.Security.Scheme.SchemeName).Should(Equal("jwt"))
+			Ω(res.Security.Scopes).Should(Equal([]string{"api:read"}))
+			Ω(res.Actions["show"].Security).Should(Equal(res.Security))
+			Ω(res.Actions["update"].Security.Scopes).Should(Equal([]string{"api:write"}))
+			Ω(res.Actions["health"].Security).Should(BeNil())
+		})
+
+		Context("with an undefined scope", func() {
+			BeforeEach(func() {
+				scopes = []string{"api:admin"}
+			})
+
+			It("produces an invalid API definition", func() {
+				Ω(dslengine.Errors).Should(HaveOccurred())
+				Ω(Design.Validate()).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Context("using an unknown scheme", func() {
+		JustBeforeEach(func() {
+			Resource("res", func() {
+				Security("unknown")
+			})
+			dslengine.Run()
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
+})
@@ -6,6 +6,7 @@ import (
 	"path"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/goadesign/goa/dslengine"
 	"github.com/julienschmidt/httprouter"
@@ -62,6 +63,16 @@ type (
 		DSLFunc func()
 		// Metadata is a list of key/value pairs
 		Metadata dslengine.MetadataDefinition
+		// Origins defines the CORS policies that apply to all API resources indexed by origin
+		Origins map[string]*CORSDefinition
+		// SecuritySchemes lists the security schemes available to the API actions
+		SecuritySchemes []*SecuritySchemeDefinition
+		// Security is the default security requirement of all the API actions
+		Security *SecurityDefinition
+		// RateLimit is the rate limit shared by the API actions if any
+		RateLimit *RateLimitDefinition
+		// Errors lists the application error ids declared with Error
+		Errors []*ErrorDefinition
 
 		// rand is the random generator used to generate examples.
 		rand *RandomGenerator
@@ -93,6 +104,24 @@ type (
 		URL string `json:"url,omitempty"`
 	}
 
+	// CORSDefinition contains the definition for a specific origin CORS policy.
+	CORSDefinition struct {
+		// Parent API or resource
+		Parent dslengine.Definition
+		// Origin is the origin pattern, may contain a single "*" wildcard
+		Origin string
+		// Exposed lists the headers exposed to the client
+		Exposed []string
+		// Methods lists the allowed HTTP methods
+		Methods []string
+		// Headers lists the allowed request headers
+		Headers []string
+		// MaxAge is the number of seconds preflight responses may be cached
+		MaxAge uint
+		// Credentials indicates whether the client may send credentials
+		Credentials bool
+	}
+
 	// ResourceDefinition describes a REST resource.
 	// It defines both a media type and a set of actions that can be executed through HTTP
 	// requests.
@@ -121,6 +150,21 @@ type (
 		Params *AttributeDefinition
 		// Request headers that apply to all actions.
 		Headers *AttributeDefinition
+		// Timeout is the default maximum duration allowed for handling requests made to the
+		// resource actions, zero means no timeout.
+		Timeout time.Duration
+		// MaxBodySize is the default maximum size in bytes of the request bodies of the
+		// resource actions, zero means that the service limit applies.
+		MaxBodySize int64
+		// Origins defines the CORS policies that apply to the resource actions indexed by
+		// origin, these override the API level policies with the same origin.
+		Origins map[string]*CORSDefinition
+		// Security is the default security requirement of the resource actions, overrides
+		// the API security requirement.
+		Security *SecurityDefinition
+		// RateLimit is the rate limit shared by the resource actions if any, overrides
+		// the API rate limit.
+		RateLimit *RateLimitDefinition
 		// DSLFunc contains the DSL used to create this definition if any.
 		DSLFunc func()
 		// metadata is a list of key/value pairs
@@ -204,6 +248,20 @@ type (
 		Payload *UserTypeDefinition
 		// Request headers that need to be made available to action
 		Headers *AttributeDefinition
+		// Timeout is the maximum duration allowed for handling requests made to the action,
+		// zero means no timeout.
+		Timeout time.Duration
+		// MaxBodySize is the maximum size in bytes of the action request bodies, zero means
+		// that the service limit applies.
+		MaxBodySize int64
+		// Security is the security requirement of the action, nil if the action requests
+		// are not authenticated. It defaults to the resource or API security requirement.
+		Security *SecurityDefinition
+		// RateLimit is the rate limit of the action if any. It defaults to the resource or
+		// API rate limit.
+		RateLimit *RateLimitDefinition
+		// RequireIfMatch is true if the action requests must carry the If-Match header.
+		RequireIfMatch bool
 		// Metadata is a list of key/value pairs
 		Metadata dslengine.MetadataDefinition
 	}
@@ -652,7 +710,9 @@ func (r *ResourceDefinition) DSL() func() {
 }
 
 // Finalize is run post DSL execution. It merges response definitions, creates implicit action
-// parameters, initializes querystring parameters and sets path parameters as non zero attributes.
+// parameters, initializes querystring parameters, sets path parameters as non zero attributes,
+// sets the timeout of actions that don't define one to the resource timeout and sets the security
+// requirement of actions that don't define one to the resource or API security requirement.
 func (r *ResourceDefinition) Finalize() {
 	r.IterateActions(func(a *ActionDefinition) error {
 		// 1. Merge response definitions
@@ -706,6 +766,30 @@ func (r *ResourceDefinition) Finalize() {
 			// to actual attributes cos' we just deleted them but that's probably OK.)
 			a.QueryParams = queryParams
 		}
+		// 4. Inherit resource timeout
+		if a.Timeout == 0 {
+			a.Timeout = r.Timeout
+		}
+		if a.MaxBodySize == 0 {
+			a.MaxBodySize = r.MaxBodySize
+		}
+		// 5. Inherit resource or API security, NoSecurity stops the inheritance
+		if a.Security == nil {
+			a.Security = r.Security
+		}
+		if a.Security == nil {
+			a.Security = Design.Security
+		}
+		if a.Security != nil && a.Security.Scheme == nil {
+			a.Security = nil
+		}
+		// 6. Inherit resource or API rate limit
+		if a.RateLimit == nil {
+			a.RateLimit = r.RateLimit
+		}
+		if a.RateLimit == nil {
+			a.RateLimit = Design.RateLimit
+		}
 
 		return nil
 	})
@@ -765,7 +849,8 @@ func (a *AttributeDefinition) IsNonZero(attName string) bool {
 }
 
 // IsPrimitivePointer returns true if the field generated for the given attribute should be a
-// pointer to a primitive type. The target attribute must be an object.
+// pointer to a primitive type. The target attribute must be an object. File attributes are
+// always generated as *goa.FileUpload fields.
 func (a *AttributeDefinition) IsPrimitivePointer(attName string) bool {
 	if !a.Type.IsObject() {
 		panic("checking pointer field on non-object") // bug
@@ -774,7 +859,7 @@ func (a *AttributeDefinition) IsPrimitivePointer(attName string) bool {
 	if att == nil {
 		return false
 	}
-	if att.Type.IsPrimitive() {
+	if att.Type.IsPrimitive() && att.Type.Kind() != FileKind {
 		return !a.IsRequired(attName) && !a.IsNonZero(attName)
 	}
 	return false
@@ -981,6 +1066,15 @@ func (d *DocsDefinition) Context() string {
 	return fmt.Sprintf("documentation for %s", Design.Name)
 }
 
+// Context returns the generic definition name used in error messages.
+func (cors *CORSDefinition) Context() string {
+	var suffix string
+	if cors.Parent != nil {
+		suffix = fmt.Sprintf(" of %s", cors.Parent.Context())
+	}
+	return fmt.Sprintf("CORS policy for %#v%s", cors.Origin, suffix)
+}
+
 // Context returns the generic definition name used in error messages.
 func (t *UserTypeDefinition) Context() string {
 	if t.TypeName != "" {
@@ -1134,6 +1228,24 @@ func (a *ActionDefinition) AllParams() *AttributeDefinition {
 	return res
 }
 
+// HasFilePayload returns true if the action payload is an object with File attributes or
+// attributes that are arrays of File. Such payloads are sent with multipart/form-data requests.
+func (a *ActionDefinition) HasFilePayload() bool {
+	if a.Payload == nil {
+		return false
+	}
+	for _, att := range a.Payload.Type.ToObject() {
+		t := att.Type
+		if arr := t.ToArray(); arr != nil {
+			t = arr.ElemType.Type
+		}
+		if t.Kind() == FileKind {
+			return true
+		}
+	}
+	return false
+}
+
 // HasAbsoluteRoutes returns true if all the action routes are absolute.
 func (a *ActionDefinition) HasAbsoluteRoutes() bool {
 	for _, r := range a.Routes {
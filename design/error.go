@@ -0,0 +1,15 @@
+package design
+
+// ErrorDefinition describes an application error id declared in the design with the Error DSL.
+// The code generated by goagen registers the definition in the goa error catalog, see
+// goa.RegisterError.
+type ErrorDefinition struct {
+	// ID is the error id.
+	ID int
+	// Title is the human friendly error title.
+	Title string
+	// Status is the default HTTP status code of the responses, 500 if zero.
+	Status int
+	// URL is the address of the error documentation if any.
+	URL string
+}
@@ -0,0 +1,28 @@
+package design
+
+import "time"
+
+// RateLimitDefinition describes the maximum rate at which a client may send requests to the API,
+// resource or action.
+type RateLimitDefinition struct {
+	// Requests is the number of requests allowed per Period.
+	Requests int
+	// Period is the duration over which the requests are counted.
+	Period time.Duration
+	// APIKey is the API key security scheme used to identify clients if any.
+	APIKey *SecuritySchemeDefinition
+	// KeyName is the name of the user provided function used to identify clients if any.
+	KeyName string
+}
+
+// Key returns a description of how clients are identified: "ip", "apikey" or "custom".
+func (r *RateLimitDefinition) Key() string {
+	switch {
+	case r.APIKey != nil:
+		return "apikey"
+	case r.KeyName != "":
+		return "custom"
+	default:
+		return "ip"
+	}
+}
@@ -0,0 +1,150 @@
+package design
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/goadesign/goa/dslengine"
+)
+
+// SecuritySchemeKind is the kind of a security scheme.
+type SecuritySchemeKind int
+
+const (
+	// BasicAuthSecurityKind is the kind of security schemes that use HTTP basic auth.
+	BasicAuthSecurityKind SecuritySchemeKind = iota + 1
+	// APIKeySecurityKind is the kind of security schemes that use an API key.
+	APIKeySecurityKind
+	// JWTSecurityKind is the kind of security schemes that use JSON Web Tokens.
+	JWTSecurityKind
+	// OAuth2SecurityKind is the kind of security schemes that use OAuth2 access tokens.
+	OAuth2SecurityKind
+)
+
+type (
+	// SecuritySchemeDefinition defines a security scheme used to authenticate requests made to
+	// the API actions.
+	SecuritySchemeDefinition struct {
+		// Kind is the kind of security scheme.
+		Kind SecuritySchemeKind
+		// SchemeName is the name used to refer to the scheme in Security.
+		SchemeName string
+		// Description of the security scheme.
+		Description string
+		// In is the location of the API key or JWT, either "header" or "query".
+		In string
+		// Name is the name of the header or query string parameter that holds the API key
+		// or JWT.
+		Name string
+		// Flow is the OAuth2 flow, one of "accessCode", "implicit", "password" or
+		// "application".
+		Flow string
+		// AuthorizationURL is the OAuth2 authorization URL.
+		AuthorizationURL string
+		// TokenURL is the OAuth2 token URL or the URL of the service that issues JWTs.
+		TokenURL string
+		// Scopes lists the scopes that may be required by the actions indexed by name,
+		// the values are the scope descriptions.
+		Scopes map[string]string
+	}
+
+	// SecurityDefinition describes the security requirements of an API, resource or action.
+	SecurityDefinition struct {
+		// Scheme is the security scheme used to authenticate requests, nil if the
+		// requests are not authenticated (see NoSecurity).
+		Scheme *SecuritySchemeDefinition
+		// Scopes lists the scopes required to make the requests.
+		Scopes []string
+	}
+)
+
+// Type returns the swagger type of the security scheme.
+func (s *SecuritySchemeDefinition) Type() string {
+	switch s.Kind {
+	case BasicAuthSecurityKind:
+		return "basic"
+	case APIKeySecurityKind, JWTSecurityKind:
+		return "apiKey"
+	case OAuth2SecurityKind:
+		return "oauth2"
+	}
+	return ""
+}
+
+// Context returns the generic definition name used in error messages.
+func (s *SecuritySchemeDefinition) Context() string {
+	if s.SchemeName != "" {
+		return fmt.Sprintf("security scheme %#v", s.SchemeName)
+	}
+	return "unnamed security scheme"
+}
+
+// Validate checks that the security scheme defines all the fields required by its kind.
+func (s *SecuritySchemeDefinition) Validate() *dslengine.ValidationErrors {
+	verr := new(dslengine.ValidationErrors)
+	if s.SchemeName == "" {
+		verr.Add(s, "security scheme name cannot be empty")
+	}
+	switch s.Kind {
+	case APIKeySecurityKind, JWTSecurityKind:
+		if s.In != "header" && s.In != "query" {
+			verr.Add(s, `invalid key location %#v, must be "header" or "query", use Header or Query`, s.In)
+		}
+		if s.Name == "" {
+			verr.Add(s, "missing header or query string parameter name, use Header or Query")
+		}
+	case OAuth2SecurityKind:
+		switch s.Flow {
+		case "accessCode":
+			if s.AuthorizationURL == "" || s.TokenURL == "" {
+				verr.Add(s, "access code flow requires both an authorization and a token URL")
+			}
+		case "implicit":
+			if s.AuthorizationURL == "" {
+				verr.Add(s, "implicit flow requires an authorization URL")
+			}
+		case "password", "application":
+			if s.TokenURL == "" {
+				verr.Add(s, "%s flow requires a token URL", s.Flow)
+			}
+		default:
+			verr.Add(s, "missing OAuth2 flow, use AccessCodeFlow, ImplicitFlow, PasswordFlow or ApplicationFlow")
+		}
+	}
+	for _, u := range []string{s.AuthorizationURL, s.TokenURL} {
+		if u == "" {
+			continue
+		}
+		if _, err := url.Parse(u); err != nil {
+			verr.Add(s, "invalid URL %#v: %s", u, err)
+		}
+	}
+	return verr.AsError()
+}
+
+// Context returns the generic definition name used in error messages.
+func (s *SecurityDefinition) Context() string {
+	if s.Scheme == nil {
+		return "no security"
+	}
+	return fmt.Sprintf("security using %s", s.Scheme.Context())
+}
+
+// Validate checks that the required scopes are defined by the security scheme.
+func (s *SecurityDefinition) Validate(parent dslengine.Definition) *dslengine.ValidationErrors {
+	verr := new(dslengine.ValidationErrors)
+	if s.Scheme == nil {
+		return nil
+	}
+	if len(s.Scopes) > 0 && s.Scheme.Kind != JWTSecurityKind && s.Scheme.Kind != OAuth2SecurityKind {
+		verr.Add(parent, "scopes can only be required by JWT or OAuth2 security schemes, %s does not support scopes", s.Scheme.Context())
+	}
+	if s.Scheme.Scopes != nil {
+		for _, scope := range s.Scopes {
+			if _, ok := s.Scheme.Scopes[scope]; !ok {
+				verr.Add(parent, "scope %#v is not defined by %s", scope, s.Scheme.Context())
+			}
+		}
+	}
+	return verr.AsError()
+}
@@ -107,6 +107,15 @@ type (
 		Views map[string]*ViewDefinition
 		// Resource this media type is the canonical representation for if any
 		Resource *ResourceDefinition
+		// ETag is the name of the attribute whose value is the entity tag of the media
+		// type instances if any.
+		ETag string
+		// HashETag is true if the entity tag of the media type instances is computed by
+		// hashing their content.
+		HashETag bool
+		// LastModified is the name of the DateTime attribute whose value is the last
+		// modification time of the media type instances if any.
+		LastModified string
 	}
 )
 
@@ -133,6 +142,8 @@ const (
 	UserTypeKind
 	// MediaTypeKind represents a media type.
 	MediaTypeKind
+	// FileKind represents a file uploaded with a multipart/form-data request.
+	FileKind
 )
 
 const (
@@ -154,6 +165,10 @@ const (
 
 	// Any is the type for an arbitrary JSON value (interface{} in Go).
 	Any = Primitive(AnyKind)
+
+	// File is the type for a file uploaded with a multipart/form-data request (*goa.FileUpload
+	// in Go). File may only be used to define the attributes of action payloads.
+	File = Primitive(FileKind)
 )
 
 // DataType implementation
@@ -176,6 +191,8 @@ func (p Primitive) Name() string {
 		return "string"
 	case Any:
 		return "any"
+	case File:
+		return "file"
 	default:
 		panic("unknown primitive type") // bug
 	}
@@ -254,6 +271,9 @@ func (p Primitive) IsCompatible(val interface{}) (ok bool) {
 				ok = true
 			}
 		}
+	case File:
+		// Uploaded files have no literal value.
+		ok = false
 	default:
 		panic("unknown primitive type") // bug
 	}
@@ -278,6 +298,9 @@ func (p Primitive) GenerateExample(r *RandomGenerator) interface{} {
 	case Any:
 		// to not make it too complicated, pick one of the primitive types
 		return anyPrimitive[r.Int()%len(anyPrimitive)].GenerateExample(r)
+	case File:
+		// use a random file name
+		return r.String()
 	default:
 		panic("unknown primitive type") // bug
 	}
@@ -551,6 +574,11 @@ func (m *MediaTypeDefinition) ComputeViews() map[string]*ViewDefinition {
 	return nil
 }
 
+// HasETag returns true if the media type instances have an entity tag.
+func (m *MediaTypeDefinition) HasETag() bool {
+	return m.ETag != "" || m.HashETag
+}
+
 // ViewIterator is the type of the function given to IterateViews.
 type ViewIterator func(*ViewDefinition) error
 
@@ -679,6 +707,17 @@ func (m *MediaTypeDefinition) projectSingle(view string) (p *MediaTypeDefinition
 			}
 		}
 	}
+	// Views that do not render the entity tag attribute use a hash of their content
+	if m.HasETag() {
+		if _, ok := viewObj[m.ETag]; ok {
+			p.ETag = m.ETag
+		} else {
+			p.HashETag = true
+		}
+	}
+	if _, ok := viewObj[m.LastModified]; ok && m.LastModified != "" {
+		p.LastModified = m.LastModified
+	}
 	return
 }
 
@@ -71,8 +71,16 @@ func (a *APIDefinition) Validate() error {
 	a.validateContact(verr)
 	a.validateLicense(verr)
 	a.validateDocs(verr)
+	for _, o := range a.Origins {
+		verr.Merge(o.Validate())
+	}
+	for _, scheme := range a.SecuritySchemes {
+		verr.Merge(scheme.Validate())
+	}
+	if a.Security != nil {
+		verr.Merge(a.Security.Validate(a))
+	}
 
-	var allRoutes []*routeInfo
 	a.IterateResources(func(r *ResourceDefinition) error {
 		verr.Merge(r.Validate())
 		r.IterateActions(func(ac *ActionDefinition) error {
@@ -82,8 +90,6 @@ func (a *APIDefinition) Validate() error {
 				}
 			}
 			for _, ro := range ac.Routes {
-				info := newRouteInfo(r, ac, ro)
-				allRoutes = append(allRoutes, info)
 				rwcs := ExtractWildcards(ac.Parent.FullPath())
 				wcs := ExtractWildcards(ro.Path)
 				for _, rwc := range rwcs {
@@ -99,6 +105,49 @@ func (a *APIDefinition) Validate() error {
 		})
 		return nil
 	})
+	a.IterateMediaTypes(func(mt *MediaTypeDefinition) error {
+		verr.Merge(mt.Validate())
+		return nil
+	})
+	a.IterateUserTypes(func(t *UserTypeDefinition) error {
+		verr.Merge(t.Validate("", a))
+		return nil
+	})
+	a.IterateResponses(func(r *ResponseDefinition) error {
+		verr.Merge(r.Validate())
+		return nil
+	})
+	for _, dec := range a.Consumes {
+		verr.Merge(dec.Validate())
+	}
+	for _, enc := range a.Produces {
+		verr.Merge(enc.Validate())
+	}
+
+	err := verr.AsError()
+	if err == nil {
+		// *ValidationErrors(nil) != error(nil)
+		return nil
+	}
+	return err
+}
+
+// ValidateRouteWildcards checks that the routes whose paths share a prefix use the same names for
+// the wildcards at the same positions. This is required by the default goa mux but not by the
+// mux returned by goa.NewTreeMux, so the check is done by the application generator rather than
+// by Validate.
+func (a *APIDefinition) ValidateRouteWildcards() error {
+	verr := new(dslengine.ValidationErrors)
+	var allRoutes []*routeInfo
+	a.IterateResources(func(r *ResourceDefinition) error {
+		r.IterateActions(func(ac *ActionDefinition) error {
+			for _, ro := range ac.Routes {
+				allRoutes = append(allRoutes, newRouteInfo(r, ac, ro))
+			}
+			return nil
+		})
+		return nil
+	})
 	for _, route := range allRoutes {
 		for _, other := range allRoutes {
 			if route == other {
@@ -125,31 +174,10 @@ func (a *APIDefinition) Validate() error {
 			}
 		}
 	}
-	a.IterateMediaTypes(func(mt *MediaTypeDefinition) error {
-		verr.Merge(mt.Validate())
-		return nil
-	})
-	a.IterateUserTypes(func(t *UserTypeDefinition) error {
-		verr.Merge(t.Validate("", a))
-		return nil
-	})
-	a.IterateResponses(func(r *ResponseDefinition) error {
-		verr.Merge(r.Validate())
-		return nil
-	})
-	for _, dec := range a.Consumes {
-		verr.Merge(dec.Validate())
-	}
-	for _, enc := range a.Produces {
-		verr.Merge(enc.Validate())
-	}
-
-	err := verr.AsError()
-	if err == nil {
-		// *ValidationErrors(nil) != error(nil)
-		return nil
+	if err := verr.AsError(); err != nil {
+		return err
 	}
-	return err
+	return nil
 }
 
 func (a *APIDefinition) validateContact(verr *dslengine.ValidationErrors) {
@@ -196,6 +224,27 @@ func (r *ResourceDefinition) Validate() *dslengine.ValidationErrors {
 	if r.Params != nil {
 		verr.Merge(r.Params.Validate("resource parameters", r))
 	}
+	for _, o := range r.Origins {
+		verr.Merge(o.Validate())
+	}
+	if r.Security != nil {
+		verr.Merge(r.Security.Validate(r))
+	}
+	return verr.AsError()
+}
+
+// Validate makes sure the CORS definition origin is valid.
+func (cors *CORSDefinition) Validate() *dslengine.ValidationErrors {
+	verr := new(dslengine.ValidationErrors)
+	if cors.Origin == "" {
+		verr.Add(cors, "CORS origin cannot be empty")
+	}
+	if strings.Count(cors.Origin, "*") > 1 {
+		verr.Add(cors, "invalid origin, can only contain one wildcard character")
+	}
+	if cors.Origin == "*" && cors.Credentials {
+		verr.Add(cors, `invalid origin, credentials cannot be allowed for any origin ("*")`)
+	}
 	return verr.AsError()
 }
 
@@ -215,7 +264,7 @@ func (r *ResourceDefinition) validateActions(verr *dslengine.ValidationErrors) {
 func (r *ResourceDefinition) validateBaseParams(verr *dslengine.ValidationErrors) {
 	baseParams, ok := r.BaseParams.Type.(Object)
 	if !ok {
-		verr.Add(r, "invalid type for BaseParams, must be an Object", r)
+		verr.Add(r, "invalid type for BaseParams, must be an Object")
 	} else {
 		vars := ExtractWildcards(r.BasePath)
 		if len(vars) > 0 {
@@ -294,6 +343,13 @@ func (enc *EncodingDefinition) Validate() *dslengine.ValidationErrors {
 			}
 		}
 	}
+	if enc.Encoder && enc.PackagePath == "" && enc.Function == "" {
+		for _, m := range enc.MIMETypes {
+			if fns, ok := KnownEncoderFunctions[m]; ok && fns[0] == "" {
+				verr.Add(enc, "goa only knows how to decode %s, use Package to specify an encoder Go package", m)
+			}
+		}
+	}
 	if enc.Function != "" && enc.PackagePath == "" {
 		verr.Add(enc, "Must specify encoder package page with PackagePath")
 	}
@@ -321,6 +377,30 @@ func (a *ActionDefinition) Validate() *dslengine.ValidationErrors {
 	verr.Merge(a.ValidateParams())
 	if a.Payload != nil {
 		verr.Merge(a.Payload.Validate("action payload", a))
+		if a.HasFilePayload() && Design != nil {
+			consumed := false
+			for _, enc := range Design.Consumes {
+				for _, m := range enc.MIMETypes {
+					if m == MultipartContentTypes[0] {
+						consumed = true
+					}
+				}
+			}
+			if !consumed {
+				verr.Add(a, `payload has File attributes but the API does not consume %#v, use Consumes(%#v)`,
+					MultipartContentTypes[0], MultipartContentTypes[0])
+			}
+		}
+	}
+	if a.Security != nil {
+		verr.Merge(a.Security.Validate(a))
+	}
+	if a.RequireIfMatch {
+		for _, r := range a.Routes {
+			if r.Verb != "PUT" && r.Verb != "PATCH" && r.Verb != "DELETE" {
+				verr.Add(a, "If-Match may only be required by PUT, PATCH or DELETE actions, route %s %s", r.Verb, r.Path)
+			}
+		}
 	}
 	if a.Parent == nil {
 		verr.Add(a, "missing parent resource")
@@ -365,6 +445,9 @@ func (a *ActionDefinition) ValidateParams() *dslengine.ValidationErrors {
 		if p.Type.Kind() == ObjectKind {
 			verr.Add(a, `parameter %s cannot be an object, only action payloads may be of type object`, n)
 		}
+		if p.Type.Kind() == FileKind {
+			verr.Add(a, `parameter %s cannot be a file, only action payload attributes may be of type File`, n)
+		}
 		ctx := fmt.Sprintf("parameter %s", n)
 		verr.Merge(p.Validate(ctx, a))
 	}
@@ -510,6 +593,26 @@ func (m *MediaTypeDefinition) Validate() *dslengine.ValidationErrors {
 	for _, l := range m.Links {
 		verr.Merge(l.Validate())
 	}
+	if m.ETag != "" || m.LastModified != "" {
+		if m.Type.IsArray() || obj == nil {
+			verr.Add(m, "only object media types may define an entity tag or last modification attribute")
+		} else {
+			if m.ETag != "" {
+				if att, ok := obj[m.ETag]; !ok {
+					verr.Add(m, "unknown entity tag attribute %#v", m.ETag)
+				} else if !att.Type.IsPrimitive() || att.Type.Kind() == AnyKind || att.Type.Kind() == FileKind {
+					verr.Add(m, "entity tag attribute %#v must be a primitive", m.ETag)
+				}
+			}
+			if m.LastModified != "" {
+				if att, ok := obj[m.LastModified]; !ok {
+					verr.Add(m, "unknown last modification attribute %#v", m.LastModified)
+				} else if att.Type.Kind() != DateTimeKind {
+					verr.Add(m, "last modification attribute %#v must be a DateTime", m.LastModified)
+				}
+			}
+		}
+	}
 	return verr.AsError()
 }
 
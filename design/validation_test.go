@@ -271,4 +271,145 @@ var _ = Describe("Validation", func() {
 			})
 		})
 	})
+
+	Context("with routes that use different wildcard names at the same position", func() {
+		BeforeEach(func() {
+			dslengine.Reset()
+			Resource("users", func() {
+				Action("show", func() {
+					Routing(GET("/users/:id([0-9]+)"))
+				})
+				Action("me", func() {
+					Routing(GET("/users/me"))
+				})
+				Action("posts", func() {
+					Routing(GET("/users/:name/posts"))
+				})
+			})
+			dslengine.Run()
+		})
+
+		It("does not produce a validation error", func() {
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+		})
+
+		It("extracts the constrained wildcards", func() {
+			Ω(Design.Resources["users"].Actions["show"].Routes[0].Params()).Should(Equal([]string{"id"}))
+		})
+
+		It("reports the conflicts with ValidateRouteWildcards", func() {
+			Ω(Design.ValidateRouteWildcards()).Should(HaveOccurred())
+		})
+	})
+
+	Context("with an origin that allows credentials", func() {
+		var origin string
+
+		JustBeforeEach(func() {
+			dslengine.Reset()
+			API("test", func() {
+				Origin(origin, func() {
+					Credentials()
+				})
+			})
+			dslengine.Run()
+		})
+
+		Context("using a wildcard", func() {
+			BeforeEach(func() {
+				origin = "https://*.goa.design"
+			})
+
+			It("does not produce a validation error", func() {
+				Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("matching any origin", func() {
+			BeforeEach(func() {
+				origin = "*"
+			})
+
+			It("produces an error", func() {
+				Ω(dslengine.Errors).Should(HaveOccurred())
+				Ω(dslengine.Errors.Error()).Should(ContainSubstring("credentials cannot be allowed"))
+			})
+		})
+	})
+
+	Context("with File attributes", func() {
+		var consumes string
+		var fileParam bool
+
+		BeforeEach(func() {
+			consumes = "multipart/form-data"
+			fileParam = false
+		})
+
+		JustBeforeEach(func() {
+			dslengine.Reset()
+			API("test", func() {
+				Consumes(consumes)
+			})
+			Resource("users", func() {
+				Action("upload", func() {
+					Routing(POST("/users/:id/avatar"))
+					Payload(func() {
+						Attribute("avatar", File)
+						Attribute("thumbnails", ArrayOf(File))
+						Attribute("caption", String)
+						Required("avatar")
+					})
+					if fileParam {
+						Params(func() {
+							Param("id", File)
+						})
+					}
+				})
+			})
+			dslengine.Run()
+		})
+
+		It("does not produce a validation error", func() {
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			Ω(Design.Resources["users"].Actions["upload"].HasFilePayload()).Should(BeTrue())
+		})
+
+		Context("when the API does not consume multipart forms", func() {
+			BeforeEach(func() {
+				consumes = "application/json"
+			})
+
+			It("produces an error", func() {
+				Ω(dslengine.Errors).Should(HaveOccurred())
+				Ω(dslengine.Errors.Error()).Should(ContainSubstring(`Consumes("multipart/form-data")`))
+			})
+		})
+
+		Context("used to define parameters", func() {
+			BeforeEach(func() {
+				fileParam = true
+			})
+
+			It("produces an error", func() {
+				Ω(dslengine.Errors).Should(HaveOccurred())
+				Ω(dslengine.Errors.Error()).Should(ContainSubstring("parameter id cannot be a file"))
+			})
+		})
+	})
+
+	Context("with an API that produces forms", func() {
+		BeforeEach(func() {
+			dslengine.Reset()
+			API("test", func() {
+				Produces("application/x-www-form-urlencoded")
+			})
+			dslengine.Run()
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+			Ω(dslengine.Errors.Error()).Should(ContainSubstring("only knows how to decode"))
+		})
+	})
 })
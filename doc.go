@@ -81,11 +81,15 @@ interface shown above all return an error value. The controller or service-wide
 controller specific error handler) function is invoked whenever the value returned by a controller
 action is not nil. The handler gets both the request context and the error as argument.
 
-The default handler implementation returns a response with status code 500 containing the error
-message in the body. A different error handler can be specificied using the SetErrorHandler
+The default handler implementation returns a response with status code 500 whose body contains
+the status text, the error message is logged. Errors that carry a 4xx status code - such as
+request validation errors - have their message written to the body. A different error handler can be specificied using the SetErrorHandler
 function on either a controller or service wide. goa comes with an alternative error handler - the
 TerseErrorHandler - which also returns a response with status 500 but does not write the error
-message to the body of the response.
+message to the body of the response. The ProblemErrorHandler writes RFC 7807
+"application/problem+json" responses instead. Errors created with NewHTTPError carry their own
+status code (e.g. 404 or 409), a stable error code and optional additional fields which
+ProblemErrorHandler includes in the response, validation errors are listed under "invalid-params".
 
 Middleware
 
@@ -1,6 +1,8 @@
 package goa
 
 import (
+	"compress/gzip"
+	"compress/zlib"
 	"encoding/gob"
 	"encoding/json"
 	"encoding/xml"
@@ -8,6 +10,8 @@ import (
 	"io"
 	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,6 +34,14 @@ type (
 		Reset(r io.Reader)
 	}
 
+	// MediaTypeParamsDecoder is implemented by decoders that make use of the parameters of the
+	// request Content-Type header such as the multipart boundary. Decode calls
+	// SetMediaTypeParams prior to decoding.
+	MediaTypeParamsDecoder interface {
+		Decoder
+		SetMediaTypeParams(params map[string]string)
+	}
+
 	// decoderPool smartly determines whether to instantiate a new Decoder or reuse
 	// one from a sync.Pool
 	decoderPool struct {
@@ -79,12 +91,44 @@ func NewGobEncoder(w io.Writer) Encoder { return gob.NewEncoder(w) }
 func NewGobDecoder(r io.Reader) Decoder { return gob.NewDecoder(r) }
 
 // DecodeRequest retrives the request body and `Content-Type` header and uses Decode
-// to unmarshal into the provided `interface{}`
+// to unmarshal into the provided `interface{}`. Bodies sent with the "gzip" or "deflate"
+// `Content-Encoding` are decompressed first, the maximum body size set by MuxHandler then also
+// applies to the decompressed body.
 func (service *Service) DecodeRequest(req *http.Request, v interface{}) error {
 	body, contentType := req.Body, req.Header.Get("Content-Type")
 	defer body.Close()
 
-	if err := service.Decode(v, body, contentType); err != nil {
+	var reader io.Reader = body
+	switch encoding := strings.ToLower(req.Header.Get("Content-Encoding")); encoding {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return fmt.Errorf("failed to decompress request body with content encoding %#v: %s", encoding, err)
+		}
+		defer gz.Close()
+		reader = gz
+	case "deflate":
+		zr, err := zlib.NewReader(body)
+		if err != nil {
+			return fmt.Errorf("failed to decompress request body with content encoding %#v: %s", encoding, err)
+		}
+		defer zr.Close()
+		reader = zr
+	}
+	var dr *decompressedReader
+	if limit := bodyLimit(body); limit > 0 && reader != body {
+		dr = &decompressedReader{Reader: reader, limit: limit}
+		reader = dr
+	}
+
+	if err := service.Decode(v, reader, contentType); err != nil {
+		if dr != nil && dr.exceeded {
+			return bodyTooLargeError(dr.limit)
+		}
+		if merr, ok := err.(MultiError); ok {
+			// Form values that cannot be coerced are reported like invalid parameters.
+			return merr
+		}
 		return fmt.Errorf("failed to decode request body with content type %#v: %s", contentType, err)
 	}
 
@@ -94,14 +138,15 @@ func (service *Service) DecodeRequest(req *http.Request, v interface{}) error {
 // Decode uses registered Decoders to unmarshal a body based on the contentType
 func (service *Service) Decode(v interface{}, body io.Reader, contentType string) error {
 	now := time.Now()
-	defer MeasureSince([]string{"goa", "decode", contentType}, now)
+	defer service.metrics().MeasureSince([]string{"goa", "decode", contentType}, now)
 	var p *decoderPool
+	var params map[string]string
 	if contentType == "" {
 		// Default to JSON
 		contentType = "application/json"
 	} else {
-		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
-			contentType = mediaType
+		if mediaType, ps, err := mime.ParseMediaType(contentType); err == nil {
+			contentType, params = mediaType, ps
 		}
 	}
 	p = service.decoderPools[contentType]
@@ -115,6 +160,9 @@ func (service *Service) Decode(v interface{}, body io.Reader, contentType string
 	// the decoderPool will handle whether or not a pool is actually in use
 	decoder := p.Get(body)
 	defer p.Put(decoder)
+	if pd, ok := decoder.(MediaTypeParamsDecoder); ok {
+		pd.SetMediaTypeParams(params)
+	}
 	if err := decoder.Decode(v); err != nil {
 		return err
 	}
@@ -177,25 +225,114 @@ func (p *decoderPool) Put(d Decoder) {
 }
 
 // EncodeResponse uses registered Encoders to marshal the response body based on the request
-// `Accept` header and writes it to the http.ResponseWriter
+// `Accept` header and writes it to the http.ResponseWriter. See Negotiate for details on how the
+// encoder is selected. The negotiated media type is set as the response Content-Type unless the
+// response header was already written.
 func (service *Service) EncodeResponse(ctx context.Context, v interface{}) error {
-	now := time.Now()
-	accept := Request(ctx).Header.Get("Accept")
+	contentType, p := service.negotiateResponse(ctx)
+	return service.encode(ctx, contentType, p, v)
+}
+
+// Negotiate returns the media type of the response given the value of the request Accept header
+// as described in RFC 7231 section 5.3.2. The candidates are the preferred media type if not empty
+// (usually the response Content-Type set by the action) followed by the content types of the
+// registered encoders in order of registration. Each candidate is given the quality of the most
+// specific matching media range, taking wildcards (e.g. "application/*") and media type parameters
+// into account. Negotiate returns the first candidate with the highest non zero quality and false
+// if none is acceptable.
+func (service *Service) Negotiate(accept, preferred string) (string, bool) {
 	if accept == "" {
 		accept = "*/*"
 	}
-	var contentType string
+	ranges := parseAccept(accept)
+	var candidates []string
+	if preferred != "" && service.encoderFor(preferred) != nil {
+		candidates = append(candidates, preferred)
+	}
+	candidates = append(candidates, service.encodableContentTypes...)
+	var best string
+	var bestQ float64
+	for _, c := range candidates {
+		if c == "*/*" {
+			continue
+		}
+		if q := quality(ranges, c); q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// negotiateResponse picks the media type and encoder used to write the response. It falls back to
+// the Content-Type already set on the response or to the default encoder if no media type is
+// acceptable. It also sets the response Content-Type and Vary headers.
+func (service *Service) negotiateResponse(ctx context.Context) (string, *encoderPool) {
+	resp := Response(ctx)
+	var accept, current string
+	if req := Request(ctx); req != nil {
+		accept = req.Header.Get("Accept")
+	}
+	if resp != nil && resp.Header() != nil {
+		current = resp.Header().Get("Content-Type")
+	}
+	contentType, ok := service.Negotiate(accept, current)
+	if !ok {
+		contentType = service.defaultContentType(current)
+	}
+	if resp != nil && resp.Header() != nil {
+		resp.Header().Add("Vary", "Accept")
+		if contentType != "" && contentType != current {
+			resp.Header().Set("Content-Type", contentType)
+		}
+	}
+	if contentType == "" {
+		return "*/*", service.encoderPools["*/*"]
+	}
+	return contentType, service.encoderFor(contentType)
+}
+
+// defaultContentType returns the media type used when no registered encoder matches the request
+// Accept header: the current response Content-Type if there is an encoder for it, the media type
+// of the default encoder otherwise.
+func (service *Service) defaultContentType(current string) string {
+	if current != "" && service.encoderFor(current) != nil {
+		return current
+	}
+	def := service.encoderPools["*/*"]
 	for _, t := range service.encodableContentTypes {
-		if accept == "*/*" || accept == t {
-			contentType = accept
-			break
+		if t != "*/*" && (def == nil || service.encoderPools[t] == def) {
+			return t
 		}
 	}
-	defer MeasureSince([]string{"goa", "encode", contentType}, now)
-	p := service.encoderPools[contentType]
-	if p == nil && contentType != "*/*" {
-		p = service.encoderPools["*/*"]
+	return ""
+}
+
+// encoderFor returns the encoder registered for the given media type. Media types using a
+// structured syntax suffix such as "application/vnd.goa.error+json" use the encoder registered for
+// the corresponding base type, "application/json" in this example. encoderFor falls back to the
+// default encoder registered under "*/*" if any.
+func (service *Service) encoderFor(contentType string) *encoderPool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	if p, ok := service.encoderPools[mediaType]; ok {
+		return p
+	}
+	if i := strings.LastIndex(mediaType, "+"); i > 0 {
+		if slash := strings.Index(mediaType, "/"); slash > 0 && slash < i {
+			if p, ok := service.encoderPools[mediaType[:slash+1]+mediaType[i+1:]]; ok {
+				return p
+			}
+		}
 	}
+	return service.encoderPools["*/*"]
+}
+
+// encode marshals v using the given encoder and writes the result to the response.
+func (service *Service) encode(ctx context.Context, contentType string, p *encoderPool, v interface{}) error {
+	now := time.Now()
+	defer service.metrics().MeasureSince([]string{"goa", "encode", contentType}, now)
 	if p == nil {
 		return fmt.Errorf("No encoder registered for %s and no default encoder", contentType)
 	}
@@ -211,7 +348,9 @@ func (service *Service) EncodeResponse(ctx context.Context, v interface{}) error
 }
 
 // Encoder sets a specific encoder to be used for the specified content types. If
-// an encoder is already registered, it will be overwritten.
+// an encoder is already registered, it will be overwritten. Content negotiation
+// favors the content types registered first when the request Accept header does
+// not express a preference.
 func (service *Service) Encoder(f EncoderFunc, contentTypes ...string) {
 	p := newEncodePool(f)
 	for _, contentType := range contentTypes {
@@ -219,15 +358,96 @@ func (service *Service) Encoder(f EncoderFunc, contentTypes ...string) {
 		if err != nil {
 			mediaType = contentType
 		}
+		if _, ok := service.encoderPools[mediaType]; !ok {
+			service.encodableContentTypes = append(service.encodableContentTypes, mediaType)
+		}
 		service.encoderPools[mediaType] = p
 	}
+}
 
-	// Rebuild a unique index of registered content encoders to be used in EncodeResponse
-	service.encodableContentTypes = make([]string, 0, len(service.encoderPools))
-	for contentType := range service.encoderPools {
-		service.encodableContentTypes = append(service.encodableContentTypes, contentType)
+// mediaRange is a single element of an Accept header.
+type mediaRange struct {
+	typ, subtype string
+	params       map[string]string
+	q            float64
+}
+
+// parseAccept parses the value of an Accept header into media ranges. Invalid elements are
+// ignored.
+func parseAccept(accept string) []*mediaRange {
+	var ranges []*mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		slash := strings.Index(mediaType, "/")
+		if slash < 0 {
+			if mediaType != "*" {
+				continue
+			}
+			mediaType, slash = "*/*", 1
+		}
+		r := &mediaRange{typ: mediaType[:slash], subtype: mediaType[slash+1:], params: params, q: 1}
+		if q, ok := params["q"]; ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil {
+				r.q = v
+			}
+			delete(params, "q")
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// quality returns the quality of the most specific media range matching the given media type, 0
+// if none matches.
+func quality(ranges []*mediaRange, contentType string) float64 {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return 0
 	}
+	slash := strings.Index(mediaType, "/")
+	if slash < 0 {
+		return 0
+	}
+	typ, subtype := mediaType[:slash], mediaType[slash+1:]
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := r.match(typ, subtype, params)
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
 
+// match returns the specificity of the media range with respect to the given media type: 0 for
+// "*/*", 1 for "type/*", 2 for "type/subtype" and 3 if the range parameters also match. It returns
+// -1 if the range does not match.
+func (r *mediaRange) match(typ, subtype string, params map[string]string) int {
+	switch {
+	case r.typ == "*" && r.subtype == "*":
+		return 0
+	case r.typ != typ:
+		return -1
+	case r.subtype == "*":
+		return 1
+	case r.subtype != subtype:
+		return -1
+	case len(r.params) == 0:
+		return 2
+	}
+	for k, v := range r.params {
+		if !strings.EqualFold(params[k], v) {
+			return -1
+		}
+	}
+	return 3
 }
 
 // newEncodePool checks to see if the EncoderFactory returns reusable encoders
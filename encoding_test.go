@@ -0,0 +1,173 @@
+package goa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Negotiate", func() {
+	var service *goa.Service
+	var accept, preferred string
+	var contentType string
+	var ok bool
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		service.Encoder(goa.NewJSONEncoder, "application/json")
+		service.Encoder(goa.NewXMLEncoder, "application/xml", "text/xml")
+		service.Encoder(goa.NewJSONEncoder, "*/*")
+		accept = ""
+		preferred = ""
+	})
+
+	JustBeforeEach(func() {
+		contentType, ok = service.Negotiate(accept, preferred)
+	})
+
+	Context("with no Accept header", func() {
+		It("picks the first registered encoder", func() {
+			Ω(ok).Should(BeTrue())
+			Ω(contentType).Should(Equal("application/json"))
+		})
+	})
+
+	Context("with quality values", func() {
+		BeforeEach(func() {
+			accept = "application/json;q=0.5, application/xml"
+		})
+
+		It("picks the media type with the highest quality", func() {
+			Ω(ok).Should(BeTrue())
+			Ω(contentType).Should(Equal("application/xml"))
+		})
+	})
+
+	Context("with a wildcard subtype", func() {
+		BeforeEach(func() {
+			accept = "text/*"
+		})
+
+		It("picks a matching media type", func() {
+			Ω(ok).Should(BeTrue())
+			Ω(contentType).Should(Equal("text/xml"))
+		})
+	})
+
+	Context("with a more specific range excluding a media type", func() {
+		BeforeEach(func() {
+			accept = "application/*, application/json;q=0"
+		})
+
+		It("uses the quality of the most specific range", func() {
+			Ω(ok).Should(BeTrue())
+			Ω(contentType).Should(Equal("application/xml"))
+		})
+	})
+
+	Context("with a preferred media type", func() {
+		BeforeEach(func() {
+			accept = "application/*"
+			preferred = "application/vnd.goa.bottle+json"
+		})
+
+		It("picks the preferred media type", func() {
+			Ω(ok).Should(BeTrue())
+			Ω(contentType).Should(Equal(preferred))
+		})
+	})
+
+	Context("with media type parameters", func() {
+		BeforeEach(func() {
+			accept = "application/vnd.goa.bottle+json; view=tiny, application/vnd.goa.bottle+json;q=0.1, application/xml;q=0.5"
+		})
+
+		It("matches the parameters of the preferred media type", func() {
+			preferred = "application/vnd.goa.bottle+json; view=tiny"
+			contentType, ok = service.Negotiate(accept, preferred)
+			Ω(ok).Should(BeTrue())
+			Ω(contentType).Should(Equal(preferred))
+		})
+
+		It("uses the less specific range for other parameters", func() {
+			preferred = "application/vnd.goa.bottle+json; view=full"
+			contentType, ok = service.Negotiate(accept, preferred)
+			Ω(ok).Should(BeTrue())
+			Ω(contentType).Should(Equal("application/xml"))
+		})
+	})
+
+	Context("with no acceptable media type", func() {
+		BeforeEach(func() {
+			accept = "image/png"
+		})
+
+		It("returns false", func() {
+			Ω(ok).Should(BeFalse())
+		})
+	})
+})
+
+type bottle struct {
+	Name string `json:"name" xml:"name"`
+}
+
+var _ = Describe("Send", func() {
+	var service *goa.Service
+	var accept string
+	var rw *httptest.ResponseRecorder
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		service.Encoder(goa.NewJSONEncoder, "application/json")
+		service.Encoder(goa.NewXMLEncoder, "application/xml")
+		service.Encoder(goa.NewJSONEncoder, "*/*")
+		accept = ""
+		rw = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		req, err := http.NewRequest("GET", "/bottles", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		ctrl := service.NewController("test")
+		ctx := goa.NewContext(ctrl.Context, rw, req, nil)
+		resp := goa.Response(ctx)
+		resp.Header().Set("Content-Type", "application/vnd.goa.bottle+json")
+		Ω(resp.Send(ctx, 200, &bottle{Name: "goa"})).ShouldNot(HaveOccurred())
+	})
+
+	It("keeps the Content-Type set by the action", func() {
+		Ω(rw.Code).Should(Equal(200))
+		Ω(rw.Header().Get("Content-Type")).Should(Equal("application/vnd.goa.bottle+json"))
+		Ω(rw.Header().Get("Vary")).Should(Equal("Accept"))
+		Ω(rw.Body.String()).Should(MatchJSON(`{"name":"goa"}`))
+	})
+
+	Context("with a request that accepts another media type", func() {
+		BeforeEach(func() {
+			accept = "application/xml"
+		})
+
+		It("sets the negotiated Content-Type", func() {
+			Ω(rw.Header().Get("Content-Type")).Should(Equal("application/xml"))
+			Ω(rw.Body.String()).Should(Equal("<bottle><name>goa</name></bottle>"))
+		})
+	})
+
+	Context("with a request that accepts no known media type", func() {
+		BeforeEach(func() {
+			accept = "image/png"
+		})
+
+		It("falls back to the Content-Type set by the action", func() {
+			Ω(rw.Code).Should(Equal(200))
+			Ω(rw.Header().Get("Content-Type")).Should(Equal("application/vnd.goa.bottle+json"))
+		})
+	})
+})
@@ -3,7 +3,9 @@
 // error(s). Each error object has three keys: a id (number), a title and a message. The title
 // for a given id is always the same, the intent is to provide a human friendly categorization.
 // The message is specific to the error occurrence and provides additional details that often
-// include contextual information (name of parameters etc.).
+// include contextual information (name of parameters etc.). Errors whose id is registered with a
+// documentation URL also have a "url" key. Applications may register their own error ids, titles,
+// status codes and URLs with RegisterError or declare them in the design with the Error DSL.
 //
 // The basic data structure backing errors is TypedError which simply contains the id and message.
 // Multiple errors (not just TypedError instances) can be encapsulated in a MultiError. Both
@@ -15,26 +17,68 @@
 // InvalidAttributeTypeError etc. These methods take and return an error which is a MultiError that
 // gets built over time. The final MultiError object then gets serialized into the response and sent
 // back to the client. The response status code is inferred from the type wrapping the error object:
-// a BadRequestError produces a 400 status code while any other error produce a 500. This behavior
-// can be overridden by setting a custom ErrorHandler in the application.
+// a BadRequestError produces a 400 status code, an UnauthorizedError a 401 status code, a
+// ForbiddenError a 403 status code, a ServiceUnavailableError a 503 status code, a TypedError the
+// status code registered for its id while any other error produce a 500. This behavior can be
+// overridden by setting a custom ErrorHandler in the application.
+//
+// Errors that need a specific status code (e.g. 404, 409 or 422) can be created with
+// NewHTTPError. A HTTPError carries the status code, a stable error code string, a link to the
+// error documentation and arbitrary additional fields. ProblemErrorHandler renders all errors as
+// RFC 7807 "application/problem+json" documents.
 package goa
 
 import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
 )
 
 type (
-	// ErrorID is an enum listing the possible types of errors.
+	// ErrorID identifies a type of errors. The ids defined by goa are listed below,
+	// applications may define additional ids with RegisterError.
 	ErrorID int
 
 	// TypedError describes an error that can be returned in a HTTP response.
 	TypedError struct {
 		ID   ErrorID
 		Mesg string
+		// Field is the name of the parameter, header or attribute the error applies to if
+		// any.
+		Field string
+
+		// format is the format that failed to validate for errors with id ErrInvalidFormat.
+		format Format
+	}
+
+	// HTTPError describes an error that results in a response with a specific status code.
+	HTTPError struct {
+		// Status is the HTTP status code of the response, 500 if zero.
+		Status int
+		// Code is a short, stable, machine readable error code, e.g. "bottle_not_found".
+		Code string
+		// Detail is the message specific to this occurrence of the error.
+		Detail string
+		// Type is a URI that links to the error documentation if any.
+		Type string
+		// Fields lists additional error specific fields indexed by name.
+		Fields map[string]interface{}
+	}
+
+	// ErrorDefinition describes an error id: its title, the status code of the responses
+	// produced by errors with that id and a link to the documentation. See RegisterError.
+	ErrorDefinition struct {
+		// ID is the error id.
+		ID ErrorID
+		// Title is the human friendly error title.
+		Title string
+		// Status is the default HTTP status code of the responses, 500 if zero.
+		Status int
+		// URL is the address of the error documentation if any.
+		URL string
 	}
 
 	// MultiError records multiple errors.
@@ -44,6 +88,22 @@ type (
 	BadRequestError struct {
 		Actual error
 	}
+
+	// UnauthorizedError is the type of errors that result in a response with status code 401.
+	UnauthorizedError struct {
+		Actual error
+	}
+
+	// ForbiddenError is the type of errors that result in a response with status code 403.
+	ForbiddenError struct {
+		Actual error
+	}
+
+	// ServiceUnavailableError is the type of errors that result in a response with status code
+	// 503.
+	ServiceUnavailableError struct {
+		Actual error
+	}
 )
 
 const (
@@ -94,51 +154,168 @@ const (
 	// specified in the design definition or more elements than the
 	// maximum length.
 	ErrInvalidLength
+
+	// ErrPanic is the error produced by the Recover middleware when a
+	// request handler panics and by MuxHandler when a payload
+	// unmarshaler panics.
+	ErrPanic
+
+	// ErrRequestTimeout is the error produced by the Timeout middleware
+	// when a request handler does not complete before the deadline.
+	ErrRequestTimeout
+
+	// ErrUnauthorized is the error produced by the security middleware
+	// when a request is missing credentials or the credentials are
+	// invalid.
+	ErrUnauthorized
+
+	// ErrRequestBodyTooLarge is the error produced by the controller when
+	// a request body exceeds the service or action maximum body size.
+	ErrRequestBodyTooLarge
+
+	// ErrRateLimited is the error produced by the RateLimit middleware
+	// when a client exceeds the rate limit.
+	ErrRateLimited
+
+	// ErrPreconditionFailed is the error produced by the generated code
+	// when a request If-Match, If-None-Match or If-Unmodified-Since
+	// condition does not hold.
+	ErrPreconditionFailed
+
+	// ErrPreconditionRequired is the error produced by the RequireIfMatch
+	// middleware when a request is missing the If-Match header.
+	ErrPreconditionRequired
+
+	// ErrNotFound is the error produced by the service mux when a
+	// request does not match any handler.
+	ErrNotFound
+
+	// ErrMethodNotAllowed is the error produced by the service mux when a
+	// request path matches handlers registered for other HTTP methods.
+	ErrMethodNotAllowed
 )
 
+var (
+	// errorCatalog records the error definitions indexed by id.
+	errorCatalog = make(map[ErrorID]*ErrorDefinition)
+	// catalogLock protects errorCatalog.
+	catalogLock sync.RWMutex
+)
+
+// Register the goa error ids.
+func init() {
+	for _, def := range []*ErrorDefinition{
+		{ID: ErrInvalidParamType, Title: "invalid parameter value", Status: 400},
+		{ID: ErrMissingParam, Title: "missing required parameter", Status: 400},
+		{ID: ErrInvalidAttributeType, Title: "invalid attribute type", Status: 400},
+		{ID: ErrMissingAttribute, Title: "missing required attribute", Status: 400},
+		{ID: ErrInvalidEnumValue, Title: "invalid value", Status: 400},
+		{ID: ErrMissingHeader, Title: "missing required HTTP header", Status: 400},
+		{ID: ErrInvalidFormat, Title: "value does not match validation format", Status: 400},
+		{ID: ErrInvalidPattern, Title: "value does not match validation pattern", Status: 400},
+		{ID: ErrInvalidRange, Title: "invalid value range", Status: 400},
+		{ID: ErrInvalidLength, Title: "invalid value length", Status: 400},
+		{ID: ErrPanic, Title: "request handler panic", Status: 500},
+		{ID: ErrRequestTimeout, Title: "request timeout", Status: 503},
+		{ID: ErrUnauthorized, Title: "unauthorized", Status: 401},
+		{ID: ErrRequestBodyTooLarge, Title: "request body too large", Status: 413},
+		{ID: ErrRateLimited, Title: "too many requests", Status: 429},
+		{ID: ErrPreconditionFailed, Title: "precondition failed", Status: 412},
+		{ID: ErrPreconditionRequired, Title: "precondition required", Status: 428},
+		{ID: ErrNotFound, Title: "not found", Status: 404},
+		{ID: ErrMethodNotAllowed, Title: "method not allowed", Status: 405},
+	} {
+		if err := RegisterError(def); err != nil {
+			panic(err) // bug
+		}
+	}
+}
+
+// RegisterError adds the given error definition to the error catalog. The definition title,
+// status and URL are then used when rendering TypedError values with the same id. RegisterError
+// returns an error if the id is already registered. Application ids should start at 1000 to
+// avoid clashing with the ids defined by goa.
+//
+// The generated Swagger and JSON schema documents only list the errors known to goagen: the ones
+// declared in the design with the Error DSL - whose registration code goagen generates - and the
+// ones registered by the design package itself. Errors registered by other application packages
+// are not documented.
+func RegisterError(def *ErrorDefinition) error {
+	if def.Title == "" {
+		return fmt.Errorf("missing title for error id %d", def.ID)
+	}
+	catalogLock.Lock()
+	defer catalogLock.Unlock()
+	if _, ok := errorCatalog[def.ID]; ok {
+		return fmt.Errorf("error id %d already registered", def.ID)
+	}
+	d := *def
+	errorCatalog[def.ID] = &d
+	return nil
+}
+
+// ErrorCatalog returns the definitions of all the registered error ids sorted by id.
+func ErrorCatalog() []*ErrorDefinition {
+	catalogLock.RLock()
+	defer catalogLock.RUnlock()
+	defs := make([]*ErrorDefinition, 0, len(errorCatalog))
+	for _, def := range errorCatalog {
+		d := *def
+		defs = append(defs, &d)
+	}
+	sort.Sort(byErrorID(defs))
+	return defs
+}
+
+// definition returns the catalog definition of the error id, nil if not registered.
+func (k ErrorID) definition() *ErrorDefinition {
+	catalogLock.RLock()
+	defer catalogLock.RUnlock()
+	return errorCatalog[k]
+}
+
 // Title returns a human friendly error title
 func (k ErrorID) Title() string {
-	switch k {
-	case ErrInvalidParamType:
-		return "invalid parameter value"
-	case ErrMissingParam:
-		return "missing required parameter"
-	case ErrInvalidAttributeType:
-		return "invalid attribute type"
-	case ErrMissingAttribute:
-		return "missing required attribute"
-	case ErrMissingHeader:
-		return "missing required HTTP header"
-	case ErrInvalidEnumValue:
-		return "invalid value"
-	case ErrInvalidFormat:
-		return "value does not match validation format"
-	case ErrInvalidPattern:
-		return "value does not match validation pattern"
-	case ErrInvalidRange:
-		return "invalid value range"
-	case ErrInvalidLength:
-		return "invalid value length"
+	if def := k.definition(); def != nil {
+		return def.Title
 	}
 	return "unknown error"
 }
 
+// Status returns the default HTTP status code of the responses produced by errors with the id,
+// 500 if none was registered.
+func (k ErrorID) Status() int {
+	if def := k.definition(); def != nil && def.Status != 0 {
+		return def.Status
+	}
+	return 500
+}
+
+// URL returns the address of the error documentation if any.
+func (k ErrorID) URL() string {
+	if def := k.definition(); def != nil {
+		return def.URL
+	}
+	return ""
+}
+
 // MarshalJSON implements the json marshaler interface.
 func (t *TypedError) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
 		ID    int    `json:"id" xml:"id"`
 		Title string `json:"title" xml:"title"`
 		Msg   string `json:"msg" xml:"msg"`
+		URL   string `json:"url,omitempty" xml:"url,omitempty"`
 	}{
 		ID:    int(t.ID),
 		Title: t.ID.Title(),
 		Msg:   t.Mesg,
+		URL:   t.ID.URL(),
 	})
 }
 
 // Error builds an error message from the typed error details.
 func (t *TypedError) Error() string {
-	IncrCounter([]string{"goa", "error", strconv.Itoa(int(t.ID))}, 1.0)
 	js, err := json.Marshal(t)
 	if err != nil {
 		return `{"id":0,"title":"generic","msg":"failed to serialize error"}`
@@ -177,6 +354,50 @@ func (m MultiError) Error() string {
 	return txt
 }
 
+// NewHTTPError creates a HTTPError with the given status, code and detail message. The message
+// can be built using a format and substituted values a la fmt.Printf.
+func NewHTTPError(status int, code, format string, a ...interface{}) *HTTPError {
+	return &HTTPError{Status: status, Code: code, Detail: fmt.Sprintf(format, a...)}
+}
+
+// WithType sets the URI of the error documentation and returns the error.
+func (e *HTTPError) WithType(uri string) *HTTPError {
+	e.Type = uri
+	return e
+}
+
+// WithField sets an additional error field and returns the error.
+func (e *HTTPError) WithField(name string, value interface{}) *HTTPError {
+	if e.Fields == nil {
+		e.Fields = make(map[string]interface{})
+	}
+	e.Fields[name] = value
+	return e
+}
+
+// MarshalJSON implements the json marshaler interface.
+func (e *HTTPError) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]interface{}, len(e.Fields)+3)
+	for k, v := range e.Fields {
+		fields[k] = v
+	}
+	fields["code"] = e.Code
+	fields["msg"] = e.Detail
+	if e.Type != "" {
+		fields["type"] = e.Type
+	}
+	return json.Marshal(fields)
+}
+
+// Error builds an error message from the error details.
+func (e *HTTPError) Error() string {
+	js, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf(`{"code":%q,"msg":"failed to serialize error"}`, e.Code)
+	}
+	return string(js)
+}
+
 // NewBadRequestError wraps the given error into a BadRequestError.
 func NewBadRequestError(err error) *BadRequestError {
 	return &BadRequestError{Actual: err}
@@ -187,6 +408,36 @@ func (b *BadRequestError) Error() string {
 	return b.Actual.Error()
 }
 
+// NewUnauthorizedError wraps the given error into an UnauthorizedError.
+func NewUnauthorizedError(err error) *UnauthorizedError {
+	return &UnauthorizedError{Actual: err}
+}
+
+// Error implements error.
+func (u *UnauthorizedError) Error() string {
+	return u.Actual.Error()
+}
+
+// NewForbiddenError wraps the given error into a ForbiddenError.
+func NewForbiddenError(err error) *ForbiddenError {
+	return &ForbiddenError{Actual: err}
+}
+
+// Error implements error.
+func (f *ForbiddenError) Error() string {
+	return f.Actual.Error()
+}
+
+// NewServiceUnavailableError wraps the given error into a ServiceUnavailableError.
+func NewServiceUnavailableError(err error) *ServiceUnavailableError {
+	return &ServiceUnavailableError{Actual: err}
+}
+
+// Error implements error.
+func (s *ServiceUnavailableError) Error() string {
+	return s.Actual.Error()
+}
+
 // InvalidParamTypeError appends a typed error of id ErrInvalidParamType to
 // err and returns it.
 func InvalidParamTypeError(name string, val interface{}, expected string, err error) error {
@@ -194,6 +445,7 @@ func InvalidParamTypeError(name string, val interface{}, expected string, err er
 		ID: ErrInvalidParamType,
 		Mesg: fmt.Sprintf("invalid value %#v for parameter %#v, must be a %s",
 			val, name, expected),
+		Field: name,
 	}
 	return ReportError(err, &terr)
 }
@@ -202,8 +454,9 @@ func InvalidParamTypeError(name string, val interface{}, expected string, err er
 // returns it.
 func MissingParamError(name string, err error) error {
 	terr := TypedError{
-		ID:   ErrMissingParam,
-		Mesg: fmt.Sprintf("missing required parameter %#v", name),
+		ID:    ErrMissingParam,
+		Mesg:  fmt.Sprintf("missing required parameter %#v", name),
+		Field: name,
 	}
 	return ReportError(err, &terr)
 }
@@ -215,6 +468,7 @@ func InvalidAttributeTypeError(ctx string, val interface{}, expected string, err
 		ID: ErrInvalidAttributeType,
 		Mesg: fmt.Sprintf("type of %s must be %s but got value %#v", ctx,
 			expected, val),
+		Field: ctx,
 	}
 	return ReportError(err, &terr)
 }
@@ -223,8 +477,9 @@ func InvalidAttributeTypeError(ctx string, val interface{}, expected string, err
 // err and returns it.
 func MissingAttributeError(ctx, name string, err error) error {
 	terr := TypedError{
-		ID:   ErrMissingAttribute,
-		Mesg: fmt.Sprintf("attribute %#v of %s is missing and required", name, ctx),
+		ID:    ErrMissingAttribute,
+		Mesg:  fmt.Sprintf("attribute %#v of %s is missing and required", name, ctx),
+		Field: ctx + "." + name,
 	}
 	return ReportError(err, &terr)
 }
@@ -233,8 +488,9 @@ func MissingAttributeError(ctx, name string, err error) error {
 // returns it.
 func MissingHeaderError(name string, err error) error {
 	terr := TypedError{
-		ID:   ErrMissingHeader,
-		Mesg: fmt.Sprintf("missing required HTTP header %#v", name),
+		ID:    ErrMissingHeader,
+		Mesg:  fmt.Sprintf("missing required HTTP header %#v", name),
+		Field: name,
 	}
 	return ReportError(err, &terr)
 }
@@ -250,6 +506,7 @@ func InvalidEnumValueError(ctx string, val interface{}, allowed []interface{}, e
 		ID: ErrInvalidEnumValue,
 		Mesg: fmt.Sprintf("value of %s must be one of %s but got value %#v", ctx,
 			strings.Join(elems, ", "), val),
+		Field: ctx,
 	}
 	return ReportError(err, &terr)
 }
@@ -261,6 +518,8 @@ func InvalidFormatError(ctx, target string, format Format, formatError, err erro
 		ID: ErrInvalidFormat,
 		Mesg: fmt.Sprintf("%s must be formatted as a %s but got value %#v, %s",
 			ctx, format, target, formatError.Error()),
+		Field:  ctx,
+		format: format,
 	}
 	return ReportError(err, &terr)
 }
@@ -272,6 +531,7 @@ func InvalidPatternError(ctx, target string, pattern string, err error) error {
 		ID: ErrInvalidPattern,
 		Mesg: fmt.Sprintf("%s must match the regexp %#v but got value %#v",
 			ctx, pattern, target),
+		Field: ctx,
 	}
 	return ReportError(err, &terr)
 }
@@ -287,6 +547,7 @@ func InvalidRangeError(ctx string, target interface{}, value int, min bool, err
 		ID: ErrInvalidRange,
 		Mesg: fmt.Sprintf("%s must be %s than %d but got value %#v",
 			ctx, comp, value, target),
+		Field: ctx,
 	}
 	return ReportError(err, &terr)
 }
@@ -302,10 +563,35 @@ func InvalidLengthError(ctx string, target interface{}, ln, value int, min bool,
 		ID: ErrInvalidLength,
 		Mesg: fmt.Sprintf("length of %s must be %s than %d but got value %#v (len=%d)",
 			ctx, comp, value, target, ln),
+		Field: ctx,
 	}
 	return ReportError(err, &terr)
 }
 
+// typedErrors returns the typed errors contained in err, it looks into multi errors and into the
+// errors that wrap another error to set the response status code.
+func typedErrors(err error) []*TypedError {
+	switch actual := err.(type) {
+	case *TypedError:
+		return []*TypedError{actual}
+	case MultiError:
+		var res []*TypedError
+		for _, e := range actual {
+			res = append(res, typedErrors(e)...)
+		}
+		return res
+	case *BadRequestError:
+		return typedErrors(actual.Actual)
+	case *UnauthorizedError:
+		return typedErrors(actual.Actual)
+	case *ForbiddenError:
+		return typedErrors(actual.Actual)
+	case *ServiceUnavailableError:
+		return typedErrors(actual.Actual)
+	}
+	return nil
+}
+
 // ReportError coerces the first argument into a MultiError then appends the second argument and
 // returns the resulting MultiError.
 func ReportError(err error, err2 error) error {
@@ -338,3 +624,10 @@ func ReportError(err error, err2 error) error {
 	}
 	return append(merr, err2)
 }
+
+// byErrorID makes it possible to sort error definitions by id.
+type byErrorID []*ErrorDefinition
+
+func (b byErrorID) Len() int           { return len(b) }
+func (b byErrorID) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byErrorID) Less(i, j int) bool { return b[i].ID < b[j].ID }
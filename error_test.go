@@ -11,7 +11,7 @@ import (
 )
 
 // allErrorKinds list all the existing goa.ErrorID values.
-var allErrorKinds = [10]goa.ErrorID{
+var allErrorKinds = [13]goa.ErrorID{
 	goa.ErrInvalidParamType,
 	goa.ErrMissingParam,
 	goa.ErrInvalidAttributeType,
@@ -22,6 +22,9 @@ var allErrorKinds = [10]goa.ErrorID{
 	goa.ErrInvalidPattern,
 	goa.ErrInvalidRange,
 	goa.ErrInvalidLength,
+	goa.ErrPanic,
+	goa.ErrRequestTimeout,
+	goa.ErrUnauthorized,
 }
 
 var _ = Describe("ErrorKind", func() {
@@ -32,6 +35,67 @@ var _ = Describe("ErrorKind", func() {
 	}
 })
 
+var _ = Describe("RegisterError", func() {
+	var next goa.ErrorID = 1000
+	var id goa.ErrorID
+	var def *goa.ErrorDefinition
+	var regErr error
+
+	BeforeEach(func() {
+		// Registrations are global, use a different id for each test
+		next++
+		id = next
+		def = &goa.ErrorDefinition{
+			ID:     id,
+			Title:  "bottle not found",
+			Status: 404,
+			URL:    "https://goa.design/errors/bottle_not_found",
+		}
+	})
+
+	JustBeforeEach(func() {
+		regErr = goa.RegisterError(def)
+	})
+
+	Context("with a new id", func() {
+		It("registers the error", func() {
+			Ω(regErr).ShouldNot(HaveOccurred())
+			Ω(id.Title()).Should(Equal("bottle not found"))
+			Ω(id.Status()).Should(Equal(404))
+			Ω(id.URL()).Should(Equal("https://goa.design/errors/bottle_not_found"))
+			Ω(goa.ErrorCatalog()).Should(ContainElement(def))
+		})
+
+		It("adds the URL to the typed errors", func() {
+			var data map[string]interface{}
+			te := &goa.TypedError{ID: id, Mesg: "no bottle 1"}
+			Ω(json.Unmarshal([]byte(te.Error()), &data)).ShouldNot(HaveOccurred())
+			Ω(data["url"]).Should(Equal("https://goa.design/errors/bottle_not_found"))
+		})
+	})
+
+	Context("with a goa id", func() {
+		BeforeEach(func() {
+			def.ID = goa.ErrMissingParam
+		})
+
+		It("returns an error", func() {
+			Ω(regErr).Should(HaveOccurred())
+			Ω(goa.ErrorID(goa.ErrMissingParam).Title()).Should(Equal("missing required parameter"))
+		})
+	})
+})
+
+var _ = Describe("ErrorCatalog", func() {
+	It("lists the goa errors sorted by id", func() {
+		catalog := goa.ErrorCatalog()
+		Ω(len(catalog)).Should(BeNumerically(">=", len(allErrorKinds)))
+		for i, kind := range allErrorKinds {
+			Ω(catalog[i].ID).Should(Equal(kind))
+		}
+	})
+})
+
 var _ = Describe("TypedError", func() {
 	var kind goa.ErrorID
 	var msg string
@@ -145,6 +209,25 @@ var _ = Describe("BadRequestError", func() {
 	})
 })
 
+var _ = Describe("HTTPError", func() {
+	var httpErr *goa.HTTPError
+
+	JustBeforeEach(func() {
+		httpErr = goa.NewHTTPError(409, "conflict", "bottle %q already exists", "foo").WithField("name", "foo")
+	})
+
+	It("builds an error message that is valid JSON and contains the code, message and fields", func() {
+		var data map[string]interface{}
+		err := json.Unmarshal([]byte(httpErr.Error()), &data)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(data).Should(Equal(map[string]interface{}{
+			"code": "conflict",
+			"msg":  `bottle "foo" already exists`,
+			"name": "foo",
+		}))
+	})
+})
+
 var _ = Describe("InvalidParamTypeError", func() {
 	var valErr, err error
 	name := "param"
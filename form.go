@@ -0,0 +1,449 @@
+package goa
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMultipartMaxMemory is the default maximum number of bytes of a multipart request
+	// body that MultipartDecoder keeps in memory, file contents beyond are stored in temporary
+	// files.
+	DefaultMultipartMaxMemory = 32 << 20
+
+	// maxFormSize is the maximum size of url-encoded form request bodies, same as net/http.
+	maxFormSize = 10 << 20
+)
+
+type (
+	// FormDecoder decodes "application/x-www-form-urlencoded" request bodies into structs. Each
+	// form field is mapped onto the struct field whose json tag has the same name and coerced
+	// to the field type the same way the generated code coerces querystring parameters. Array
+	// fields may be given as repeated form fields or as a single comma separated value. Nested
+	// struct fields are mapped using dot separated names, e.g. "address.city". Form fields that
+	// do not map onto a struct field are ignored.
+	FormDecoder struct {
+		r io.Reader
+	}
+
+	// MultipartDecoder decodes "multipart/form-data" request bodies into structs. Form value
+	// parts are decoded like FormDecoder does and file parts are decoded into *FileUpload or
+	// []*FileUpload struct fields.
+	MultipartDecoder struct {
+		// MaxMemory is the maximum number of bytes of the request body kept in memory. File
+		// contents beyond are stored in temporary files, form values beyond cause an error.
+		MaxMemory int64
+		// MaxFileSize is the maximum size of each uploaded file in bytes, there is no limit
+		// if zero or less.
+		MaxFileSize int64
+
+		r      io.Reader
+		params map[string]string
+	}
+
+	// FileUpload is a file uploaded with a multipart/form-data request. FileUpload implements
+	// io.ReadCloser: Read streams the file content, Close releases the temporary file used to
+	// store the content of large files if any. The files of the request payload are closed once
+	// the action handler returns, handlers must copy the content they need to keep.
+	FileUpload struct {
+		// Filename is the name of the file given by the client.
+		Filename string `json:"filename"`
+		// Header contains the file part MIME headers.
+		Header textproto.MIMEHeader `json:"header,omitempty"`
+		// Size is the size of the file in bytes.
+		Size int64 `json:"size"`
+
+		content *bytes.Reader
+		file    *os.File
+	}
+)
+
+var (
+	fileUploadType = reflect.TypeOf((*FileUpload)(nil))
+	timeType       = reflect.TypeOf(time.Time{})
+)
+
+// NewFormDecoder returns a decoder for "application/x-www-form-urlencoded" request bodies.
+func NewFormDecoder(r io.Reader) Decoder { return &FormDecoder{r: r} }
+
+// NewMultipartDecoder returns a decoder for "multipart/form-data" request bodies that uses the
+// default memory limit and no file size limit, see MultipartDecoderFunc.
+func NewMultipartDecoder(r io.Reader) Decoder {
+	return &MultipartDecoder{MaxMemory: DefaultMultipartMaxMemory, r: r}
+}
+
+// MultipartDecoderFunc returns a DecoderFunc that creates multipart decoders with the given
+// limits, see MultipartDecoder. Example:
+//
+//	service.Decoder(goa.MultipartDecoderFunc(1<<20, 10<<20), "multipart/form-data")
+func MultipartDecoderFunc(maxMemory, maxFileSize int64) DecoderFunc {
+	return func(r io.Reader) Decoder {
+		return &MultipartDecoder{MaxMemory: maxMemory, MaxFileSize: maxFileSize, r: r}
+	}
+}
+
+// Decode decodes the form into v which must be a pointer to a struct.
+func (d *FormDecoder) Decode(v interface{}) error {
+	body, err := ioutil.ReadAll(io.LimitReader(d.r, maxFormSize+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > maxFormSize {
+		return fmt.Errorf("form body is larger than %d bytes", maxFormSize)
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return err
+	}
+	return decodeForm(v, values, nil)
+}
+
+// Reset resets the decoder so it reads from r.
+func (d *FormDecoder) Reset(r io.Reader) { d.r = r }
+
+// SetMediaTypeParams records the request Content-Type parameters, the "boundary" parameter is
+// required.
+func (d *MultipartDecoder) SetMediaTypeParams(params map[string]string) { d.params = params }
+
+// Reset resets the decoder so it reads from r.
+func (d *MultipartDecoder) Reset(r io.Reader) {
+	d.r = r
+	d.params = nil
+}
+
+// Decode decodes the multipart form into v which must be a pointer to a struct. The content of
+// the files is read before Decode returns so that the request body may be closed.
+func (d *MultipartDecoder) Decode(v interface{}) (err error) {
+	boundary := d.params["boundary"]
+	if boundary == "" {
+		return fmt.Errorf("missing multipart boundary")
+	}
+	values := make(url.Values)
+	files := make(map[string][]*FileUpload)
+	defer func() {
+		// Close the files that were not decoded into v or all of them on error.
+		for name, fs := range files {
+			for _, f := range fs {
+				f.Close()
+			}
+			delete(files, name)
+		}
+		if err != nil {
+			CloseFiles(v)
+		}
+	}()
+	remaining := d.MaxMemory
+	mr := multipart.NewReader(d.r, boundary)
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := p.FormName()
+		if name == "" {
+			continue
+		}
+		if p.FileName() == "" {
+			var buf bytes.Buffer
+			n, err := io.CopyN(&buf, p, remaining+1)
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if n > remaining {
+				return fmt.Errorf("multipart form values are larger than %d bytes", d.MaxMemory)
+			}
+			remaining -= n
+			values.Add(name, buf.String())
+			continue
+		}
+		f, err := d.readFile(p, &remaining)
+		if err != nil {
+			return err
+		}
+		files[name] = append(files[name], f)
+	}
+	return decodeForm(v, values, files)
+}
+
+// readFile reads the content of the given file part, it keeps up to remaining bytes in memory
+// and stores the content in a temporary file beyond.
+func (d *MultipartDecoder) readFile(p *multipart.Part, remaining *int64) (*FileUpload, error) {
+	f := &FileUpload{Filename: p.FileName(), Header: p.Header}
+	var r io.Reader = p
+	if d.MaxFileSize > 0 {
+		r = io.LimitReader(p, d.MaxFileSize+1)
+	}
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, r, *remaining+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n > *remaining {
+		tmp, err := ioutil.TempFile("", "goa-upload-")
+		if err != nil {
+			return nil, err
+		}
+		f.file = tmp
+		n, err = io.Copy(tmp, io.MultiReader(&buf, r))
+		if err == nil {
+			_, err = tmp.Seek(0, 0)
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	} else {
+		*remaining -= n
+		f.content = bytes.NewReader(buf.Bytes())
+	}
+	f.Size = n
+	if d.MaxFileSize > 0 && n > d.MaxFileSize {
+		f.Close()
+		return nil, fmt.Errorf("file %#v is larger than %d bytes", f.Filename, d.MaxFileSize)
+	}
+	return f, nil
+}
+
+// ContentType returns the value of the file part Content-Type header.
+func (f *FileUpload) ContentType() string {
+	return f.Header.Get("Content-Type")
+}
+
+// Read reads the file content.
+func (f *FileUpload) Read(p []byte) (int, error) {
+	if f.file != nil {
+		return f.file.Read(p)
+	}
+	if f.content == nil {
+		return 0, io.EOF
+	}
+	return f.content.Read(p)
+}
+
+// Close releases the resources used to store the file content, it deletes the temporary file if
+// any. Read returns io.EOF once the file is closed.
+func (f *FileUpload) Close() error {
+	f.content = nil
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	if rerr := os.Remove(f.file.Name()); err == nil {
+		err = rerr
+	}
+	f.file = nil
+	return err
+}
+
+// decodeForm sets the fields of the struct v points to with the given form values and files.
+// The files that are set are removed from files.
+func decodeForm(v interface{}, values url.Values, files map[string][]*FileUpload) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot decode form into %T, must be a pointer to a struct", v)
+	}
+	return decodeFormStruct(rv.Elem(), "", values, files)
+}
+
+// decodeFormStruct sets the fields of the given struct value. prefix is the name of the parent
+// fields of nested structs.
+func decodeFormStruct(rv reflect.Value, prefix string, values url.Values, files map[string][]*FileUpload) error {
+	var err error
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		name = prefix + name
+		fv := rv.Field(i)
+		switch {
+		case fv.Type() == fileUploadType:
+			if fs := files[name]; len(fs) > 0 {
+				fv.Set(reflect.ValueOf(fs[0]))
+				for _, f := range fs[1:] {
+					f.Close()
+				}
+				delete(files, name)
+			}
+		case fv.Kind() == reflect.Slice && fv.Type().Elem() == fileUploadType:
+			if fs := files[name]; len(fs) > 0 {
+				fv.Set(reflect.ValueOf(fs))
+				delete(files, name)
+			}
+		case isFormStruct(fv.Type()):
+			if !hasFormPrefix(values, files, name+".") {
+				continue
+			}
+			sv := fv
+			if fv.Kind() == reflect.Ptr {
+				sv = reflect.New(fv.Type().Elem())
+				fv.Set(sv)
+				sv = sv.Elem()
+			}
+			if e := decodeFormStruct(sv, name+".", values, files); e != nil {
+				err = ReportError(err, e)
+			}
+		default:
+			if raw, ok := values[name]; ok && len(raw) > 0 {
+				err = setFormValue(fv, name, raw, err)
+			}
+		}
+	}
+	return err
+}
+
+// setFormValue coerces the given raw values into the type of fv and sets fv. It appends a typed
+// error to err and returns it if the values cannot be coerced.
+func setFormValue(fv reflect.Value, name string, raw []string, err error) error {
+	switch fv.Kind() {
+	case reflect.Ptr:
+		v := reflect.New(fv.Type().Elem())
+		if e := setFormValue(v.Elem(), name, raw, nil); e != nil {
+			return ReportError(err, e)
+		}
+		fv.Set(v)
+		return err
+	case reflect.Slice:
+		elems := raw
+		if len(raw) == 1 {
+			elems = strings.Split(raw[0], ",")
+		}
+		s := reflect.MakeSlice(fv.Type(), len(elems), len(elems))
+		var serr error
+		for i, elem := range elems {
+			serr = setFormValue(s.Index(i), name, []string{elem}, serr)
+		}
+		if serr != nil {
+			return ReportError(err, serr)
+		}
+		fv.Set(s)
+		return err
+	}
+	val := raw[0]
+	switch fv.Kind() {
+	case reflect.Bool:
+		b, e := strconv.ParseBool(val)
+		if e != nil {
+			return InvalidParamTypeError(name, val, "boolean", err)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, e := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if e != nil {
+			return InvalidParamTypeError(name, val, "integer", err)
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, e := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if e != nil {
+			return InvalidParamTypeError(name, val, "integer", err)
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, e := strconv.ParseFloat(val, fv.Type().Bits())
+		if e != nil {
+			return InvalidParamTypeError(name, val, "number", err)
+		}
+		fv.SetFloat(f)
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Interface:
+		if fv.NumMethod() > 0 {
+			return InvalidParamTypeError(name, val, fv.Type().String(), err)
+		}
+		fv.Set(reflect.ValueOf(val))
+	default:
+		if fv.Type() != timeType {
+			return InvalidParamTypeError(name, val, fv.Type().String(), err)
+		}
+		t, e := time.Parse(time.RFC3339, val)
+		if e != nil {
+			return InvalidParamTypeError(name, val, "datetime", err)
+		}
+		fv.Set(reflect.ValueOf(t))
+	}
+	return err
+}
+
+// isFormStruct returns true if values of type t are decoded from nested form fields, i.e. if t
+// is a struct or a pointer to a struct other than time.Time.
+func isFormStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// hasFormPrefix returns true if there is a form value or file whose name starts with prefix.
+func hasFormPrefix(values url.Values, files map[string][]*FileUpload, prefix string) bool {
+	for n := range values {
+		if strings.HasPrefix(n, prefix) {
+			return true
+		}
+	}
+	for n := range files {
+		if strings.HasPrefix(n, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CloseFiles closes the uploaded files set in the fields of the struct v points to, it does nothing
+// if v is not a pointer to a struct. The decoders call it when decoding fails, the code generated
+// by goagen when the payload is invalid and MuxHandler once the request has been handled.
+func CloseFiles(v interface{}) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return
+	}
+	var walk func(reflect.Value)
+	walk = func(rv reflect.Value) {
+		for i := 0; i < rv.NumField(); i++ {
+			if rv.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			fv := rv.Field(i)
+			switch {
+			case fv.Type() == fileUploadType:
+				if !fv.IsNil() {
+					fv.Interface().(*FileUpload).Close()
+				}
+			case fv.Kind() == reflect.Slice && fv.Type().Elem() == fileUploadType:
+				for j := 0; j < fv.Len(); j++ {
+					fv.Index(j).Interface().(*FileUpload).Close()
+				}
+			case isFormStruct(fv.Type()):
+				if fv.Kind() == reflect.Ptr {
+					if fv.IsNil() {
+						continue
+					}
+					fv = fv.Elem()
+				}
+				walk(fv)
+			}
+		}
+	}
+	walk(rv.Elem())
+}
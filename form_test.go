@@ -0,0 +1,205 @@
+package goa_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type (
+	formAddress struct {
+		City *string `json:"city,omitempty"`
+	}
+
+	formPayload struct {
+		Name       string            `json:"name"`
+		Age        *int              `json:"age,omitempty"`
+		Ratio      float64           `json:"ratio,omitempty"`
+		Admin      *bool             `json:"admin,omitempty"`
+		Born       *time.Time        `json:"born,omitempty"`
+		Tags       []string          `json:"tags,omitempty"`
+		Scores     []int             `json:"scores,omitempty"`
+		Address    *formAddress      `json:"address,omitempty"`
+		Avatar     *goa.FileUpload   `json:"avatar,omitempty"`
+		Pictures   []*goa.FileUpload `json:"pictures,omitempty"`
+		Ignored    string            `json:"-"`
+		Extra      map[string]string `json:"extra,omitempty"`
+		Any        interface{}       `json:"any,omitempty"`
+		unexported string
+	}
+)
+
+var _ = Describe("FormDecoder", func() {
+	var service *goa.Service
+	var body string
+	var payload formPayload
+	var err error
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		service.Decoder(goa.NewFormDecoder, "application/x-www-form-urlencoded")
+		payload = formPayload{}
+	})
+
+	JustBeforeEach(func() {
+		req, _ := http.NewRequest("POST", "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		err = service.DecodeRequest(req, &payload)
+	})
+
+	Context("with valid values", func() {
+		BeforeEach(func() {
+			body = "name=joe&age=42&ratio=0.5&admin=true&born=2016-01-02T15:04:05Z&tags=a,b" +
+				"&scores=1&scores=2&address.city=Paris&any=x&Ignored=y&unknown=z"
+		})
+
+		It("coerces the values onto the struct fields", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(payload.Name).Should(Equal("joe"))
+			Ω(*payload.Age).Should(Equal(42))
+			Ω(payload.Ratio).Should(Equal(0.5))
+			Ω(*payload.Admin).Should(BeTrue())
+			Ω(*payload.Born).Should(Equal(time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC)))
+			Ω(payload.Tags).Should(Equal([]string{"a", "b"}))
+			Ω(payload.Scores).Should(Equal([]int{1, 2}))
+			Ω(payload.Address).ShouldNot(BeNil())
+			Ω(*payload.Address.City).Should(Equal("Paris"))
+			Ω(payload.Any).Should(Equal("x"))
+			Ω(payload.Ignored).Should(BeEmpty())
+		})
+	})
+
+	Context("with values that cannot be coerced", func() {
+		BeforeEach(func() {
+			body = "name=joe&age=old&scores=1,two"
+		})
+
+		It("returns invalid parameter type errors", func() {
+			Ω(err).Should(HaveOccurred())
+			merr, ok := err.(goa.MultiError)
+			Ω(ok).Should(BeTrue())
+			Ω(merr).Should(HaveLen(2))
+			terr := merr[0].(*goa.TypedError)
+			Ω(terr.ID).Should(Equal(goa.ErrorID(goa.ErrInvalidParamType)))
+			Ω(terr.Field).Should(Equal("age"))
+			Ω(payload.Age).Should(BeNil())
+			Ω(payload.Scores).Should(BeNil())
+		})
+	})
+})
+
+var _ = Describe("MultipartDecoder", func() {
+	var service *goa.Service
+	var decoder goa.DecoderFunc
+	var files map[string]string
+	var values map[string]string
+	var payload formPayload
+	var err error
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		decoder = goa.NewMultipartDecoder
+		values = map[string]string{"name": "joe", "age": "42"}
+		files = map[string]string{"avatar": "avatar content"}
+		payload = formPayload{}
+	})
+
+	JustBeforeEach(func() {
+		service.Decoder(decoder, "multipart/form-data")
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		for n, v := range values {
+			mw.WriteField(n, v)
+		}
+		for n, c := range files {
+			fw, _ := mw.CreateFormFile(n, n+".png")
+			fw.Write([]byte(c))
+		}
+		fw, _ := mw.CreateFormFile("pictures", "1.png")
+		fw.Write([]byte("1"))
+		fw, _ = mw.CreateFormFile("pictures", "2.png")
+		fw.Write([]byte("2"))
+		mw.Close()
+		req, _ := http.NewRequest("POST", "/", &buf)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		err = service.DecodeRequest(req, &payload)
+	})
+
+	AfterEach(func() {
+		if payload.Avatar != nil {
+			payload.Avatar.Close()
+		}
+		for _, p := range payload.Pictures {
+			p.Close()
+		}
+	})
+
+	It("decodes values and files", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(payload.Name).Should(Equal("joe"))
+		Ω(*payload.Age).Should(Equal(42))
+		Ω(payload.Avatar).ShouldNot(BeNil())
+		Ω(payload.Avatar.Filename).Should(Equal("avatar.png"))
+		Ω(payload.Avatar.Size).Should(Equal(int64(len("avatar content"))))
+		Ω(payload.Avatar.ContentType()).Should(Equal("application/octet-stream"))
+		content, err := ioutil.ReadAll(payload.Avatar)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(content)).Should(Equal("avatar content"))
+		Ω(payload.Pictures).Should(HaveLen(2))
+		Ω(payload.Pictures[1].Filename).Should(Equal("2.png"))
+	})
+
+	Context("with files larger than the memory limit", func() {
+		BeforeEach(func() {
+			decoder = goa.MultipartDecoderFunc(16, 0)
+			values = nil
+			files = map[string]string{"avatar": strings.Repeat("x", 100)}
+		})
+
+		It("streams the file content from a temporary file", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			content, err := ioutil.ReadAll(payload.Avatar)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(HaveLen(100))
+			Ω(payload.Avatar.Close()).ShouldNot(HaveOccurred())
+			content, err = ioutil.ReadAll(payload.Avatar)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(BeEmpty())
+		})
+	})
+
+	Context("with files larger than the file size limit", func() {
+		BeforeEach(func() {
+			decoder = goa.MultipartDecoderFunc(goa.DefaultMultipartMaxMemory, 10)
+			files = map[string]string{"avatar": strings.Repeat("x", 100)}
+		})
+
+		It("fails", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("larger than 10 bytes"))
+			Ω(payload.Avatar).Should(BeNil())
+		})
+	})
+
+	Context("with values that cannot be coerced", func() {
+		BeforeEach(func() {
+			values = map[string]string{"admin": "maybe"}
+		})
+
+		It("returns invalid parameter type errors and releases the files", func() {
+			Ω(err).Should(HaveOccurred())
+			merr, ok := err.(goa.MultiError)
+			Ω(ok).Should(BeTrue())
+			Ω(merr[0].(*goa.TypedError).Field).Should(Equal("admin"))
+			content, _ := ioutil.ReadAll(payload.Avatar)
+			Ω(content).Should(BeEmpty())
+		})
+	})
+})
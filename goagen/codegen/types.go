@@ -219,6 +219,8 @@ func GoNativeType(t design.DataType) string {
 			return "time.Time"
 		case design.AnyKind:
 			return "interface{}"
+		case design.FileKind:
+			return "*goa.FileUpload"
 		default:
 			panic(fmt.Sprintf("goa bug: unknown primitive type %#v", actual))
 		}
@@ -206,6 +206,24 @@ var _ = Describe("code generation", func() {
 				})
 			})
 
+			Context("of file types", func() {
+				BeforeEach(func() {
+					object = Object{
+						"foo":  &AttributeDefinition{Type: File},
+						"bars": &AttributeDefinition{Type: &Array{ElemType: &AttributeDefinition{Type: File}}},
+					}
+					required = nil
+				})
+
+				It("produces the struct go code", func() {
+					expected := "struct {\n" +
+						"	Bars []*goa.FileUpload `json:\"bars,omitempty\" xml:\"bars,omitempty\"`\n" +
+						"	Foo *goa.FileUpload `json:\"foo,omitempty\" xml:\"foo,omitempty\"`\n" +
+						"}"
+					Ω(st).Should(Equal(expected))
+				})
+			})
+
 			Context("of hash of objects", func() {
 				BeforeEach(func() {
 					elem := Object{
@@ -120,7 +120,7 @@ func RecursiveChecker(att *design.AttributeDefinition, nonzero, required bool, t
 func ValidationChecker(att *design.AttributeDefinition, nonzero, required bool, target, context string, depth int) string {
 	t := target
 	isPointer := !required && !nonzero
-	if isPointer && att.Type.IsPrimitive() {
+	if isPointer && att.Type.IsPrimitive() && att.Type.Kind() != design.FileKind {
 		t = "*" + t
 	}
 	data := map[string]interface{}{
@@ -279,7 +279,7 @@ const (
 
 	requiredValTmpl = `{{range $r := .required}}{{$catt := index $.attribute.Type.ToObject $r}}{{if eq $catt.Type.Kind 4}}{{tabs $.depth}}if {{$.target}}.{{goify $r true}} == "" {
 {{tabs $.depth}}	err = goa.MissingAttributeError(` + "`" + `{{$.context}}` + "`" + `, "{{$r}}", err)
-{{tabs $.depth}}}{{else if (not $catt.Type.IsPrimitive)}}{{tabs $.depth}}if {{$.target}}.{{goify $r true}} == nil {
+{{tabs $.depth}}}{{else if (or (not $catt.Type.IsPrimitive) (eq $catt.Type.Kind 12))}}{{tabs $.depth}}if {{$.target}}.{{goify $r true}} == nil {
 {{tabs $.depth}}	err = goa.MissingAttributeError(` + "`" + `{{$.context}}` + "`" + `, "{{$r}}", err)
 {{tabs $.depth}}}{{end}}
 {{end}}`
@@ -8,6 +8,10 @@ import (
 var (
 	// TargetPackage is the name of the generated Go package.
 	TargetPackage string
+
+	// TargetMux is the name of the request mux used by the generated code, "httprouter" for
+	// the default goa mux or "tree" for the mux returned by goa.NewTreeMux.
+	TargetMux string
 )
 
 // Command is the goa application code generator command line data structure.
@@ -24,11 +28,12 @@ func NewCommand() *Command {
 // RegisterFlags registers the command line flags with the given registry.
 func (c *Command) RegisterFlags(r codegen.FlagRegistry) {
 	r.Flags().StringVar(&TargetPackage, "pkg", "app", "Name of generated Go package containing controllers supporting code (contexts, media types, user types etc.)")
+	r.Flags().StringVar(&TargetMux, "mux", "httprouter", `Request mux used by the generated code: "httprouter" or "tree" to support overlapping routes and constrained wildcards`)
 }
 
 // Run simply calls the meta generator.
 func (c *Command) Run() ([]string, error) {
-	flags := map[string]string{"pkg": TargetPackage}
+	flags := map[string]string{"pkg": TargetPackage, "mux": TargetMux}
 	gen := meta.NewGenerator(
 		"genapp.Generate",
 		[]*codegen.ImportSpec{codegen.SimpleImport("github.com/goadesign/goa/goagen/gen_app")},
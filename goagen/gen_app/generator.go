@@ -70,6 +70,16 @@ func (g *Generator) Generate(api *design.APIDefinition) (_ []string, err error)
 	if api == nil {
 		return nil, fmt.Errorf("missing API definition, make sure design is properly initialized")
 	}
+	switch TargetMux {
+	case "", "httprouter":
+		// The default goa mux requires wildcards at the same position to have the same name.
+		if err := api.ValidateRouteWildcards(); err != nil {
+			return nil, err
+		}
+	case "tree":
+	default:
+		return nil, fmt.Errorf(`invalid mux %#v, must be "httprouter" or "tree"`, TargetMux)
+	}
 
 	go utils.Catch(nil, func() { g.Cleanup() })
 
@@ -99,6 +109,15 @@ func (g *Generator) Generate(api *design.APIDefinition) (_ []string, err error)
 	if err := g.generateUserTypes(api); err != nil {
 		return nil, err
 	}
+	if err := g.generateSecurity(api); err != nil {
+		return nil, err
+	}
+	if err := g.generateRateLimit(api); err != nil {
+		return nil, err
+	}
+	if err := g.generateErrors(api); err != nil {
+		return nil, err
+	}
 
 	return g.genfiles, nil
 }
@@ -167,6 +186,7 @@ func (g *Generator) generateContexts(api *design.APIDefinition) error {
 				Responses:    MergeResponses(r.Responses, a.Responses),
 				API:          api,
 				DefaultPkg:   TargetPackage,
+				Precondition: preconditionMediaType(api, r, a),
 			}
 			return ctxWr.Execute(&ctxData)
 		})
@@ -366,20 +386,61 @@ func (g *Generator) generateControllers(api *design.APIDefinition) error {
 			imports = append(imports, codegen.SimpleImport(packagePath))
 		}
 	}
+	if hasTimeout(api) || len(rateLimits(api)) > 0 {
+		imports = append(imports, codegen.SimpleImport("time"))
+	}
 	ctlWr.WriteHeader(title, TargetPackage, imports)
 	ctlWr.WriteInitService(encoders, decoders)
 	var controllersData []*ControllerTemplateData
+	// Routes explicitly defined with OPTIONS take precedence over the CORS preflight handlers.
+	preflights := make(map[string]bool)
 	api.IterateResources(func(r *design.ResourceDefinition) error {
-		data := &ControllerTemplateData{API: api, Resource: codegen.Goify(r.Name, true)}
+		return r.IterateActions(func(a *design.ActionDefinition) error {
+			for _, ro := range a.Routes {
+				if ro.Verb == "OPTIONS" {
+					preflights[ro.FullPath()] = true
+				}
+			}
+			return nil
+		})
+	})
+	api.IterateResources(func(r *design.ResourceDefinition) error {
+		data := &ControllerTemplateData{
+			API:      api,
+			Resource: codegen.Goify(r.Name, true),
+			Origins:  resourceOrigins(api, r),
+		}
 		err := r.IterateActions(func(a *design.ActionDefinition) error {
+			if len(data.Origins) > 0 {
+				for _, ro := range a.Routes {
+					if path := ro.FullPath(); !preflights[path] {
+						preflights[path] = true
+						data.PreflightPaths = append(data.PreflightPaths, path)
+					}
+				}
+			}
 			context := fmt.Sprintf("%s%sContext", codegen.Goify(a.Name, true), codegen.Goify(r.Name, true))
 			unmarshal := fmt.Sprintf("unmarshal%s%sPayload", codegen.Goify(a.Name, true), codegen.Goify(r.Name, true))
 			action := map[string]interface{}{
-				"Name":      codegen.Goify(a.Name, true),
-				"Routes":    a.Routes,
-				"Context":   context,
-				"Unmarshal": unmarshal,
-				"Payload":   a.Payload,
+				"Name":        codegen.Goify(a.Name, true),
+				"Routes":      a.Routes,
+				"Context":     context,
+				"Unmarshal":   unmarshal,
+				"Payload":     a.Payload,
+				"Timeout":     a.Timeout,
+				"Security":    a.Security,
+				"MaxBodySize": a.MaxBodySize,
+				"Metadata":    map[string][]string(a.Metadata),
+			}
+			if a.RequireIfMatch {
+				action["RequireIfMatch"] = true
+			}
+			if rl := actionRateLimit(api, r, a); rl != nil {
+				action["RateLimit"] = rl
+			}
+			if mediaTypes, ok := acceptableMediaTypes(r, a); ok {
+				action["NotAcceptable"] = true
+				action["MediaTypes"] = mediaTypes
 			}
 			data.Actions = append(data.Actions, action)
 			return nil
@@ -401,6 +462,158 @@ func (g *Generator) generateControllers(api *design.APIDefinition) error {
 	return ctlWr.FormatCode()
 }
 
+// resourceOrigins returns the CORS policies that apply to the given resource: the API policies
+// merged with the resource policies, the latter taking precedence. The policies are sorted so that
+// exact origins come first followed by the wildcard origins, longest first.
+func resourceOrigins(api *design.APIDefinition, r *design.ResourceDefinition) []*design.CORSDefinition {
+	merged := make(map[string]*design.CORSDefinition, len(api.Origins)+len(r.Origins))
+	for o, cors := range api.Origins {
+		merged[o] = cors
+	}
+	for o, cors := range r.Origins {
+		merged[o] = cors
+	}
+	origins := make([]*design.CORSDefinition, 0, len(merged))
+	for _, cors := range merged {
+		origins = append(origins, cors)
+	}
+	sort.Sort(byOriginPriority(origins))
+	return origins
+}
+
+// byOriginPriority sorts CORS definitions in the order their origins should be matched.
+type byOriginPriority []*design.CORSDefinition
+
+func (b byOriginPriority) Len() int      { return len(b) }
+func (b byOriginPriority) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byOriginPriority) Less(i, j int) bool {
+	wi, wj := strings.Contains(b[i].Origin, "*"), strings.Contains(b[j].Origin, "*")
+	if wi != wj {
+		return wj
+	}
+	if wi && len(b[i].Origin) != len(b[j].Origin) {
+		return len(b[i].Origin) > len(b[j].Origin)
+	}
+	return b[i].Origin < b[j].Origin
+}
+
+// preconditionMediaType returns the default view of the resource canonical media type if the
+// action modifies the resource and the media type defines an entity tag or a last modification
+// time, nil otherwise.
+func preconditionMediaType(api *design.APIDefinition, r *design.ResourceDefinition, a *design.ActionDefinition) *design.MediaTypeDefinition {
+	mt := api.MediaTypeWithIdentifier(r.MediaType)
+	if mt == nil || (!mt.HasETag() && mt.LastModified == "") {
+		return nil
+	}
+	if safeRoutes(a.Routes) {
+		return nil
+	}
+	p, _, err := mt.Project("default")
+	if err != nil {
+		return nil
+	}
+	return p
+}
+
+// safeRoutes returns true if all the given routes use the GET or HEAD method.
+func safeRoutes(routes []*design.RouteDefinition) bool {
+	for _, route := range routes {
+		if route.Verb != "GET" && route.Verb != "HEAD" {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptableMediaTypes returns the sorted media types of the action responses and true if the
+// action or its resource define a NotAcceptable response, nil and false otherwise.
+func acceptableMediaTypes(r *design.ResourceDefinition, a *design.ActionDefinition) ([]string, bool) {
+	notAcceptable := false
+	seen := make(map[string]bool)
+	var mediaTypes []string
+	for _, responses := range []map[string]*design.ResponseDefinition{r.Responses, a.Responses} {
+		for _, resp := range responses {
+			if resp.Status == 406 {
+				notAcceptable = true
+				continue
+			}
+			if resp.MediaType != "" && !seen[resp.MediaType] {
+				seen[resp.MediaType] = true
+				mediaTypes = append(mediaTypes, resp.MediaType)
+			}
+		}
+	}
+	if !notAcceptable {
+		return nil, false
+	}
+	sort.Strings(mediaTypes)
+	return mediaTypes, true
+}
+
+// actionRateLimit returns the data used to generate the rate limit middleware of the action, nil if
+// the action is not rate limited. The scope identifies the definition the rate limit comes from
+// so that all the actions inheriting it share the same limit.
+func actionRateLimit(api *design.APIDefinition, r *design.ResourceDefinition, a *design.ActionDefinition) map[string]interface{} {
+	rl := a.RateLimit
+	if rl == nil {
+		return nil
+	}
+	scope := r.Name + "." + a.Name
+	switch rl {
+	case r.RateLimit:
+		scope = r.Name
+	case api.RateLimit:
+		scope = "api"
+	}
+	key := "goa.ClientIPRateLimitKey"
+	switch {
+	case rl.APIKey != nil:
+		key = fmt.Sprintf("goa.APIKeyRateLimitKey(%q, %q)", rl.APIKey.In, rl.APIKey.Name)
+	case rl.KeyName != "":
+		key = fmt.Sprintf("rateLimitKey(service, %q)", rl.KeyName)
+	}
+	// Limits keyed by API key apply once the key has been validated by the security middleware.
+	afterSecurity := rl.APIKey != nil && a.Security != nil &&
+		a.Security.Scheme.SchemeName == rl.APIKey.SchemeName
+	return map[string]interface{}{
+		"Requests":      rl.Requests,
+		"Period":        rl.Period,
+		"Scope":         scope,
+		"Key":           key,
+		"AfterSecurity": afterSecurity,
+	}
+}
+
+// rateLimits returns the rate limits used by the API actions.
+func rateLimits(api *design.APIDefinition) []*design.RateLimitDefinition {
+	var res []*design.RateLimitDefinition
+	seen := make(map[*design.RateLimitDefinition]bool)
+	api.IterateResources(func(r *design.ResourceDefinition) error {
+		return r.IterateActions(func(a *design.ActionDefinition) error {
+			if a.RateLimit != nil && !seen[a.RateLimit] {
+				seen[a.RateLimit] = true
+				res = append(res, a.RateLimit)
+			}
+			return nil
+		})
+	})
+	return res
+}
+
+// hasTimeout returns true if at least one of the API actions defines a timeout.
+func hasTimeout(api *design.APIDefinition) bool {
+	found := false
+	api.IterateResources(func(r *design.ResourceDefinition) error {
+		return r.IterateActions(func(a *design.ActionDefinition) error {
+			if a.Timeout > 0 {
+				found = true
+			}
+			return nil
+		})
+	})
+	return found
+}
+
 // generateHrefs iterates through the API resources and generates the href factory methods.
 func (g *Generator) generateHrefs(api *design.APIDefinition) error {
 	hrefFile := filepath.Join(AppOutputDir(), "hrefs.go")
@@ -498,3 +711,84 @@ func (g *Generator) generateUserTypes(api *design.APIDefinition) error {
 	}
 	return utWr.FormatCode()
 }
+
+// generateSecurity generates the security scheme hooks if the API defines security schemes.
+func (g *Generator) generateSecurity(api *design.APIDefinition) error {
+	if len(api.SecuritySchemes) == 0 {
+		return nil
+	}
+	secFile := filepath.Join(AppOutputDir(), "security.go")
+	secWr, err := NewSecurityWriter(secFile)
+	if err != nil {
+		panic(err) // bug
+	}
+	title := fmt.Sprintf("%s: Application Security", api.Context())
+	imports := []*codegen.ImportSpec{
+		codegen.SimpleImport("fmt"),
+		codegen.SimpleImport("net/http"),
+		codegen.SimpleImport("golang.org/x/net/context"),
+		codegen.SimpleImport("github.com/goadesign/goa"),
+	}
+	secWr.WriteHeader(title, TargetPackage, imports)
+	g.genfiles = append(g.genfiles, secFile)
+	if err = secWr.Execute(api.SecuritySchemes); err != nil {
+		return err
+	}
+	return secWr.FormatCode()
+}
+
+// generateRateLimit generates the rate limit key hooks if the API actions use custom rate limit
+// keys.
+func (g *Generator) generateRateLimit(api *design.APIDefinition) error {
+	names := make(map[string]bool)
+	var keys []string
+	for _, rl := range rateLimits(api) {
+		if rl.KeyName != "" && !names[rl.KeyName] {
+			names[rl.KeyName] = true
+			keys = append(keys, rl.KeyName)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+	rlFile := filepath.Join(AppOutputDir(), "ratelimit.go")
+	rlWr, err := NewRateLimitWriter(rlFile)
+	if err != nil {
+		panic(err) // bug
+	}
+	title := fmt.Sprintf("%s: Application Rate Limit Keys", api.Context())
+	imports := []*codegen.ImportSpec{
+		codegen.SimpleImport("net/http"),
+		codegen.SimpleImport("golang.org/x/net/context"),
+		codegen.SimpleImport("github.com/goadesign/goa"),
+	}
+	rlWr.WriteHeader(title, TargetPackage, imports)
+	g.genfiles = append(g.genfiles, rlFile)
+	if err = rlWr.Execute(keys); err != nil {
+		return err
+	}
+	return rlWr.FormatCode()
+}
+
+// generateErrors generates the code that registers the errors declared in the design if any.
+func (g *Generator) generateErrors(api *design.APIDefinition) error {
+	if len(api.Errors) == 0 {
+		return nil
+	}
+	errFile := filepath.Join(AppOutputDir(), "errors.go")
+	errWr, err := NewErrorsWriter(errFile)
+	if err != nil {
+		panic(err) // bug
+	}
+	title := fmt.Sprintf("%s: Application Errors", api.Context())
+	imports := []*codegen.ImportSpec{
+		codegen.SimpleImport("github.com/goadesign/goa"),
+	}
+	errWr.WriteHeader(title, TargetPackage, imports)
+	g.genfiles = append(g.genfiles, errFile)
+	if err = errWr.Execute(api.Errors); err != nil {
+		return err
+	}
+	return errWr.FormatCode()
+}
@@ -215,6 +215,59 @@ var _ = Describe("Generate", func() {
 			})
 		})
 
+		Context("with a payload that has File attributes", func() {
+			BeforeEach(func() {
+				payload = &design.UserTypeDefinition{
+					AttributeDefinition: &design.AttributeDefinition{
+						Type: design.Object{
+							"avatar":  &design.AttributeDefinition{Type: design.File},
+							"caption": &design.AttributeDefinition{Type: design.String},
+						},
+						Validation: &dslengine.ValidationDefinition{Required: []string{"avatar"}},
+					},
+					TypeName: "UploadPayload",
+				}
+				design.Design.Resources["Widget"].Actions["get"].Payload = payload
+			})
+
+			It("generates FileUpload payload fields", func() {
+				Ω(genErr).Should(BeNil())
+
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "app", "contexts.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(string(content)).Should(MatchRegexp(`Avatar\s+\*goa\.FileUpload`))
+				Ω(string(content)).Should(ContainSubstring("if payload.Avatar == nil {"))
+
+				content, err = ioutil.ReadFile(filepath.Join(outDir, "app", "controllers.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(string(content)).Should(ContainSubstring("goa.CloseFiles(&payload)"))
+			})
+		})
+
+		Context("with the tree mux", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--mux=tree")
+			})
+
+			It("configures the service to use the tree mux", func() {
+				Ω(genErr).Should(BeNil())
+
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "app", "controllers.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(string(content)).Should(ContainSubstring("service.UseMux(goa.NewTreeMux())"))
+			})
+		})
+
+		Context("with an invalid mux", func() {
+			BeforeEach(func() {
+				os.Args = append(os.Args, "--mux=gorilla")
+			})
+
+			It("fails", func() {
+				Ω(genErr).Should(HaveOccurred())
+			})
+		})
+
 	})
 })
 
@@ -417,8 +470,11 @@ type WidgetController interface {
 }
 
 // MountWidgetController "mounts" a Widget resource controller on the given service.
-func MountWidgetController(service *goa.Service, ctrl WidgetController) {
+// version is the API version served by the controller, empty to serve requests regardless of the
+// version they target. See goa.VersionMux.
+func MountWidgetController(service *goa.Service, version string, ctrl WidgetController) {
 	initService(service)
+	mux := service.VersionedMux(version)
 	var h goa.Handler
 	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
 		rctx, err := NewGetWidgetContext(ctx)
@@ -427,7 +483,7 @@ func MountWidgetController(service *goa.Service, ctrl WidgetController) {
 		}
 		return ctrl.Get(rctx)
 	}
-	service.Mux.Handle("GET", "/:id", ctrl.MuxHandler("Get", h, nil))
+	mux.HandleRoute(ctrl.Route("GET", "/:id", "Get", nil), ctrl.MuxHandler("Get", h, nil))
 	service.Info("mount", "ctrl", "Widget", "action", "Get", "route", "GET /:id")
 }
 `
@@ -469,8 +525,11 @@ package app
 
 const controllersSlicePayloadCode = `
 // MountWidgetController "mounts" a Widget resource controller on the given service.
-func MountWidgetController(service *goa.Service, ctrl WidgetController) {
+// version is the API version served by the controller, empty to serve requests regardless of the
+// version they target. See goa.VersionMux.
+func MountWidgetController(service *goa.Service, version string, ctrl WidgetController) {
 	initService(service)
+	mux := service.VersionedMux(version)
 	var h goa.Handler
 	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
 		rctx, err := NewGetWidgetContext(ctx)
@@ -482,7 +541,7 @@ func MountWidgetController(service *goa.Service, ctrl WidgetController) {
 		}
 		return ctrl.Get(rctx)
 	}
-	service.Mux.Handle("GET", "/:id", ctrl.MuxHandler("Get", h, unmarshalGetWidgetPayload))
+	mux.HandleRoute(ctrl.Route("GET", "/:id", "Get", nil), ctrl.MuxHandler("Get", h, unmarshalGetWidgetPayload))
 	service.Info("mount", "ctrl", "Widget", "action", "Get", "route", "GET /:id")
 }
 
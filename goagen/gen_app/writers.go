@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"strings"
 	"text/template"
+	"time"
 
 	"sort"
 
@@ -56,6 +57,29 @@ type (
 		UserTypeTmpl *template.Template
 	}
 
+	// SecurityWriter generate code for the goa application security scheme hooks.
+	// The hooks make it possible to register the functions that validate the request credentials
+	// of the actions that use the security schemes.
+	SecurityWriter struct {
+		*codegen.SourceFile
+		SecurityTmpl *template.Template
+	}
+
+	// RateLimitWriter generate code for the goa application rate limit key hooks.
+	// The hooks make it possible to register the functions that identify the clients of the
+	// actions whose rate limit uses a custom key.
+	RateLimitWriter struct {
+		*codegen.SourceFile
+		RateLimitTmpl *template.Template
+	}
+
+	// ErrorsWriter generate code that registers the application errors declared in the design in
+	// the goa error catalog.
+	ErrorsWriter struct {
+		*codegen.SourceFile
+		ErrorsTmpl *template.Template
+	}
+
 	// ContextTemplateData contains all the information used by the template to render the context
 	// code for an action.
 	ContextTemplateData struct {
@@ -69,15 +93,21 @@ type (
 		Responses    map[string]*design.ResponseDefinition
 		API          *design.APIDefinition
 		DefaultPkg   string
+		// Precondition is the resource media type whose entity tag and last modification time
+		// are checked by the context Precondition method if any.
+		Precondition *design.MediaTypeDefinition
 	}
 
 	// ControllerTemplateData contains the information required to generate an action handler.
 	ControllerTemplateData struct {
 		API      *design.APIDefinition    // API definition
 		Resource string                   // Lower case plural resource name, e.g. "bottles"
-		Actions  []map[string]interface{} // Array of actions, each action has keys "Name", "Routes", "Context" and "Unmarshal"
+		Actions  []map[string]interface{} // Array of actions, each action has keys "Name", "Routes", "Context", "Unmarshal", "Payload", "Timeout", "Security", "RateLimit", "MaxBodySize", "Metadata", "NotAcceptable", "MediaTypes" and "RequireIfMatch"
 		Encoders []*EncoderTemplateData   // Encoder data
 		Decoders []*EncoderTemplateData   // Decoder data
+		Origins  []*design.CORSDefinition // CORS policies that apply to the resource actions, in match order
+		// PreflightPaths lists the action paths that get a CORS preflight OPTIONS handler.
+		PreflightPaths []string
 	}
 
 	// ResourceData contains the information required to generate the resource GoGenerator
@@ -180,11 +210,22 @@ func (w *ContextsWriter) Execute(data *ContextTemplateData) error {
 			return err
 		}
 	}
+	if data.Precondition != nil {
+		if err := w.ExecuteTemplate("precondition", ctxPreconditionT, nil, data); err != nil {
+			return err
+		}
+	}
 	fn = template.FuncMap{
 		"project": func(mt *design.MediaTypeDefinition, v string) *design.MediaTypeDefinition {
 			p, _, _ := mt.Project(v)
 			return p
 		},
+		"conditional": func(mt *design.MediaTypeDefinition, resp *design.ResponseDefinition) bool {
+			// Actions that modify resources check the conditions with Precondition
+			// prior to making the change, see ctxPreconditionT.
+			return safeRoutes(data.Routes) && (mt.HasETag() || mt.LastModified != "") &&
+				resp.Status >= 200 && resp.Status < 300
+		},
 	}
 	data.IterateResponses(func(resp *design.ResponseDefinition) error {
 		respData := map[string]interface{}{
@@ -234,6 +275,7 @@ func (w *ControllersWriter) WriteInitService(encoders, decoders []*EncoderTempla
 		"API":      design.Design,
 		"Encoders": encoders,
 		"Decoders": decoders,
+		"TreeMux":  TargetMux == "tree",
 	}
 	if err := w.ExecuteTemplate("service", serviceT, nil, ctx); err != nil {
 		return err
@@ -246,11 +288,20 @@ func (w *ControllersWriter) Execute(data []*ControllerTemplateData) error {
 	if len(data) == 0 {
 		return nil
 	}
+	fn := template.FuncMap{
+		"goduration": goDuration,
+		"gometadata": goMetadata,
+	}
 	for _, d := range data {
 		if err := w.ExecuteTemplate("controller", ctrlT, nil, d); err != nil {
 			return err
 		}
-		if err := w.ExecuteTemplate("mount", mountT, nil, d); err != nil {
+		if len(d.Origins) > 0 {
+			if err := w.ExecuteTemplate("origins", originsT, nil, d); err != nil {
+				return err
+			}
+		}
+		if err := w.ExecuteTemplate("mount", mountT, fn, d); err != nil {
 			return err
 		}
 		if err := w.ExecuteTemplate("unmarshal", unmarshalT, nil, d); err != nil {
@@ -329,6 +380,77 @@ func (w *UserTypesWriter) Execute(t *design.UserTypeDefinition) error {
 	return w.ExecuteTemplate("types", userTypeT, nil, t)
 }
 
+// NewSecurityWriter returns a security hooks code writer.
+func NewSecurityWriter(filename string) (*SecurityWriter, error) {
+	file, err := codegen.SourceFileFor(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &SecurityWriter{SourceFile: file}, nil
+}
+
+// Execute writes the code for the security scheme hooks to the writer.
+func (w *SecurityWriter) Execute(schemes []*design.SecuritySchemeDefinition) error {
+	if len(schemes) == 0 {
+		return nil
+	}
+	fn := template.FuncMap{
+		"validator": securityValidator,
+	}
+	return w.ExecuteTemplate("security", securityT, fn, schemes)
+}
+
+// NewRateLimitWriter returns a rate limit key hooks code writer.
+func NewRateLimitWriter(filename string) (*RateLimitWriter, error) {
+	file, err := codegen.SourceFileFor(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &RateLimitWriter{SourceFile: file}, nil
+}
+
+// Execute writes the code for the rate limit key hooks to the writer, keys lists the names of the
+// custom rate limit keys.
+func (w *RateLimitWriter) Execute(keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return w.ExecuteTemplate("ratelimit", rateLimitT, nil, keys)
+}
+
+// NewErrorsWriter returns an error registration code writer.
+func NewErrorsWriter(filename string) (*ErrorsWriter, error) {
+	file, err := codegen.SourceFileFor(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &ErrorsWriter{SourceFile: file}, nil
+}
+
+// Execute writes the code that registers the given errors to the writer.
+func (w *ErrorsWriter) Execute(errors []*design.ErrorDefinition) error {
+	if len(errors) == 0 {
+		return nil
+	}
+	return w.ExecuteTemplate("errors", errorsT, nil, errors)
+}
+
+// securityValidator returns the goa validator type and middleware constructor call used to
+// implement the given security scheme.
+func securityValidator(s *design.SecuritySchemeDefinition) map[string]string {
+	switch s.Kind {
+	case design.BasicAuthSecurityKind:
+		return map[string]string{"Type": "goa.BasicAuthValidator", "Middleware": "goa.BasicAuth(validator)"}
+	case design.APIKeySecurityKind:
+		return map[string]string{"Type": "goa.APIKeyValidator", "Middleware": fmt.Sprintf("goa.APIKeyAuth(%q, %q, validator)", s.In, s.Name)}
+	case design.JWTSecurityKind:
+		return map[string]string{"Type": "goa.TokenValidator", "Middleware": fmt.Sprintf("goa.JWTAuth(%q, %q, validator)", s.In, s.Name)}
+	case design.OAuth2SecurityKind:
+		return map[string]string{"Type": "goa.TokenValidator", "Middleware": "goa.OAuth2Auth(validator)"}
+	}
+	panic(fmt.Sprintf("unknown security scheme kind %d", s.Kind)) // bug
+}
+
 // newCoerceData is a helper function that creates a map that can be given to the "Coerce" template.
 func newCoerceData(name string, att *design.AttributeDefinition, pointer bool, pkg string, depth int) map[string]interface{} {
 	return map[string]interface{}{
@@ -341,6 +463,47 @@ func newCoerceData(name string, att *design.AttributeDefinition, pointer bool, p
 	}
 }
 
+// goDuration returns the Go code that produces the given duration, e.g. "30 * time.Second".
+func goDuration(d time.Duration) string {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d * %s", d/u.unit, u.name)
+		}
+	}
+	return fmt.Sprintf("time.Duration(%d)", int64(d))
+}
+
+// goMetadata returns the Go code that produces the given design metadata, "nil" if empty.
+func goMetadata(md map[string][]string) string {
+	if len(md) == 0 {
+		return "nil"
+	}
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	elems := make([]string, len(keys))
+	for i, k := range keys {
+		vals := make([]string, len(md[k]))
+		for j, v := range md[k] {
+			vals[j] = fmt.Sprintf("%q", v)
+		}
+		elems[i] = fmt.Sprintf("%q: {%s}", k, strings.Join(vals, ", "))
+	}
+	return fmt.Sprintf("map[string][]string{%s}", strings.Join(elems, ", "))
+}
+
 // arrayAttribute returns the array element attribute definition.
 func arrayAttribute(a *design.AttributeDefinition) *design.AttributeDefinition {
 	return a.Type.(*design.Array).ElemType
@@ -456,12 +619,26 @@ func New{{.Name}}(ctx context.Context) (*{{.Name}}, error) {
 	// template input: map[string]interface{}
 	ctxMTRespT = `{{$ctx := .Context}}{{$resp := .Response}}{{$mt := .MediaType}}{{/*
 */}}{{range $name, $view := $mt.Views}}{{if not (eq $name "link")}}{{$projected := project $mt $name}}
-// {{respName $resp $name}} sends a HTTP response with status code {{$resp.Status}}.
+// {{respName $resp $name}} sends a HTTP response with status code {{$resp.Status}}.{{$conditional := conditional $projected $resp}}{{if $conditional}}
+// It responds with status code 304 or 412 instead if the request conditions do not hold.{{end}}
 func (ctx *{{$ctx.Name}}) {{respName $resp $name}}(r {{gotyperef $projected $projected.AllRequired 0}}) error {
 	ctx.ResponseData.Header().Set("Content-Type", "{{$resp.MediaType}}")
-	return ctx.ResponseData.Send(ctx.Context, {{$resp.Status}}, r)
+	return ctx.ResponseData.{{if $conditional}}SendConditional{{else}}Send{{end}}(ctx.Context, {{$resp.Status}}, r)
 }
 {{end}}{{end}}
+`
+
+	// ctxPreconditionT generates the helper that checks the request conditions of actions that
+	// modify resources.
+	// template input: *ContextTemplateData
+	ctxPreconditionT = `{{$mt := .Precondition}}
+// Precondition evaluates the If-Match, If-None-Match and If-Unmodified-Since request headers
+// against the current state of the resource, current is nil if the resource does not exist.
+// It returns an error with status code 412 if a condition does not hold. Call Precondition prior
+// to modifying the resource.
+func (ctx *{{.Name}}) Precondition(current {{gotyperef $mt $mt.AllRequired 0}}) error {
+	return goa.CheckPreconditions(ctx.Context, current)
+}
 `
 
 	// ctxTRespT generates the response helpers for responses with overridden types.
@@ -518,7 +695,10 @@ func initService(service *goa.Service) {
 		return
 	}
 	inited = true
-
+{{if .TreeMux}}
+	// Setup mux
+	service.UseMux(goa.NewTreeMux())
+{{end}}
 	// Setup encoders and decoders
 {{range .Encoders}}{{/*
 */}}	service.Encoder({{.PackageName}}.{{.Function}}, "{{join .MIMETypes "\", \""}}")
@@ -538,10 +718,13 @@ func initService(service *goa.Service) {
 	// template input: *ControllerTemplateData
 	mountT = `
 // Mount{{.Resource}}Controller "mounts" a {{.Resource}} resource controller on the given service.
-func Mount{{.Resource}}Controller(service *goa.Service, ctrl {{.Resource}}Controller) {
+// version is the API version served by the controller, empty to serve requests regardless of the
+// version they target. See goa.VersionMux.
+func Mount{{.Resource}}Controller(service *goa.Service, version string, ctrl {{.Resource}}Controller) {
 	initService(service)
+	mux := service.VersionedMux(version)
 	var h goa.Handler
-{{$res := .Resource}}{{range .Actions}}{{$action := .}}	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+{{$res := .Resource}}{{$origins := .Origins}}{{range .Actions}}{{$action := .}}	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
 		rctx, err := New{{.Context}}(ctx)
 		if err != nil {
 			return goa.NewBadRequestError(err)
@@ -551,9 +734,106 @@ func Mount{{.Resource}}Controller(service *goa.Service, ctrl {{.Resource}}Contro
 		}
 		{{end}}		return ctrl.{{.Name}}(rctx)
 	}
-{{range .Routes}}	service.Mux.Handle("{{.Verb}}", "{{.FullPath}}", ctrl.MuxHandler("{{$action.Name}}", h, {{if $action.Payload}}{{$action.Unmarshal}}{{else}}nil{{end}}))
+{{if .NotAcceptable}}	h = goa.RequireAcceptable({{range $i, $mt := .MediaTypes}}{{if $i}}, {{end}}{{printf "%q" $mt}}{{end}})(h)
+{{end}}{{if .RequireIfMatch}}	h = goa.RequireIfMatch()(h)
+{{end}}{{with .RateLimit}}{{if .AfterSecurity}}	h = goa.RateLimit({{.Requests}}, {{goduration .Period}}, {{printf "%q" .Scope}}, {{.Key}})(h)
+{{end}}{{end}}{{if .Security}}	h = handleSecurity(service, {{printf "%q" .Security.Scheme.SchemeName}}, h{{range .Security.Scopes}}, {{printf "%q" .}}{{end}})
+{{end}}{{if .Timeout}}	h = goa.Timeout({{goduration .Timeout}})(h)
+{{end}}{{with .RateLimit}}{{if not .AfterSecurity}}	h = goa.RateLimit({{.Requests}}, {{goduration .Period}}, {{printf "%q" .Scope}}, {{.Key}})(h)
+{{end}}{{end}}{{if $origins}}	h = handle{{$res}}Origin(h)
+{{end}}{{range .Routes}}	mux.HandleRoute(ctrl.Route("{{.Verb}}", "{{.FullPath}}", "{{$action.Name}}", {{gometadata $action.Metadata}}), {{if $action.MaxBodySize}}ctrl.LimitedMuxHandler("{{$action.Name}}", h, {{if $action.Payload}}{{$action.Unmarshal}}{{else}}nil{{end}}, {{$action.MaxBodySize}}){{else}}ctrl.MuxHandler("{{$action.Name}}", h, {{if $action.Payload}}{{$action.Unmarshal}}{{else}}nil{{end}}){{end}})
 	service.Info("mount", "ctrl", "{{$res}}", "action", "{{$action.Name}}", "route", "{{.Verb}} {{.FullPath}}")
-{{end}}{{end}}}
+{{end}}{{end}}{{range .PreflightPaths}}	mux.HandleRoute(ctrl.Route("OPTIONS", "{{.}}", "preflight", nil), ctrl.MuxHandler("preflight", handle{{$res}}Origin(goa.HandlePreflight()), nil))
+	service.Info("mount", "ctrl", "{{$res}}", "action", "preflight", "route", "OPTIONS {{.}}")
+{{end}}}
+`
+
+	// originsT generates the code for the resource CORS handler.
+	// template input: *ControllerTemplateData
+	originsT = `
+// handle{{.Resource}}Origin applies the CORS response headers corresponding to the request origin.
+func handle{{.Resource}}Origin(h goa.Handler) goa.Handler {
+	return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		origin := req.Header.Get("Origin")
+		if origin == "" {
+			// Not a CORS request
+			return h(ctx, rw, req)
+		}
+{{range .Origins}}		if goa.MatchOrigin(origin, {{printf "%q" .Origin}}) {
+			ctx = goa.LogWith(ctx, "origin", origin)
+			rw.Header().Set("Access-Control-Allow-Origin", origin)
+			rw.Header().Add("Vary", "Origin")
+{{if .Exposed}}			rw.Header().Set("Access-Control-Expose-Headers", {{printf "%q" (join .Exposed ", ")}})
+{{end}}{{if .MaxAge}}			rw.Header().Set("Access-Control-Max-Age", "{{.MaxAge}}")
+{{end}}{{if .Credentials}}			rw.Header().Set("Access-Control-Allow-Credentials", "true")
+{{end}}{{if or .Methods .Headers}}			if goa.IsPreflight(req) {
+{{if .Methods}}				rw.Header().Set("Access-Control-Allow-Methods", {{printf "%q" (join .Methods ", ")}})
+{{end}}{{if .Headers}}				rw.Header().Set("Access-Control-Allow-Headers", {{printf "%q" (join .Headers ", ")}})
+{{end}}			}
+{{end}}			return h(ctx, rw, req)
+		}
+{{end}}		return h(ctx, rw, req)
+	}
+}
+`
+
+	// securityT generates the code for the security scheme hooks.
+	// template input: []*design.SecuritySchemeDefinition
+	securityT = `// securitySchemeKey is the private type used to store the security scheme middleware in the
+// service context.
+type securitySchemeKey string
+{{range .}}{{$validator := validator .}}
+// Use{{goify .SchemeName true}}Security sets the function used to validate the credentials of the requests made
+// to the actions that use the "{{.SchemeName}}" security scheme.
+func Use{{goify .SchemeName true}}Security(service *goa.Service, validator {{$validator.Type}}) {
+	service.Context = context.WithValue(service.Context, securitySchemeKey({{printf "%q" .SchemeName}}), {{$validator.Middleware}})
+}
+{{end}}
+// handleSecurity authenticates the request using the middleware registered for the given security
+// scheme prior to calling the handler. It fails the request if no middleware was registered.
+func handleSecurity(service *goa.Service, scheme string, h goa.Handler, scopes ...string) goa.Handler {
+	return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		m, ok := service.Context.Value(securitySchemeKey(scheme)).(goa.Middleware)
+		if !ok {
+			return fmt.Errorf("no validator registered for security scheme %#v", scheme)
+		}
+		return m(h)(goa.WithRequiredScopes(ctx, scopes), rw, req)
+	}
+}
+`
+
+	// rateLimitT generates the code for the rate limit key hooks.
+	// template input: []string
+	rateLimitT = `// rateLimitKeyName is the private type used to store the rate limit key functions in the service
+// context.
+type rateLimitKeyName string
+{{range .}}
+// Use{{goify . true}}RateLimitKey sets the function used to identify the clients of the actions whose rate
+// limit uses the "{{.}}" key.
+func Use{{goify . true}}RateLimitKey(service *goa.Service, key goa.RateLimitKeyFunc) {
+	service.Context = context.WithValue(service.Context, rateLimitKeyName({{printf "%q" .}}), key)
+}
+{{end}}
+// rateLimitKey returns a function that calls the rate limit key function registered for the given
+// name. The rate limit middleware identifies clients by IP if no function was registered.
+func rateLimitKey(service *goa.Service, name string) goa.RateLimitKeyFunc {
+	return func(ctx context.Context, req *http.Request) string {
+		if key, ok := service.Context.Value(rateLimitKeyName(name)).(goa.RateLimitKeyFunc); ok {
+			return key(ctx, req)
+		}
+		return ""
+	}
+}
+`
+
+	// errorsT generates the code that registers the errors declared in the design.
+	// template input: []*design.ErrorDefinition
+	errorsT = `// init registers the errors declared in the design in the goa error catalog.
+func init() {
+{{range .}}	if err := goa.RegisterError(&goa.ErrorDefinition{ID: {{.ID}}, Title: {{printf "%q" .Title}}, Status: {{.Status}}{{if .URL}}, URL: {{printf "%q" .URL}}{{end}}}); err != nil {
+		panic(err)
+	}
+{{end}}}
 `
 
 	// unmarshalT generates the code for an action payload unmarshal function.
@@ -566,6 +846,7 @@ func {{.Unmarshal}}(ctx context.Context, req *http.Request) error {
 		return err
 	}{{$validation := recursiveValidate .Payload.AttributeDefinition false false "payload" "raw" 1}}{{if $validation}}
 	if err := payload.Validate(); err != nil {
+		goa.CloseFiles(&payload)
 		return err
 	}{{end}}
 	goa.Request(ctx).Payload = {{if .Payload.IsObject}}&{{end}}payload
@@ -594,6 +875,22 @@ func (mt {{gotyperef . .AllRequired 0}}) Validate() (err error) {
 {{$validation}}
 	return
 }
+{{end}}{{if .HasETag}}
+// ETag returns the entity tag of the {{$typeName}} media type instance.
+func (mt {{gotyperef . .AllRequired 0}}) ETag() string {
+	if mt == nil {
+		return ""
+	}
+	return goa.{{if .ETag}}NewETag(mt.{{goify .ETag true}}){{else}}HashETag(mt){{end}}
+}
+{{end}}{{if .LastModified}}{{$ptr := .IsPrimitivePointer .LastModified}}
+// LastModified returns the last modification time of the {{$typeName}} media type instance.
+func (mt {{gotyperef . .AllRequired 0}}) LastModified() time.Time {
+	if mt == nil{{if $ptr}} || mt.{{goify .LastModified true}} == nil{{end}} {
+		return time.Time{}
+	}
+	return {{if $ptr}}*{{end}}mt.{{goify .LastModified true}}
+}
 {{end}}
 `
 
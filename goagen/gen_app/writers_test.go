@@ -3,6 +3,7 @@ package genapp_test
 import (
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/goadesign/goa/design"
 	"github.com/goadesign/goa/dslengine"
@@ -50,6 +51,8 @@ var _ = Describe("ContextsWriter", func() {
 			var payload *design.UserTypeDefinition
 			var responses map[string]*design.ResponseDefinition
 			var mediaTypes map[string]*design.MediaTypeDefinition
+			var precondition *design.MediaTypeDefinition
+			var routes []*design.RouteDefinition
 
 			var data *genapp.ContextTemplateData
 
@@ -59,6 +62,8 @@ var _ = Describe("ContextsWriter", func() {
 				payload = nil
 				responses = nil
 				mediaTypes = nil
+				precondition = nil
+				routes = nil
 				data = nil
 			})
 
@@ -73,6 +78,8 @@ var _ = Describe("ContextsWriter", func() {
 					Responses:    responses,
 					API:          design.Design,
 					DefaultPkg:   "",
+					Precondition: precondition,
+					Routes:       routes,
 				}
 			})
 
@@ -283,6 +290,53 @@ var _ = Describe("ContextsWriter", func() {
 				})
 			})
 
+			Context("with a response using a media type with an entity tag", func() {
+				BeforeEach(func() {
+					mt := taggedMediaType()
+					mediaTypes = map[string]*design.MediaTypeDefinition{mt.Identifier: mt}
+					design.Design = &design.APIDefinition{MediaTypes: mediaTypes}
+					design.GeneratedMediaTypes = make(design.MediaTypeRoot)
+					responses = map[string]*design.ResponseDefinition{
+						"OK": {Name: "OK", Status: 200, MediaType: mt.Identifier},
+					}
+				})
+
+				It("writes a response helper that evaluates the request conditions", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(conditionalResponse))
+				})
+
+				Context("and a precondition", func() {
+					BeforeEach(func() {
+						precondition, _, _ = mediaTypes["application/vnd.goa.tagged.bottle+json"].Project("default")
+						routes = []*design.RouteDefinition{{Verb: "PUT", Path: "/:id"}}
+					})
+
+					It("writes the Precondition helper", func() {
+						err := writer.Execute(data)
+						Ω(err).ShouldNot(HaveOccurred())
+						b, err := ioutil.ReadFile(filename)
+						Ω(err).ShouldNot(HaveOccurred())
+						written := string(b)
+						Ω(written).Should(ContainSubstring(preconditionHelper))
+					})
+
+					It("writes a response helper that does not evaluate the request conditions", func() {
+						err := writer.Execute(data)
+						Ω(err).ShouldNot(HaveOccurred())
+						b, err := ioutil.ReadFile(filename)
+						Ω(err).ShouldNot(HaveOccurred())
+						written := string(b)
+						Ω(written).Should(ContainSubstring(unconditionalResponse))
+						Ω(written).ShouldNot(ContainSubstring("SendConditional"))
+					})
+				})
+			})
+
 			Context("with a simple payload", func() {
 				BeforeEach(func() {
 					payload = &design.UserTypeDefinition{
@@ -394,6 +448,15 @@ var _ = Describe("ControllersWriter", func() {
 		Context("with data", func() {
 			var actions, verbs, paths, contexts, unmarshals []string
 			var payloads []*design.UserTypeDefinition
+			var timeouts []time.Duration
+			var securities []*design.SecurityDefinition
+			var acceptables [][]string
+			var maxBodySizes []int64
+			var ifMatches []bool
+			var metadatas []map[string][]string
+			var rateLimits []map[string]interface{}
+			var origins []*design.CORSDefinition
+			var preflightPaths []string
 			var encoders, decoders []*genapp.EncoderTemplateData
 
 			var data []*genapp.ControllerTemplateData
@@ -405,6 +468,15 @@ var _ = Describe("ControllersWriter", func() {
 				contexts = nil
 				unmarshals = nil
 				payloads = nil
+				timeouts = nil
+				securities = nil
+				acceptables = nil
+				maxBodySizes = nil
+				ifMatches = nil
+				metadatas = nil
+				rateLimits = nil
+				origins = nil
+				preflightPaths = nil
 				encoders = nil
 				decoders = nil
 			})
@@ -417,12 +489,20 @@ var _ = Describe("ControllersWriter", func() {
 				for i, a := range actions {
 					var unmarshal string
 					var payload *design.UserTypeDefinition
+					var timeout time.Duration
+					var security *design.SecurityDefinition
 					if i < len(unmarshals) {
 						unmarshal = unmarshals[i]
 					}
 					if i < len(payloads) {
 						payload = payloads[i]
 					}
+					if i < len(timeouts) {
+						timeout = timeouts[i]
+					}
+					if i < len(securities) {
+						security = securities[i]
+					}
 					as[i] = map[string]interface{}{
 						"Name": a,
 						"Routes": []*design.RouteDefinition{
@@ -433,6 +513,24 @@ var _ = Describe("ControllersWriter", func() {
 						"Context":   contexts[i],
 						"Unmarshal": unmarshal,
 						"Payload":   payload,
+						"Timeout":   timeout,
+						"Security":  security,
+					}
+					if i < len(rateLimits) {
+						as[i]["RateLimit"] = rateLimits[i]
+					}
+					if i < len(maxBodySizes) {
+						as[i]["MaxBodySize"] = maxBodySizes[i]
+					}
+					if i < len(acceptables) {
+						as[i]["NotAcceptable"] = true
+						as[i]["MediaTypes"] = acceptables[i]
+					}
+					if i < len(ifMatches) {
+						as[i]["RequireIfMatch"] = ifMatches[i]
+					}
+					if i < len(metadatas) {
+						as[i]["Metadata"] = metadatas[i]
 					}
 				}
 				if len(as) > 0 {
@@ -440,6 +538,8 @@ var _ = Describe("ControllersWriter", func() {
 					d.Actions = as
 					d.Encoders = encoders
 					d.Decoders = decoders
+					d.Origins = origins
+					d.PreflightPaths = preflightPaths
 					data = []*genapp.ControllerTemplateData{d}
 				} else {
 					data = nil
@@ -477,6 +577,208 @@ var _ = Describe("ControllersWriter", func() {
 				})
 			})
 
+			Context("with an action that has a timeout", func() {
+				BeforeEach(func() {
+					actions = []string{"List"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					timeouts = []time.Duration{30 * time.Second}
+				})
+
+				It("wraps the handler with the timeout middleware", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(timeoutMount))
+				})
+			})
+
+			Context("with an action that requires security", func() {
+				BeforeEach(func() {
+					actions = []string{"List"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					securities = []*design.SecurityDefinition{
+						{
+							Scheme: &design.SecuritySchemeDefinition{
+								Kind:       design.JWTSecurityKind,
+								SchemeName: "jwt",
+							},
+							Scopes: []string{"api:read"},
+						},
+					}
+				})
+
+				It("wraps the handler with the security middleware", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(securityMount))
+				})
+			})
+
+			Context("with an action that is rate limited", func() {
+				BeforeEach(func() {
+					actions = []string{"List"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					rateLimits = []map[string]interface{}{{
+						"Requests": 100,
+						"Period":   time.Minute,
+						"Scope":    "bottle",
+						"Key":      `goa.APIKeyRateLimitKey("header", "X-Api-Key")`,
+					}}
+				})
+
+				It("wraps the handler with the rate limit middleware", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(rateLimitMount))
+				})
+
+				Context("by API key once validated", func() {
+					BeforeEach(func() {
+						rateLimits[0]["AfterSecurity"] = true
+						securities = []*design.SecurityDefinition{
+							{
+								Scheme: &design.SecuritySchemeDefinition{
+									Kind:       design.APIKeySecurityKind,
+									SchemeName: "api_key",
+								},
+							},
+						}
+					})
+
+					It("applies the rate limit after the security middleware", func() {
+						err := writer.Execute(data)
+						Ω(err).ShouldNot(HaveOccurred())
+						b, err := ioutil.ReadFile(filename)
+						Ω(err).ShouldNot(HaveOccurred())
+						written := string(b)
+						Ω(written).Should(ContainSubstring(securedRateLimitMount))
+					})
+				})
+			})
+
+			Context("with an action that has metadata", func() {
+				BeforeEach(func() {
+					actions = []string{"List"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					metadatas = []map[string][]string{{"swagger:tag": {"bottle", "list"}, "owner": {"cellar"}}}
+				})
+
+				It("records the metadata with the route", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(metadataMount))
+				})
+			})
+
+			Context("with an action that limits the request body size", func() {
+				BeforeEach(func() {
+					actions = []string{"List"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					maxBodySizes = []int64{1024}
+				})
+
+				It("uses a limited mux handler", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(`ctrl.LimitedMuxHandler("List", h, nil, 1024)`))
+				})
+			})
+
+			Context("with an action that defines a NotAcceptable response", func() {
+				BeforeEach(func() {
+					actions = []string{"List"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					acceptables = [][]string{{"application/vnd.goa.bottle+json", "text/plain"}}
+				})
+
+				It("wraps the handler with the content negotiation middleware", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(acceptableMount))
+				})
+			})
+
+			Context("with an action that requires the If-Match header", func() {
+				BeforeEach(func() {
+					actions = []string{"Update"}
+					verbs = []string{"PUT"}
+					paths = []string{"/accounts/:accountID/bottles/:id"}
+					contexts = []string{"UpdateBottleContext"}
+					ifMatches = []bool{true}
+				})
+
+				It("wraps the handler with the RequireIfMatch middleware", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring("	h = goa.RequireIfMatch()(h)\n"))
+				})
+			})
+
+			Context("with CORS origins", func() {
+				BeforeEach(func() {
+					actions = []string{"List"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					origins = []*design.CORSDefinition{
+						{
+							Origin:      "http://swagger.goa.design",
+							Methods:     []string{"GET", "POST"},
+							Headers:     []string{"X-Shared-Secret"},
+							Exposed:     []string{"X-Time"},
+							MaxAge:      600,
+							Credentials: true,
+						},
+						{
+							Origin: "*",
+						},
+					}
+					preflightPaths = []string{"/accounts/:accountID/bottles"}
+				})
+
+				It("writes the CORS handler and mounts the preflight handlers", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(originsHandler))
+					Ω(written).Should(ContainSubstring(originsMount))
+				})
+			})
+
 			Context("with actions that take a payload", func() {
 				BeforeEach(func() {
 					actions = []string{"List"}
@@ -598,6 +900,68 @@ var _ = Describe("ControllersWriter", func() {
 	})
 })
 
+var _ = Describe("MediaTypesWriter", func() {
+	var writer *genapp.MediaTypesWriter
+	var workspace *codegen.Workspace
+	var filename string
+
+	BeforeEach(func() {
+		var err error
+		workspace, err = codegen.NewWorkspace("test")
+		Ω(err).ShouldNot(HaveOccurred())
+		pkg, err := workspace.NewPackage("media_types")
+		Ω(err).ShouldNot(HaveOccurred())
+		src := pkg.CreateSourceFile("test.go")
+		filename = src.Abs()
+		design.GeneratedMediaTypes = make(design.MediaTypeRoot)
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		writer, err = genapp.NewMediaTypesWriter(filename)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		workspace.Delete()
+	})
+
+	Context("with a media type that defines an entity tag and a last modification time", func() {
+		It("writes the ETag and LastModified methods", func() {
+			Ω(writer.Execute(taggedMediaType())).ShouldNot(HaveOccurred())
+			b, err := ioutil.ReadFile(filename)
+			Ω(err).ShouldNot(HaveOccurred())
+			written := string(b)
+			Ω(written).Should(ContainSubstring(taggedMediaTypeMethods))
+		})
+	})
+})
+
+// taggedMediaType returns a media type whose instances have an entity tag and a last modification
+// time.
+func taggedMediaType() *design.MediaTypeDefinition {
+	att := &design.AttributeDefinition{
+		Type: design.Object{
+			"revision":   {Type: design.String},
+			"updated_at": {Type: design.DateTime},
+		},
+		Validation: &dslengine.ValidationDefinition{Required: []string{"revision"}},
+	}
+	mt := &design.MediaTypeDefinition{
+		UserTypeDefinition: &design.UserTypeDefinition{
+			AttributeDefinition: att,
+			TypeName:            "TaggedBottle",
+		},
+		Identifier:   "application/vnd.goa.tagged.bottle+json",
+		ETag:         "revision",
+		LastModified: "updated_at",
+	}
+	mt.Views = map[string]*design.ViewDefinition{
+		"default": {AttributeDefinition: att, Name: "default", Parent: mt},
+	}
+	return mt
+}
+
 var _ = Describe("HrefWriter", func() {
 	var writer *genapp.ResourcesWriter
 	var workspace *codegen.Workspace
@@ -714,7 +1078,144 @@ var _ = Describe("HrefWriter", func() {
 	})
 })
 
+var _ = Describe("SecurityWriter", func() {
+	var writer *genapp.SecurityWriter
+	var workspace *codegen.Workspace
+	var filename string
+
+	BeforeEach(func() {
+		var err error
+		workspace, err = codegen.NewWorkspace("test")
+		Ω(err).ShouldNot(HaveOccurred())
+		pkg, err := workspace.NewPackage("controllers")
+		Ω(err).ShouldNot(HaveOccurred())
+		src := pkg.CreateSourceFile("test.go")
+		filename = src.Abs()
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		writer, err = genapp.NewSecurityWriter(filename)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		workspace.Delete()
+	})
+
+	Context("with security schemes", func() {
+		var schemes []*design.SecuritySchemeDefinition
+
+		BeforeEach(func() {
+			schemes = []*design.SecuritySchemeDefinition{
+				{Kind: design.BasicAuthSecurityKind, SchemeName: "basic"},
+				{Kind: design.APIKeySecurityKind, SchemeName: "api_key", In: "query", Name: "key"},
+			}
+		})
+
+		It("writes the security hooks", func() {
+			err := writer.Execute(schemes)
+			Ω(err).ShouldNot(HaveOccurred())
+			b, err := ioutil.ReadFile(filename)
+			Ω(err).ShouldNot(HaveOccurred())
+			written := string(b)
+			Ω(written).Should(ContainSubstring(basicSecurityHook))
+			Ω(written).Should(ContainSubstring(apiKeySecurityHook))
+			Ω(written).Should(ContainSubstring("func handleSecurity("))
+		})
+	})
+})
+
+var _ = Describe("RateLimitWriter", func() {
+	var writer *genapp.RateLimitWriter
+	var workspace *codegen.Workspace
+	var filename string
+
+	BeforeEach(func() {
+		var err error
+		workspace, err = codegen.NewWorkspace("test")
+		Ω(err).ShouldNot(HaveOccurred())
+		pkg, err := workspace.NewPackage("controllers")
+		Ω(err).ShouldNot(HaveOccurred())
+		src := pkg.CreateSourceFile("test.go")
+		filename = src.Abs()
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		writer, err = genapp.NewRateLimitWriter(filename)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		workspace.Delete()
+	})
+
+	Context("with custom keys", func() {
+		It("writes the rate limit key hooks", func() {
+			err := writer.Execute([]string{"user"})
+			Ω(err).ShouldNot(HaveOccurred())
+			b, err := ioutil.ReadFile(filename)
+			Ω(err).ShouldNot(HaveOccurred())
+			written := string(b)
+			Ω(written).Should(ContainSubstring(userRateLimitHook))
+			Ω(written).Should(ContainSubstring("func rateLimitKey("))
+		})
+	})
+})
+
+var _ = Describe("ErrorsWriter", func() {
+	var writer *genapp.ErrorsWriter
+	var workspace *codegen.Workspace
+	var filename string
+
+	BeforeEach(func() {
+		var err error
+		workspace, err = codegen.NewWorkspace("test")
+		Ω(err).ShouldNot(HaveOccurred())
+		pkg, err := workspace.NewPackage("controllers")
+		Ω(err).ShouldNot(HaveOccurred())
+		src := pkg.CreateSourceFile("test.go")
+		filename = src.Abs()
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		writer, err = genapp.NewErrorsWriter(filename)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		workspace.Delete()
+	})
+
+	Context("with errors declared in the design", func() {
+		It("writes the code that registers the errors", func() {
+			err := writer.Execute([]*design.ErrorDefinition{
+				{ID: 1000, Title: "bottle is empty", Status: 409, URL: "https://goa.design/errors#empty"},
+				{ID: 1001, Title: "cellar is full", Status: 422},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			b, err := ioutil.ReadFile(filename)
+			Ω(err).ShouldNot(HaveOccurred())
+			written := string(b)
+			Ω(written).Should(ContainSubstring(registerErrors))
+		})
+	})
+})
+
 const (
+	registerErrors = `// init registers the errors declared in the design in the goa error catalog.
+func init() {
+	if err := goa.RegisterError(&goa.ErrorDefinition{ID: 1000, Title: "bottle is empty", Status: 409, URL: "https://goa.design/errors#empty"}); err != nil {
+		panic(err)
+	}
+	if err := goa.RegisterError(&goa.ErrorDefinition{ID: 1001, Title: "cellar is full", Status: 422}); err != nil {
+		panic(err)
+	}
+}
+`
+
 	emptyContext = `
 type ListBottleContext struct {
 	context.Context
@@ -978,6 +1479,7 @@ func unmarshalListBottlePayload(ctx context.Context, req *http.Request) error {
 		return err
 	}
 	if err := payload.Validate(); err != nil {
+		goa.CloseFiles(&payload)
 		return err
 	}
 	goa.Request(ctx).Payload = &payload
@@ -1004,8 +1506,11 @@ type BottlesController interface {
 
 	encoderController = `
 // MountBottlesController "mounts" a Bottles resource controller on the given service.
-func MountBottlesController(service *goa.Service, ctrl BottlesController) {
+// version is the API version served by the controller, empty to serve requests regardless of the
+// version they target. See goa.VersionMux.
+func MountBottlesController(service *goa.Service, version string, ctrl BottlesController) {
 	initService(service)
+	mux := service.VersionedMux(version)
 	var h goa.Handler
 	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
 		rctx, err := NewListBottleContext(ctx)
@@ -1014,13 +1519,14 @@ func MountBottlesController(service *goa.Service, ctrl BottlesController) {
 		}
 		return ctrl.List(rctx)
 	}
-	service.Mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.MuxHandler("List", h, nil))
+	mux.HandleRoute(ctrl.Route("GET", "/accounts/:accountID/bottles", "List", nil), ctrl.MuxHandler("List", h, nil))
 	service.Info("mount", "ctrl", "Bottles", "action", "List", "route", "GET /accounts/:accountID/bottles")
 }
 `
 
-	simpleMount = `func MountBottlesController(service *goa.Service, ctrl BottlesController) {
+	simpleMount = `func MountBottlesController(service *goa.Service, version string, ctrl BottlesController) {
 	initService(service)
+	mux := service.VersionedMux(version)
 	var h goa.Handler
 	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
 		rctx, err := NewListBottleContext(ctx)
@@ -1029,9 +1535,100 @@ func MountBottlesController(service *goa.Service, ctrl BottlesController) {
 		}
 		return ctrl.List(rctx)
 	}
-	service.Mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.MuxHandler("List", h, nil))
+	mux.HandleRoute(ctrl.Route("GET", "/accounts/:accountID/bottles", "List", nil), ctrl.MuxHandler("List", h, nil))
 	service.Info("mount", "ctrl", "Bottles", "action", "List", "route", "GET /accounts/:accountID/bottles")
 }
+`
+
+	metadataMount = `	mux.HandleRoute(ctrl.Route("GET", "/accounts/:accountID/bottles", "List", map[string][]string{"owner": {"cellar"}, "swagger:tag": {"bottle", "list"}}), ctrl.MuxHandler("List", h, nil))
+`
+
+	timeoutMount = `		return ctrl.List(rctx)
+	}
+	h = goa.Timeout(30 * time.Second)(h)
+	mux.HandleRoute(ctrl.Route("GET", "/accounts/:accountID/bottles", "List", nil), ctrl.MuxHandler("List", h, nil))
+`
+
+	acceptableMount = `		return ctrl.List(rctx)
+	}
+	h = goa.RequireAcceptable("application/vnd.goa.bottle+json", "text/plain")(h)
+	mux.HandleRoute(ctrl.Route("GET", "/accounts/:accountID/bottles", "List", nil), ctrl.MuxHandler("List", h, nil))
+`
+
+	originsHandler = `// handleBottlesOrigin applies the CORS response headers corresponding to the request origin.
+func handleBottlesOrigin(h goa.Handler) goa.Handler {
+	return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		origin := req.Header.Get("Origin")
+		if origin == "" {
+			// Not a CORS request
+			return h(ctx, rw, req)
+		}
+		if goa.MatchOrigin(origin, "http://swagger.goa.design") {
+			ctx = goa.LogWith(ctx, "origin", origin)
+			rw.Header().Set("Access-Control-Allow-Origin", origin)
+			rw.Header().Add("Vary", "Origin")
+			rw.Header().Set("Access-Control-Expose-Headers", "X-Time")
+			rw.Header().Set("Access-Control-Max-Age", "600")
+			rw.Header().Set("Access-Control-Allow-Credentials", "true")
+			if goa.IsPreflight(req) {
+				rw.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+				rw.Header().Set("Access-Control-Allow-Headers", "X-Shared-Secret")
+			}
+			return h(ctx, rw, req)
+		}
+		if goa.MatchOrigin(origin, "*") {
+			ctx = goa.LogWith(ctx, "origin", origin)
+			rw.Header().Set("Access-Control-Allow-Origin", origin)
+			rw.Header().Add("Vary", "Origin")
+			return h(ctx, rw, req)
+		}
+		return h(ctx, rw, req)
+	}
+}
+`
+
+	basicSecurityHook = `func UseBasicSecurity(service *goa.Service, validator goa.BasicAuthValidator) {
+	service.Context = context.WithValue(service.Context, securitySchemeKey("basic"), goa.BasicAuth(validator))
+}
+`
+
+	apiKeySecurityHook = `func UseAPIKeySecurity(service *goa.Service, validator goa.APIKeyValidator) {
+	service.Context = context.WithValue(service.Context, securitySchemeKey("api_key"), goa.APIKeyAuth("query", "key", validator))
+}
+`
+
+	userRateLimitHook = `func UseUserRateLimitKey(service *goa.Service, key goa.RateLimitKeyFunc) {
+	service.Context = context.WithValue(service.Context, rateLimitKeyName("user"), key)
+}
+`
+
+	rateLimitMount = `		return ctrl.List(rctx)
+	}
+	h = goa.RateLimit(100, 1 * time.Minute, "bottle", goa.APIKeyRateLimitKey("header", "X-Api-Key"))(h)
+	mux.HandleRoute(ctrl.Route("GET", "/accounts/:accountID/bottles", "List", nil), ctrl.MuxHandler("List", h, nil))
+`
+
+	securedRateLimitMount = `		return ctrl.List(rctx)
+	}
+	h = goa.RateLimit(100, 1 * time.Minute, "bottle", goa.APIKeyRateLimitKey("header", "X-Api-Key"))(h)
+	h = handleSecurity(service, "api_key", h)
+	mux.HandleRoute(ctrl.Route("GET", "/accounts/:accountID/bottles", "List", nil), ctrl.MuxHandler("List", h, nil))
+`
+
+	securityMount = `		return ctrl.List(rctx)
+	}
+	h = handleSecurity(service, "jwt", h, "api:read")
+	mux.HandleRoute(ctrl.Route("GET", "/accounts/:accountID/bottles", "List", nil), ctrl.MuxHandler("List", h, nil))
+`
+
+	originsMount = `		return ctrl.List(rctx)
+	}
+	h = handleBottlesOrigin(h)
+	mux.HandleRoute(ctrl.Route("GET", "/accounts/:accountID/bottles", "List", nil), ctrl.MuxHandler("List", h, nil))
+	service.Info("mount", "ctrl", "Bottles", "action", "List", "route", "GET /accounts/:accountID/bottles")
+	mux.HandleRoute(ctrl.Route("OPTIONS", "/accounts/:accountID/bottles", "preflight", nil), ctrl.MuxHandler("preflight", handleBottlesOrigin(goa.HandlePreflight()), nil))
+	service.Info("mount", "ctrl", "Bottles", "action", "preflight", "route", "OPTIONS /accounts/:accountID/bottles")
+}
 `
 
 	multiController = `// BottlesController is the controller interface for the Bottles actions.
@@ -1042,8 +1639,9 @@ type BottlesController interface {
 }
 `
 
-	multiMount = `func MountBottlesController(service *goa.Service, ctrl BottlesController) {
+	multiMount = `func MountBottlesController(service *goa.Service, version string, ctrl BottlesController) {
 	initService(service)
+	mux := service.VersionedMux(version)
 	var h goa.Handler
 	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
 		rctx, err := NewListBottleContext(ctx)
@@ -1052,7 +1650,7 @@ type BottlesController interface {
 		}
 		return ctrl.List(rctx)
 	}
-	service.Mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.MuxHandler("List", h, nil))
+	mux.HandleRoute(ctrl.Route("GET", "/accounts/:accountID/bottles", "List", nil), ctrl.MuxHandler("List", h, nil))
 	service.Info("mount", "ctrl", "Bottles", "action", "List", "route", "GET /accounts/:accountID/bottles")
 	h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
 		rctx, err := NewShowBottleContext(ctx)
@@ -1061,7 +1659,7 @@ type BottlesController interface {
 		}
 		return ctrl.Show(rctx)
 	}
-	service.Mux.Handle("GET", "/accounts/:accountID/bottles/:id", ctrl.MuxHandler("Show", h, nil))
+	mux.HandleRoute(ctrl.Route("GET", "/accounts/:accountID/bottles/:id", "Show", nil), ctrl.MuxHandler("Show", h, nil))
 	service.Info("mount", "ctrl", "Bottles", "action", "Show", "route", "GET /accounts/:accountID/bottles/:id")
 }
 `
@@ -1069,5 +1667,50 @@ type BottlesController interface {
 	simpleResourceHref = `func BottleHref(id interface{}) string {
 	return fmt.Sprintf("/bottles/%v", id)
 }
+`
+
+	conditionalResponse = `
+// OK sends a HTTP response with status code 200.
+// It responds with status code 304 or 412 instead if the request conditions do not hold.
+func (ctx *ListBottleContext) OK(r *TaggedBottle) error {
+	ctx.ResponseData.Header().Set("Content-Type", "application/vnd.goa.tagged.bottle+json")
+	return ctx.ResponseData.SendConditional(ctx.Context, 200, r)
+}
+`
+
+	unconditionalResponse = `
+// OK sends a HTTP response with status code 200.
+func (ctx *ListBottleContext) OK(r *TaggedBottle) error {
+	ctx.ResponseData.Header().Set("Content-Type", "application/vnd.goa.tagged.bottle+json")
+	return ctx.ResponseData.Send(ctx.Context, 200, r)
+}
+`
+
+	preconditionHelper = `
+// Precondition evaluates the If-Match, If-None-Match and If-Unmodified-Since request headers
+// against the current state of the resource, current is nil if the resource does not exist.
+// It returns an error with status code 412 if a condition does not hold. Call Precondition prior
+// to modifying the resource.
+func (ctx *ListBottleContext) Precondition(current *TaggedBottle) error {
+	return goa.CheckPreconditions(ctx.Context, current)
+}
+`
+
+	taggedMediaTypeMethods = `
+// ETag returns the entity tag of the TaggedBottle media type instance.
+func (mt *TaggedBottle) ETag() string {
+	if mt == nil {
+		return ""
+	}
+	return goa.NewETag(mt.Revision)
+}
+
+// LastModified returns the last modification time of the TaggedBottle media type instance.
+func (mt *TaggedBottle) LastModified() time.Time {
+	if mt == nil || mt.UpdatedAt == nil {
+		return time.Time{}
+	}
+	return *mt.UpdatedAt
+}
 `
 )
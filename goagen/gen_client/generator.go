@@ -64,6 +64,7 @@ func (g *Generator) generateMain(mainFile string, clientPkg string, funcs templa
 		codegen.SimpleImport("os"),
 		codegen.SimpleImport("time"),
 		codegen.SimpleImport(clientPkg),
+		codegen.SimpleImport("github.com/goadesign/goa"),
 		codegen.SimpleImport("github.com/spf13/cobra"),
 	}
 	for _, pkg := range SignerPackages {
@@ -79,9 +80,10 @@ func (g *Generator) generateMain(mainFile string, clientPkg string, funcs templa
 	g.genfiles = append(g.genfiles, mainFile)
 
 	data := map[string]interface{}{
-		"API":     api,
-		"Signers": Signers,
-		"Version": Version,
+		"API":           api,
+		"Signers":       Signers,
+		"SchemeSigners": schemeSigners(api),
+		"Version":       Version,
 	}
 	if err := file.ExecuteTemplate("main", mainTmpl, nil, data); err != nil {
 		return err
@@ -435,6 +437,57 @@ func defaultPath(action *design.ActionDefinition) string {
 	return ""
 }
 
+// schemeSigners returns the data needed to create the signers of the API security schemes in the
+// client tool, one per scheme. Signers of the same type register the same command line flags so
+// the flags of all but the first signer of a given type - including the types listed with
+// --signer - are prefixed with the scheme name.
+func schemeSigners(api *design.APIDefinition) []map[string]string {
+	var signers []map[string]string
+	seen := make(map[string]bool)
+	for _, s := range Signers {
+		seen["&"+s] = true
+	}
+	names := make(map[string]bool)
+	for _, scheme := range api.SecuritySchemes {
+		if names[scheme.SchemeName] {
+			continue
+		}
+		names[scheme.SchemeName] = true
+		literal := signerLiteral(scheme)
+		kind := literal[:strings.Index(literal, "{")]
+		prefix := ""
+		if seen[kind] {
+			prefix = scheme.SchemeName
+		}
+		seen[kind] = true
+		signers = append(signers, map[string]string{
+			"Name":    codegen.Goify(scheme.SchemeName, true),
+			"Literal": literal,
+			"Prefix":  prefix,
+		})
+	}
+	return signers
+}
+
+// signerLiteral returns the Go code that creates the goa signer matching the given security
+// scheme.
+func signerLiteral(scheme *design.SecuritySchemeDefinition) string {
+	switch scheme.Kind {
+	case design.BasicAuthSecurityKind:
+		return "&goa.BasicSigner{}"
+	case design.APIKeySecurityKind:
+		return fmt.Sprintf("&goa.APIKeySigner{In: %q, Name: %q}", scheme.In, scheme.Name)
+	case design.JWTSecurityKind:
+		if scheme.In == "query" {
+			return fmt.Sprintf("&goa.APIKeySigner{In: %q, Name: %q}", scheme.In, scheme.Name)
+		}
+		return fmt.Sprintf("&goa.JWTSigner{Header: %q}", scheme.Name)
+	case design.OAuth2SecurityKind:
+		return "&goa.OAuth2Signer{}"
+	}
+	panic(fmt.Sprintf("unknown security scheme kind %d", scheme.Kind)) // bug
+}
+
 // appPkg returns the name of the generated application package
 func appPkg() string {
 	return path.Base(AppPkg)
@@ -452,12 +505,19 @@ func main() {
 	}
 	c := client.New()
 {{if .Signers}}	c.Signers = RegisterSigners(app)
+{{end}}{{range .SchemeSigners}}{{$tmp := tempvar}}	{{$tmp}} := {{.Literal}}
+{{if .Prefix}}	goa.RegisterSignerFlags(app, {{$tmp}}, "{{.Prefix}}")
+{{else}}	{{$tmp}}.RegisterFlags(app)
+{{end}}	c.Set{{.Name}}Signer({{$tmp}})
 {{end}}	c.UserAgent = "{{.API.Name}}-cli/{{.Version}}"
 	app.PersistentFlags().StringVarP(&c.Scheme, "scheme", "s", "{{if gt (len .API.Schemes) 0}}{{index .API.Schemes 0}}{{end}}", "Set the requests scheme")
 	app.PersistentFlags().StringVarP(&c.Host, "host", "H", "{{.API.Host}}", "API hostname")
 	app.PersistentFlags().DurationVarP(&c.Timeout, "timeout", "t", time.Duration(20) * time.Second, "Set the request timeout, defaults to 20s")
 	app.PersistentFlags().BoolVar(&c.Dump, "dump", false, "Dump HTTP request and response.")
 	app.PersistentFlags().BoolVar(&PrettyPrint, "pp", false, "Pretty print response body")
+	app.PersistentFlags().StringVar(&c.TLSCertFile, "tls-cert", "", "Client certificate file used for mutual TLS authentication")
+	app.PersistentFlags().StringVar(&c.TLSKeyFile, "tls-key", "", "Client certificate key file")
+	app.PersistentFlags().StringVar(&c.TLSCAFile, "tls-cacert", "", "CA bundle used to verify the server certificate")
 	RegisterCommands(app, c)
 	if err := app.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "request failed: %s", err)
@@ -613,7 +673,10 @@ func (c *Client) {{$funcName}}(path string{{if .Payload}}, payload {{if .Payload
 {{else}}{{$tmp := tempvar}}{{toString (goify $name false) $tmp $att}}
 	header.Set("{{$name}}", {{$tmp}})
 {{end}}{{end}}{{end}}	header.Set("Content-Type", "application/json")
-	return c.Client.Do(req)
+{{if .Security}}{{$signer := printf "%sSigner" (goify .Security.Scheme.SchemeName true)}}	if c.{{$signer}} != nil {
+		return c.Client.DoSigned(req, c.{{$signer}})
+	}
+{{end}}	return c.Client.Do(req)
 }
 `
 
@@ -621,7 +684,8 @@ const clientTmpl = `type (
 	// Client is the {{.Name}} service client.
 	Client struct {
 		*goa.Client
-	}
+{{range .SecuritySchemes}}		{{goify .SchemeName true}}Signer goa.Signer
+{{end}}	}
 
 	// ActionCommand represents a single action command as defined on the command line.
 	// Each command is associated with a generated client method and contains the logic to
@@ -638,7 +702,12 @@ const clientTmpl = `type (
 func New() *Client {
 	return &Client{Client: goa.NewClient()}
 }
-`
+{{range .SecuritySchemes}}{{$name := goify .SchemeName true}}
+// Set{{$name}}Signer sets the request signer for the {{.SchemeName}} security scheme.
+func (c *Client) Set{{$name}}Signer(signer goa.Signer) {
+	c.{{$name}}Signer = signer
+}
+{{end}}`
 
 // Takes map[string][]*design.ActionDefinition as input
 const registerCmdsT = `// RegisterCommands all the resource action subcommands to the application command line.
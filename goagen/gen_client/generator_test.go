@@ -105,9 +105,93 @@ var _ = Describe("Generate", func() {
 			Ω(len(strings.Split(string(content), "\n"))).Should(BeNumerically(">=", 3))
 			Ω(content).Should(ContainSubstring("var tmp2 int"))
 			Ω(content).Should(ContainSubstring(".Flags()"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "main.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`"tls-cert"`))
+			Ω(content).Should(ContainSubstring(`"tls-cacert"`))
+			_, err = gexec.Build(filepath.Join(testgenPackagePath, "client", "testapi-cli"))
+			Ω(err).ShouldNot(HaveOccurred())
+
+		})
+	})
+
+	Context("with an action that requires security", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			jwt := &design.SecuritySchemeDefinition{
+				Kind:       design.JWTSecurityKind,
+				SchemeName: "jwt",
+				In:         "header",
+				Name:       "Authorization",
+			}
+			design.Design = &design.APIDefinition{
+				Name:            "testapi",
+				Title:           "dummy API with a secure resource",
+				Description:     "I told you it's dummy",
+				SecuritySchemes: []*design.SecuritySchemeDefinition{jwt},
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name:     "show",
+								Security: &design.SecurityDefinition{Scheme: jwt},
+								Routes: []*design.RouteDefinition{
+									{
+										Verb: "GET",
+										Path: "",
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("signs the requests with the scheme signer", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring("c.Client.DoSigned(req, c.JwtSigner)"))
+			Ω(content).ShouldNot(ContainSubstring(".Sign(req)"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "main.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`&goa.JWTSigner{Header: "Authorization"}`))
+			Ω(content).Should(ContainSubstring("c.SetJwtSigner("))
 			_, err = gexec.Build(filepath.Join(testgenPackagePath, "client", "testapi-cli"))
 			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		Context("and schemes that use signers of the same type", func() {
+			BeforeEach(func() {
+				design.Design.SecuritySchemes[0].In = "query"
+				design.Design.SecuritySchemes[0].Name = "token"
+				key := &design.SecuritySchemeDefinition{
+					Kind:       design.APIKeySecurityKind,
+					SchemeName: "key",
+					In:         "header",
+					Name:       "X-Key",
+				}
+				design.Design.SecuritySchemes = append(design.Design.SecuritySchemes, key)
+			})
 
+			It("creates a signer for each scheme", func() {
+				Ω(genErr).Should(BeNil())
+				content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "main.go"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(content).Should(ContainSubstring(`&goa.APIKeySigner{In: "query", Name: "token"}`))
+				Ω(content).Should(ContainSubstring(`&goa.APIKeySigner{In: "header", Name: "X-Key"}`))
+				Ω(content).Should(ContainSubstring("c.SetJwtSigner("))
+				Ω(content).Should(ContainSubstring("c.SetKeySigner("))
+				Ω(content).Should(ContainSubstring(`goa.RegisterSignerFlags(app, tmp2, "key")`))
+				_, err = gexec.Build(filepath.Join(testgenPackagePath, "client", "testapi-cli"))
+				Ω(err).ShouldNot(HaveOccurred())
+			})
 		})
 	})
 })
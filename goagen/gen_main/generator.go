@@ -82,7 +82,6 @@ func (g *Generator) Generate(api *design.APIDefinition) (_ []string, err error)
 		swaggerPkg := path.Join(outPkg, "swagger")
 		imports := []*codegen.ImportSpec{
 			codegen.SimpleImport("github.com/goadesign/goa"),
-			codegen.SimpleImport("github.com/goadesign/middleware"),
 			codegen.SimpleImport(appPkg),
 			codegen.SimpleImport(swaggerPkg),
 		}
@@ -226,13 +225,13 @@ func main() {
 	service := goa.New("{{.Name}}")
 
 	// Setup middleware
-	service.Use(middleware.RequestID())
-	service.Use(middleware.LogRequest(true))
-	service.Use(middleware.Recover())
+	service.Use(goa.RequestID())
+	service.Use(goa.LogRequest(true))
+	service.Use(goa.Recover())
 {{$api := .API}}
 {{range $name, $res := $api.Resources}}{{$name := goify $res.Name true}}	// Mount "{{$res.Name}}" controller
 	{{$tmp := tempvar}}{{$tmp}} := New{{$name}}Controller(service)
-	{{targetPkg}}.Mount{{$name}}Controller(service, {{$tmp}})
+	{{targetPkg}}.Mount{{$name}}Controller(service, "", {{$tmp}})
 {{end}}{{if generateSwagger}}// Mount Swagger spec provider controller
 	swagger.MountController(service)
 {{end}}
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
+	"github.com/goadesign/goa"
 	"github.com/goadesign/goa/design"
 )
 
@@ -148,9 +151,62 @@ func APISchema(api *design.APIDefinition) *JSONSchema {
 		Properties:  propertiesFromDefs(Definitions, "#/definitions/"),
 		Links:       links,
 	}
+	// The error definition is not a resource so it is added after the properties are built
+	if _, ok := Definitions["error"]; !ok {
+		Definitions["error"] = ErrorSchema(api)
+	}
 	return &s
 }
 
+// ErrorDefinitions returns the definitions of the errors registered in the goa error catalog and
+// of the errors declared in the API design with the Error DSL sorted by id. Note that the catalog
+// only contains the errors registered by goa and by the design package: the errors registered by
+// other application packages are not known to goagen.
+func ErrorDefinitions(api *design.APIDefinition) []*goa.ErrorDefinition {
+	defs := goa.ErrorCatalog()
+	registered := make(map[goa.ErrorID]bool, len(defs))
+	for _, def := range defs {
+		registered[def.ID] = true
+	}
+	for _, def := range api.Errors {
+		if !registered[goa.ErrorID(def.ID)] {
+			defs = append(defs, &goa.ErrorDefinition{
+				ID:     goa.ErrorID(def.ID),
+				Title:  def.Title,
+				Status: def.Status,
+				URL:    def.URL,
+			})
+		}
+	}
+	sort.Sort(byErrorID(defs))
+	return defs
+}
+
+// ErrorSchema produces the JSON schema of the errors returned by the API. The schema lists the ids
+// of all the errors returned by ErrorDefinitions.
+func ErrorSchema(api *design.APIDefinition) *JSONSchema {
+	catalog := ErrorDefinitions(api)
+	ids := make([]interface{}, len(catalog))
+	docs := make([]string, len(catalog))
+	for i, def := range catalog {
+		ids[i] = int(def.ID)
+		docs[i] = fmt.Sprintf("%d: %s", def.ID, def.Title)
+		if def.URL != "" {
+			docs[i] += fmt.Sprintf(" (%s)", def.URL)
+		}
+	}
+	s := NewJSONSchema()
+	s.Title = "error"
+	s.Type = JSONObject
+	s.Description = "Error returned by the API, the possible ids are:\n" + strings.Join(docs, "\n")
+	s.Properties["id"] = &JSONSchema{Type: JSONInteger, Description: "Error id", Enum: ids}
+	s.Properties["title"] = &JSONSchema{Type: JSONString, Description: "Error title"}
+	s.Properties["msg"] = &JSONSchema{Type: JSONString, Description: "Error message"}
+	s.Properties["url"] = &JSONSchema{Type: JSONString, Format: "uri", Description: "Error documentation"}
+	s.Required = []string{"id", "title", "msg"}
+	return s
+}
+
 // GenerateResourceDefinition produces the JSON schema corresponding to the given API resource.
 // It stores the results in cachedSchema.
 func GenerateResourceDefinition(api *design.APIDefinition, r *design.ResourceDefinition) {
@@ -271,6 +327,9 @@ func TypeSchema(api *design.APIDefinition, t design.DataType) *JSONSchema {
 			s.Format = "double"
 		case design.IntegerKind:
 			s.Format = "int64"
+		case design.FileKind:
+			s.Type = JSONString
+			s.Format = "binary"
 		}
 	case *design.Array:
 		s.Type = JSONArray
@@ -472,3 +531,10 @@ func buildMediaTypeSchema(api *design.APIDefinition, mt *design.MediaTypeDefinit
 	}
 	buildAttributeSchema(api, s, mt.AttributeDefinition)
 }
+
+// byErrorID makes it possible to sort error definitions by id.
+type byErrorID []*goa.ErrorDefinition
+
+func (b byErrorID) Len() int           { return len(b) }
+func (b byErrorID) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byErrorID) Less(i, j int) bool { return b[i].ID < b[j].ID }
@@ -2,6 +2,7 @@ package genswagger
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -29,6 +30,8 @@ type (
 		Security            []map[string][]string            `json:"security,omitempty"`
 		Tags                []*Tag                           `json:"tags,omitempty"`
 		ExternalDocs        *ExternalDocs                    `json:"externalDocs,omitempty"`
+		CORS                []*CORS                          `json:"x-cors,omitempty"`
+		Errors              []*ErrorDefinition               `json:"x-errors,omitempty"`
 	}
 
 	// Info provides metadata about the API. The metadata can be used by the clients if needed,
@@ -63,6 +66,50 @@ type (
 		// Parameters is the list of parameters that are applicable for all the operations
 		// described under this path.
 		Parameters []*Parameter `json:"parameters,omitempty"`
+		// CORS lists the resource level CORS policies that apply to the operations described
+		// under this path, they override the API level policies with the same origin.
+		CORS []*CORS `json:"x-cors,omitempty"`
+	}
+
+	// CORS describes the CORS policy of an origin. It is rendered using the "x-cors" vendor
+	// extension.
+	CORS struct {
+		// Origin is the origin pattern the policy applies to.
+		Origin string `json:"origin"`
+		// Methods lists the HTTP methods allowed for the origin.
+		Methods []string `json:"methods,omitempty"`
+		// Headers lists the request headers allowed for the origin.
+		Headers []string `json:"headers,omitempty"`
+		// Expose lists the response headers exposed to the origin.
+		Expose []string `json:"expose,omitempty"`
+		// MaxAge is the number of seconds preflight responses may be cached.
+		MaxAge uint `json:"maxAge,omitempty"`
+		// Credentials indicates whether the origin may send credentials.
+		Credentials bool `json:"credentials,omitempty"`
+	}
+
+	// RateLimit describes the rate limit of an operation. It is rendered using the
+	// "x-rate-limit" vendor extension.
+	RateLimit struct {
+		// Requests is the number of requests allowed per period.
+		Requests int `json:"requests"`
+		// Period is the duration over which the requests are counted, e.g. "1m0s".
+		Period string `json:"period"`
+		// Key describes how clients are identified: "ip", "apikey" or "custom".
+		Key string `json:"key"`
+	}
+
+	// ErrorDefinition describes a type of error the API may return, see goa.RegisterError and
+	// the Error DSL. It is rendered using the "x-errors" vendor extension.
+	ErrorDefinition struct {
+		// ID is the error id.
+		ID int `json:"id"`
+		// Title is the human friendly error title.
+		Title string `json:"title"`
+		// Status is the default HTTP status code of the responses.
+		Status int `json:"status,omitempty"`
+		// URL is the address of the error documentation if any.
+		URL string `json:"url,omitempty"`
 	}
 
 	// Operation describes a single API operation on a path.
@@ -95,6 +142,8 @@ type (
 		Deprecated bool `json:"deprecated,omitempty"`
 		// Secury is a declaration of which security schemes are applied for this operation.
 		Security []map[string][]string `json:"security,omitempty"`
+		// RateLimit describes the rate limit that applies to the operation if any.
+		RateLimit *RateLimit `json:"x-rate-limit,omitempty"`
 	}
 
 	// Parameter describes a single operation parameter.
@@ -207,7 +256,7 @@ type (
 		Name string `json:"name,omitempty"`
 		// In is the location of the API key when type is "apiKey".
 		// Valid values are "query" or "header".
-		In string `json:"in"`
+		In string `json:"in,omitempty"`
 		// Flow is the flow used by the OAuth2 security scheme when type is "oauth2"
 		// Valid values are "implicit", "password", "application" or "accessCode".
 		Flow string `json:"flow,omitempty"`
@@ -215,14 +264,9 @@ type (
 		AuthorizationURL string `json:"authorizationUrl,omitempty"`
 		// TokenURL  is the token URL to be used for this flow.
 		TokenURL string `json:"tokenUrl,omitempty"`
-		// Scopes list the  available scopes for the OAuth2 security scheme.
-		Scopes map[string]*Scope `json:"scopes,omitempty"`
-	}
-
-	// Scope corresponds to an available scope for an OAuth2 security scheme.
-	Scope struct {
-		// Description for scope
-		Description string `json:"description,omitempty"`
+		// Scopes list the available scopes for the OAuth2 security scheme indexed by name,
+		// the values are the scope descriptions.
+		Scopes map[string]string `json:"scopes,omitempty"`
 	}
 
 	// ExternalDocs allows referencing an external resource for extended documentation.
@@ -324,6 +368,14 @@ func New(api *design.APIDefinition) (*Swagger, error) {
 		Parameters:   paramMap,
 		Tags:         tags,
 		ExternalDocs: docsFromDefinition(api.Docs),
+		CORS:         corsFromDefinition(api.Origins),
+		Errors:       errorsFromCatalog(api),
+	}
+	if len(api.SecuritySchemes) > 0 {
+		s.SecurityDefinitions = make(map[string]*SecurityDefinition, len(api.SecuritySchemes))
+		for _, scheme := range api.SecuritySchemes {
+			s.SecurityDefinitions[scheme.SchemeName] = securityDefinitionFromDefinition(scheme)
+		}
 	}
 
 	err = api.IterateResponses(func(r *design.ResponseDefinition) error {
@@ -365,6 +417,122 @@ func New(api *design.APIDefinition) (*Swagger, error) {
 	return s, nil
 }
 
+func corsFromDefinition(origins map[string]*design.CORSDefinition) []*CORS {
+	if len(origins) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(origins))
+	for o := range origins {
+		keys = append(keys, o)
+	}
+	sort.Strings(keys)
+	res := make([]*CORS, len(keys))
+	for i, k := range keys {
+		o := origins[k]
+		res[i] = &CORS{
+			Origin:      o.Origin,
+			Methods:     o.Methods,
+			Headers:     o.Headers,
+			Expose:      o.Exposed,
+			MaxAge:      o.MaxAge,
+			Credentials: o.Credentials,
+		}
+	}
+	return res
+}
+
+func rateLimitFromDefinition(rl *design.RateLimitDefinition) *RateLimit {
+	if rl == nil {
+		return nil
+	}
+	return &RateLimit{Requests: rl.Requests, Period: rl.Period.String(), Key: rl.Key()}
+}
+
+// rateLimitedResponse describes the responses sent to clients that exceed the rate limit.
+func rateLimitedResponse() *Response {
+	return &Response{
+		Description: "Too Many Requests",
+		Headers: map[string]*Header{
+			"Retry-After":           {Description: "Number of seconds to wait before retrying", Type: "integer"},
+			"X-RateLimit-Limit":     {Description: "Number of requests allowed per period", Type: "integer"},
+			"X-RateLimit-Remaining": {Description: "Number of requests left for the current period", Type: "integer"},
+			"X-RateLimit-Reset":     {Description: "Number of seconds until the limit is reset", Type: "integer"},
+		},
+	}
+}
+
+// errorsFromCatalog returns the definitions of the errors registered in the goa error catalog and
+// of the errors declared in the design, see genschema.ErrorDefinitions.
+func errorsFromCatalog(api *design.APIDefinition) []*ErrorDefinition {
+	catalog := genschema.ErrorDefinitions(api)
+	res := make([]*ErrorDefinition, len(catalog))
+	for i, def := range catalog {
+		res[i] = &ErrorDefinition{
+			ID:     int(def.ID),
+			Title:  def.Title,
+			Status: def.Status,
+			URL:    def.URL,
+		}
+	}
+	return res
+}
+
+func securityDefinitionFromDefinition(scheme *design.SecuritySchemeDefinition) *SecurityDefinition {
+	def := &SecurityDefinition{
+		Type:        scheme.Type(),
+		Description: scheme.Description,
+	}
+	switch scheme.Kind {
+	case design.APIKeySecurityKind, design.JWTSecurityKind:
+		def.In = scheme.In
+		def.Name = scheme.Name
+	case design.OAuth2SecurityKind:
+		def.Flow = scheme.Flow
+		def.AuthorizationURL = scheme.AuthorizationURL
+		def.TokenURL = scheme.TokenURL
+		def.Scopes = scheme.Scopes
+	}
+	if scheme.Kind == design.JWTSecurityKind {
+		// Swagger 2.0 does not support JWT, document the token URL and scopes in the
+		// description.
+		var extra []string
+		if scheme.TokenURL != "" {
+			extra = append(extra, fmt.Sprintf("**Token URL**: %s", scheme.TokenURL))
+		}
+		if len(scheme.Scopes) > 0 {
+			names := make([]string, 0, len(scheme.Scopes))
+			for n := range scheme.Scopes {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			scopes := make([]string, len(names))
+			for i, n := range names {
+				scopes[i] = fmt.Sprintf("  * `%s`: %s", n, scheme.Scopes[n])
+			}
+			extra = append(extra, "**Scopes**:\n"+strings.Join(scopes, "\n"))
+		}
+		if len(extra) > 0 {
+			if def.Description != "" {
+				extra = append([]string{def.Description}, extra...)
+			}
+			def.Description = strings.Join(extra, "\n\n")
+		}
+	}
+	return def
+}
+
+func securityFromDefinition(sec *design.SecurityDefinition) []map[string][]string {
+	if sec == nil || sec.Scheme == nil {
+		return nil
+	}
+	scopes := sec.Scopes
+	if scopes == nil {
+		// Swagger requires an empty array for schemes that do not use scopes
+		scopes = []string{}
+	}
+	return []map[string][]string{{sec.Scheme.SchemeName: scopes}}
+}
+
 func tagsFromDefinition(mdata dslengine.MetadataDefinition) (tags []*Tag, err error) {
 	for key, value := range mdata {
 		chunks := strings.Split(key, ":")
@@ -463,6 +631,34 @@ func paramsFromDefinition(params *design.AttributeDefinition, path string) ([]*P
 	return res, nil
 }
 
+// formParamsFromDefinition returns the "formData" parameters that describe the attributes of a
+// payload sent with a multipart/form-data request.
+func formParamsFromDefinition(payload *design.UserTypeDefinition) ([]*Parameter, error) {
+	obj := payload.Type.ToObject()
+	if obj == nil {
+		return nil, fmt.Errorf("invalid payload definition, not an object")
+	}
+	var res []*Parameter
+	obj.IterateAttributes(func(n string, at *design.AttributeDefinition) error {
+		param := &Parameter{
+			Name:        n,
+			Default:     at.DefaultValue,
+			Description: at.Description,
+			Required:    payload.IsRequired(n),
+			In:          "formData",
+			Type:        at.Type.Name(),
+		}
+		if at.Type.IsArray() {
+			param.Items = itemsFromDefinition(at.Type.ToArray().ElemType)
+			param.CollectionFormat = "multi"
+		}
+		initValidations(at, param)
+		res = append(res, param)
+		return nil
+	})
+	return res, nil
+}
+
 func itemsFromDefinition(at *design.AttributeDefinition) *Items {
 	items := &Items{Type: at.Type.Name()}
 	initValidations(at, items)
@@ -554,7 +750,35 @@ func buildPathFromDefinition(s *Swagger, api *design.APIDefinition, route *desig
 		}
 		responses[strconv.Itoa(r.Status)] = resp
 	}
-	if action.Payload != nil {
+	if action.RateLimit != nil {
+		if _, ok := responses["429"]; !ok {
+			responses["429"] = rateLimitedResponse()
+		}
+	}
+	if action.RequireIfMatch {
+		params = append(params, &Parameter{
+			Name:        "If-Match",
+			In:          "header",
+			Description: "Entity tag of the resource",
+			Required:    true,
+			Type:        "string",
+		})
+		if _, ok := responses["412"]; !ok {
+			responses["412"] = &Response{Description: "Precondition Failed"}
+		}
+		if _, ok := responses["428"]; !ok {
+			responses["428"] = &Response{Description: "Precondition Required"}
+		}
+	}
+	var consumes []string
+	if action.HasFilePayload() {
+		formParams, err := formParamsFromDefinition(action.Payload)
+		if err != nil {
+			return err
+		}
+		params = append(params, formParams...)
+		consumes = design.MultipartContentTypes
+	} else if action.Payload != nil {
 		payloadSchema := genschema.TypeSchema(api, action.Payload)
 		pp := &Parameter{
 			Name:        "payload",
@@ -585,15 +809,18 @@ func buildPathFromDefinition(s *Swagger, api *design.APIDefinition, route *desig
 		Description:  action.Description,
 		ExternalDocs: docsFromDefinition(action.Docs),
 		OperationID:  operationID,
+		Consumes:     consumes,
 		Parameters:   params,
 		Responses:    responses,
 		Schemes:      schemes,
 		Deprecated:   false,
+		Security:     securityFromDefinition(action.Security),
+		RateLimit:    rateLimitFromDefinition(action.RateLimit),
 	}
 	key := design.WildcardRegex.ReplaceAllStringFunc(
 		route.FullPath(),
 		func(w string) string {
-			return fmt.Sprintf("/{%s}", w[2:])
+			return fmt.Sprintf("/{%s}", design.WildcardRegex.FindStringSubmatch(w)[1])
 		},
 	)
 	if key == "" {
@@ -603,7 +830,7 @@ func buildPathFromDefinition(s *Swagger, api *design.APIDefinition, route *desig
 	var path *Path
 	var ok bool
 	if path, ok = s.Paths[key]; !ok {
-		path = new(Path)
+		path = &Path{CORS: corsFromDefinition(action.Parent.Origins)}
 		s.Paths[key] = path
 	}
 	switch route.Verb {
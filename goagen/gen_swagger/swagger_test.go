@@ -2,8 +2,10 @@ package genswagger_test
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/go-swagger/go-swagger/spec"
+	"github.com/goadesign/goa"
 	_ "github.com/goadesign/goa-cellar/design"
 	. "github.com/goadesign/goa/design"
 	. "github.com/goadesign/goa/design/apidsl"
@@ -40,6 +42,25 @@ var _ = Describe("New", func() {
 		swagger, newErr = genswagger.New(Design)
 	})
 
+	Context("with errors declared in the design", func() {
+		BeforeEach(func() {
+			API("test", func() {
+				Error(1000, "bottle is empty", 409, "https://goa.design/errors#empty")
+			})
+		})
+
+		It("documents the declared errors", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			Ω(swagger.Errors).ShouldNot(BeEmpty())
+			Ω(swagger.Errors[len(swagger.Errors)-1]).Should(Equal(&genswagger.ErrorDefinition{
+				ID:     1000,
+				Title:  "bottle is empty",
+				Status: 409,
+				URL:    "https://goa.design/errors#empty",
+			}))
+		})
+	})
+
 	Context("with a valid API definition", func() {
 		const (
 			title        = "title"
@@ -118,6 +139,7 @@ var _ = Describe("New", func() {
 					Description: docDesc,
 					URL:         docURL,
 				},
+				Errors: swagger.Errors, // See "with the goa error catalog"
 			}))
 		})
 
@@ -281,6 +303,159 @@ var _ = Describe("New", func() {
 			It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
 		})
 
+		Context("with CORS origins", func() {
+			BeforeEach(func() {
+				base := Design.DSLFunc
+				Design.DSLFunc = func() {
+					base()
+					Origin("http://swagger.goa.design", func() {
+						Methods("GET", "POST")
+						Headers("X-Shared-Secret")
+						Expose("X-Time")
+						MaxAge(600)
+						Credentials()
+					})
+				}
+				Resource("res", func() {
+					Origin("*", func() {
+						Methods("GET")
+					})
+					Action("list", func() {
+						Routing(GET("/bottles"))
+						Response(NoContent)
+					})
+				})
+			})
+
+			It("documents the origins with vendor extensions", func() {
+				Ω(newErr).ShouldNot(HaveOccurred())
+				Ω(swagger.CORS).Should(Equal([]*genswagger.CORS{{
+					Origin:      "http://swagger.goa.design",
+					Methods:     []string{"GET", "POST"},
+					Headers:     []string{"X-Shared-Secret"},
+					Expose:      []string{"X-Time"},
+					MaxAge:      600,
+					Credentials: true,
+				}}))
+				Ω(swagger.Paths["/bottles"]).ShouldNot(BeNil())
+				Ω(swagger.Paths["/bottles"].CORS).Should(Equal([]*genswagger.CORS{{
+					Origin:  "*",
+					Methods: []string{"GET"},
+				}}))
+			})
+
+			It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
+		})
+
+		Context("with the goa error catalog", func() {
+			It("documents the errors with vendor extensions", func() {
+				Ω(newErr).ShouldNot(HaveOccurred())
+				Ω(swagger.Errors).ShouldNot(BeEmpty())
+				Ω(swagger.Errors[0]).Should(Equal(&genswagger.ErrorDefinition{
+					ID:     int(goa.ErrInvalidParamType),
+					Title:  "invalid parameter value",
+					Status: 400,
+				}))
+			})
+
+			It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
+		})
+
+		Context("with security schemes", func() {
+			BeforeEach(func() {
+				basic := BasicAuthSecurity("basic")
+				OAuth2Security("oauth2", func() {
+					ImplicitFlow("https://goa.design/authorize")
+					Scope("api:read", "read access")
+				})
+				Resource("res", func() {
+					Security(basic)
+					Action("list", func() {
+						Routing(GET("/bottles"))
+						Security("oauth2", "api:read")
+						Response(NoContent)
+					})
+					Action("health", func() {
+						Routing(GET("/health"))
+						NoSecurity()
+						Response(NoContent)
+					})
+					Action("show", func() {
+						Routing(GET("/bottles/:id"))
+						Response(NoContent)
+					})
+				})
+			})
+
+			It("sets the security definitions and requirements", func() {
+				Ω(newErr).ShouldNot(HaveOccurred())
+				Ω(swagger.SecurityDefinitions).Should(HaveLen(2))
+				Ω(swagger.SecurityDefinitions["basic"].Type).Should(Equal("basic"))
+				oauth2 := swagger.SecurityDefinitions["oauth2"]
+				Ω(oauth2.Type).Should(Equal("oauth2"))
+				Ω(oauth2.Flow).Should(Equal("implicit"))
+				Ω(oauth2.AuthorizationURL).Should(Equal("https://goa.design/authorize"))
+				Ω(oauth2.Scopes).Should(Equal(map[string]string{"api:read": "read access"}))
+				Ω(swagger.Paths["/bottles"].Get.Security).Should(Equal([]map[string][]string{{"oauth2": {"api:read"}}}))
+				Ω(swagger.Paths["/bottles/{id}"].Get.Security).Should(Equal([]map[string][]string{{"basic": {}}}))
+				Ω(swagger.Paths["/health"].Get.Security).Should(BeNil())
+			})
+
+			It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
+		})
+
+		Context("with a rate limit", func() {
+			BeforeEach(func() {
+				Resource("res", func() {
+					RateLimit(100, time.Minute)
+					Action("list", func() {
+						Routing(GET("/bottles"))
+						Response(NoContent)
+					})
+				})
+			})
+
+			It("documents the rate limit and the 429 response", func() {
+				Ω(newErr).ShouldNot(HaveOccurred())
+				op := swagger.Paths["/bottles"].Get
+				Ω(op.RateLimit).Should(Equal(&genswagger.RateLimit{Requests: 100, Period: "1m0s", Key: "ip"}))
+				Ω(op.Responses).Should(HaveKey("429"))
+				Ω(op.Responses["429"].Headers).Should(HaveKey("Retry-After"))
+			})
+
+			It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
+		})
+
+		Context("with an action that requires the If-Match header", func() {
+			BeforeEach(func() {
+				Resource("res", func() {
+					Action("update", func() {
+						Routing(PUT("/bottles/:id"))
+						RequireIfMatch()
+						Response(NoContent)
+					})
+				})
+			})
+
+			It("documents the If-Match header and the precondition responses", func() {
+				Ω(newErr).ShouldNot(HaveOccurred())
+				op := swagger.Paths["/bottles/{id}"].Put
+				var ifMatch *genswagger.Parameter
+				for _, p := range op.Parameters {
+					if p.Name == "If-Match" {
+						ifMatch = p
+					}
+				}
+				Ω(ifMatch).ShouldNot(BeNil())
+				Ω(ifMatch.In).Should(Equal("header"))
+				Ω(ifMatch.Required).Should(BeTrue())
+				Ω(op.Responses).Should(HaveKey("412"))
+				Ω(op.Responses).Should(HaveKey("428"))
+			})
+
+			It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
+		})
+
 		Context("with resources", func() {
 			BeforeEach(func() {
 				Origin := MediaType("application/vnd.goa.example.origin", func() {
@@ -395,4 +570,46 @@ var _ = Describe("New", func() {
 			It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
 		})
 	})
+
+	Context("with an action payload that has File attributes", func() {
+		BeforeEach(func() {
+			API("test", func() {
+				Consumes("application/json")
+				Consumes("multipart/form-data")
+			})
+			Resource("users", func() {
+				Action("upload", func() {
+					Routing(POST("/users/:id/avatar"))
+					Payload(func() {
+						Attribute("avatar", File, "The avatar image")
+						Attribute("thumbnails", ArrayOf(File))
+						Attribute("caption", String)
+						Required("avatar")
+					})
+					Response(NoContent)
+				})
+			})
+		})
+
+		It("documents the payload attributes as form data parameters", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			op := swagger.Paths["/users/{id}/avatar"].Post
+			Ω(op.Consumes).Should(Equal([]string{"multipart/form-data"}))
+			params := make(map[string]*genswagger.Parameter)
+			for _, p := range op.Parameters {
+				params[p.Name] = p
+			}
+			Ω(params).ShouldNot(HaveKey("payload"))
+			Ω(params).Should(HaveKey("avatar"))
+			Ω(params["avatar"].In).Should(Equal("formData"))
+			Ω(params["avatar"].Type).Should(Equal("file"))
+			Ω(params["avatar"].Required).Should(BeTrue())
+			Ω(params["thumbnails"].Type).Should(Equal("array"))
+			Ω(params["thumbnails"].Items.Type).Should(Equal("file"))
+			Ω(params["caption"].Type).Should(Equal("string"))
+			Ω(params["caption"].Required).Should(BeFalse())
+		})
+
+		It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
+	})
 })
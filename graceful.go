@@ -3,35 +3,67 @@
 package goa
 
 import (
+	"crypto/tls"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"gopkg.in/tylerb/graceful.v1"
 )
 
 // GracefulService is a goa application using a graceful shutdown server.
-// When sending any of the signals listed in InterruptSignals to the process GracefulService:
+// When sending any of the signals listed in InterruptSignals to the process or when calling
+// Shutdown GracefulService:
+//
+// * makes the readiness endpoint of the health controller report that the service is not ready
+// and waits for ReadinessDelay.
 //
 // * disables keepalive connections.
 //
 // * closes the listening socket, allowing another process to listen on that port immediately.
 //
-// * calls Cancel, signaling all active handlers.
+// * lets the in-flight requests complete for at most DrainTimeout then calls CancelAll,
+// signaling all active handlers.
+//
+// * closes the remaining connections once ShutdownTimeout has elapsed.
+//
+// * runs the hooks registered with OnShutdown.
 type GracefulService struct {
 	*Service
 	sync.Mutex
-	server *graceful.Server
+	servers []*graceful.Server
+	running int
+	signals sync.Once
 
 	// Interrupted is true if the application is in the process of shutting down.
 	Interrupted bool
 
-	// CancelOnShutdown tells whether existing requests should be canceled when shutdown is
-	// triggered (true) or whether to wait until the requests complete (false).
+	// CancelOnShutdown tells whether existing requests should be canceled when the servers
+	// stop (true) or whether to wait until the requests complete (false).
 	CancelOnShutdown bool
+
+	// ReadinessDelay is the time during which the readiness endpoint reports that the service
+	// is not ready before the servers stop once shutdown is triggered. This gives the load
+	// balancers polling the endpoint the time to stop routing requests to the service before
+	// the listeners close. Zero means that the servers stop right away.
+	ReadinessDelay time.Duration
+
+	// DrainTimeout is the time given to the in-flight requests to complete once the servers
+	// stop, the request contexts are canceled after that. Zero means that the contexts are
+	// never canceled unless CancelOnShutdown is true.
+	DrainTimeout time.Duration
+
+	// ShutdownTimeout is the time after which the connections still open are closed, measured
+	// from the time the servers stop. It should be greater than DrainTimeout to give the
+	// handlers a chance to honor the context cancellation. Zero means no deadline.
+	ShutdownTimeout time.Duration
+
+	hooks      []func() error
+	drainTimer *time.Timer
 }
 
 // InterruptSignals is the list of signals that initiate graceful shutdown.
@@ -49,46 +81,178 @@ func NewGraceful(name string, cancelOnShutdown bool) *GracefulService {
 	return &GracefulService{Service: service, CancelOnShutdown: cancelOnShutdown}
 }
 
-// ListenAndServe starts the HTTP server and sets up a listener on the given host/port.
+// OnShutdown registers a hook run once the server has stopped, e.g. to flush metrics or close
+// database connection pools. The hooks run in the order they were registered before
+// ListenAndServe or ListenAndServeTLS return. Errors returned by the hooks are logged.
+func (serv *GracefulService) OnShutdown(hook func() error) {
+	serv.Lock()
+	defer serv.Unlock()
+	serv.hooks = append(serv.hooks, hook)
+}
+
+// ListenAndServe starts the HTTP server and sets up a listener on the given address, see Listen
+// for the supported address formats.
 func (serv *GracefulService) ListenAndServe(addr string) error {
-	serv.setup(addr)
-	serv.Info("started", "transport", "http", "addr", addr)
-	if err := serv.server.ListenAndServe(); err != nil {
-		// there may be a final "accept" error after completion of graceful shutdown
-		// which can be safely ignored here.
-		if opErr, ok := err.(*net.OpError); !ok || (ok && opErr.Op != "accept") {
-			return err
-		}
+	l, err := Listen(addr)
+	if err != nil {
+		return err
 	}
-	return nil
+	return serv.Serve(l)
 }
 
-// ListenAndServeTLS starts a HTTPS server and sets up a listener on the given host/port.
+// ListenAndServeTLS starts a HTTPS server and sets up a listener on the given address, see Listen
+// for the supported address formats.
 func (serv *GracefulService) ListenAndServeTLS(addr, certFile, keyFile string) error {
-	serv.setup(addr)
-	serv.Info("started", "transport", "https", "addr", addr)
-	return serv.server.ListenAndServeTLS(certFile, keyFile)
+	l, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+	return serv.ServeTLS(l, certFile, keyFile)
 }
 
-// Shutdown initiates graceful shutdown of the running server once. Returns true on
-// initial shutdown and false if already shutting down.
+// Serve accepts HTTP connections on the given listener. Serve may be called concurrently with
+// different listeners, e.g. to serve both HTTP and HTTPS or the listeners returned by
+// InheritedListeners. All the listeners are closed on shutdown and the shutdown hooks run once
+// all the servers have stopped.
+func (serv *GracefulService) Serve(l net.Listener) error {
+	return serv.serve(l, "http")
+}
+
+// ServeTLS accepts HTTPS connections on the given listener using the given certificate and key
+// files, see Serve. The files are reloaded when they change, see NewTLSConfig.
+func (serv *GracefulService) ServeTLS(l net.Listener, certFile, keyFile string) error {
+	config, err := NewTLSConfig(certFile, keyFile)
+	if err != nil {
+		l.Close()
+		return err
+	}
+	return serv.ServeTLSConfig(l, config)
+}
+
+// ListenAndServeTLSConfig starts a HTTPS server using the given TLS configuration and sets up a
+// listener on the given address, see Listen for the supported address formats.
+func (serv *GracefulService) ListenAndServeTLSConfig(addr string, config *tls.Config) error {
+	l, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+	return serv.ServeTLSConfig(l, config)
+}
+
+// ServeTLSConfig accepts HTTPS connections on the given listener using the given TLS
+// configuration, see Serve.
+func (serv *GracefulService) ServeTLSConfig(l net.Listener, config *tls.Config) error {
+	return serv.serve(tls.NewListener(l, config), "https")
+}
+
+// Shutdown initiates graceful shutdown of the running servers once. Returns true on
+// initial shutdown and false if already shutting down. The readiness endpoint of the
+// health controller reports that the service is not ready from then on and the servers
+// stop once ReadinessDelay has elapsed. Shutdown may be called from any goroutine to stop
+// the servers programmatically, it does not wait for the readiness delay.
 func (serv *GracefulService) Shutdown() bool {
-	IncrCounter([]string{"goa", "graceful", "restart"}, 1.0)
+	serv.metrics().IncrCounter([]string{"goa", "graceful", "restart"}, 1.0)
 	serv.Lock()
 	defer serv.Unlock()
 	if serv.Interrupted {
 		return false
 	}
 	serv.Interrupted = true
-	serv.server.Stop(0)
+	serv.setShuttingDown()
+	if serv.ReadinessDelay > 0 {
+		serv.Info("not ready", "delay", serv.ReadinessDelay.String())
+		time.AfterFunc(serv.ReadinessDelay, func() {
+			serv.Lock()
+			defer serv.Unlock()
+			serv.stop()
+		})
+		return true
+	}
+	serv.stop()
+	return true
+}
+
+// stop stops the running servers and schedules the cancellation of the in-flight requests, the
+// caller must hold the lock.
+func (serv *GracefulService) stop() {
+	serv.Info("draining", "drain", serv.DrainTimeout.String(), "timeout", serv.ShutdownTimeout.String())
+	for _, server := range serv.servers {
+		server.Stop(serv.ShutdownTimeout)
+	}
 	if serv.CancelOnShutdown {
 		serv.CancelAll()
+	} else if serv.DrainTimeout > 0 {
+		serv.drainTimer = time.AfterFunc(serv.DrainTimeout, func() {
+			serv.Warn("drain timeout expired, canceling requests", "drain", serv.DrainTimeout.String())
+			serv.CancelAll()
+		})
 	}
-	return true
 }
 
-// setup initializes the interrupt handler and the underlying graceful server.
-func (serv *GracefulService) setup(addr string) {
+// serve runs a graceful server that accepts connections on l until shutdown.
+func (serv *GracefulService) serve(l net.Listener, transport string) error {
+	serv.signals.Do(serv.handleSignals)
+
+	// the timeout is the hard deadline, by default zero (i.e. no forced shutdown
+	// timeout) so requests can run as long as they want. there is usually a hard
+	// limit to when the response must come back (e.g. the nginx timeout) before
+	// being abandoned so the handler should implement some kind of internal
+	// timeout (e.g. the go context deadline) instead of relying on a shutdown
+	// timeout.
+	addr := l.Addr().String()
+	server := &graceful.Server{
+		Timeout:          serv.ShutdownTimeout,
+		Server:           &http.Server{Addr: addr, Handler: serv.Mux},
+		NoSignalHandling: true,
+	}
+	serv.Lock()
+	if serv.Interrupted {
+		serv.Unlock()
+		l.Close()
+		return nil
+	}
+	serv.servers = append(serv.servers, server)
+	serv.running++
+	serv.Unlock()
+
+	serv.Info("started", "transport", transport, "addr", addr)
+	err := server.Serve(l)
+	if err != nil {
+		// there may be a final "accept" error after completion of graceful shutdown
+		// which can be safely ignored here.
+		if opErr, ok := err.(*net.OpError); ok && opErr.Op == "accept" {
+			err = nil
+		}
+	}
+
+	serv.Lock()
+	serv.running--
+	last := serv.running == 0 && serv.Interrupted
+	serv.Unlock()
+	if last {
+		serv.stopped()
+	}
+	return err
+}
+
+// stopped runs the shutdown hooks once the servers have stopped.
+func (serv *GracefulService) stopped() {
+	serv.Lock()
+	if serv.drainTimer != nil {
+		serv.drainTimer.Stop()
+	}
+	hooks := serv.hooks
+	serv.Unlock()
+	for i, hook := range hooks {
+		if err := hook(); err != nil {
+			serv.Error("shutdown hook failed", "hook", i, "err", err)
+		}
+	}
+	serv.Info("stopped", "hooks", len(hooks))
+}
+
+// handleSignals initializes the interrupt handler.
+func (serv *GracefulService) handleSignals() {
 	// we will trap interrupts here instead of allowing the graceful package to do
 	// it for us. the graceful package has the odd behavior of stopping the
 	// interrupt handler after first interrupt. this leads to the dreaded double-
@@ -107,15 +271,4 @@ func (serv *GracefulService) setup(addr string) {
 			}
 		}
 	}()
-
-	// note the use of zero timeout (i.e. no forced shutdown timeout) so requests
-	// can run as long as they want. there is usually a hard limit to when the
-	// response must come back (e.g. the nginx timeout) before being abandoned so
-	// the handler should implement some kind of internal timeout (e.g. the go
-	// context deadline) instead of relying on a shutdown timeout.
-	serv.server = &graceful.Server{
-		Timeout:          0,
-		Server:           &http.Server{Addr: addr, Handler: serv.Mux},
-		NoSignalHandling: true,
-	}
 }
@@ -0,0 +1,113 @@
+// +build !appengine
+
+package goa_test
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("GracefulService", func() {
+	var service *goa.GracefulService
+	var addr string
+	var started, canceled chan struct{}
+	var hooks []string
+	var served chan error
+
+	BeforeEach(func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Ω(err).ShouldNot(HaveOccurred())
+		addr = l.Addr().String()
+		l.Close()
+
+		started = make(chan struct{})
+		canceled = make(chan struct{})
+		hooks = nil
+		service = goa.NewGraceful("test", false)
+		service.DrainTimeout = 20 * time.Millisecond
+		service.ShutdownTimeout = time.Second
+		service.OnShutdown(func() error { hooks = append(hooks, "metrics"); return nil })
+		service.OnShutdown(func() error { hooks = append(hooks, "db"); return nil })
+		ctrl := service.NewController("test")
+		h := ctrl.MuxHandler("wait", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			close(started)
+			<-ctx.Done()
+			close(canceled)
+			rw.WriteHeader(503)
+			return nil
+		}, nil)
+		service.Mux.Handle("GET", "/wait", h)
+		service.MountHealthController("", "/readyz", "")
+
+		served = make(chan error, 1)
+		go func() { served <- service.ListenAndServe(addr) }()
+		Eventually(func() error {
+			c, err := net.Dial("tcp", addr)
+			if err == nil {
+				c.Close()
+			}
+			return err
+		}).ShouldNot(HaveOccurred())
+	})
+
+	It("cancels the requests once the drain timeout expires and runs the hooks", func() {
+		go http.Get("http://" + addr + "/wait")
+		Eventually(started).Should(BeClosed())
+		Ω(service.Shutdown()).Should(BeTrue())
+		Ω(service.Shutdown()).Should(BeFalse())
+		Consistently(canceled, 10*time.Millisecond).ShouldNot(BeClosed())
+		Eventually(canceled).Should(BeClosed())
+		var err error
+		Eventually(served).Should(Receive(&err))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(hooks).Should(Equal([]string{"metrics", "db"}))
+	})
+
+	Context("with a readiness delay", func() {
+		BeforeEach(func() {
+			service.ReadinessDelay = 200 * time.Millisecond
+		})
+
+		It("reports that the service is not ready while the listener still accepts connections", func() {
+			Ω(service.Shutdown()).Should(BeTrue())
+			resp, err := http.Get("http://" + addr + "/readyz")
+			Ω(err).ShouldNot(HaveOccurred())
+			resp.Body.Close()
+			Ω(resp.StatusCode).Should(Equal(503))
+			Consistently(served, 50*time.Millisecond).ShouldNot(Receive())
+			Eventually(served).Should(Receive(BeNil()))
+			Ω(hooks).Should(Equal([]string{"metrics", "db"}))
+		})
+	})
+
+	Context("with multiple listeners", func() {
+		var served2 chan error
+
+		BeforeEach(func() {
+			l, err := goa.Listen("127.0.0.1:0")
+			Ω(err).ShouldNot(HaveOccurred())
+			served2 = make(chan error, 1)
+			go func() { served2 <- service.Serve(l) }()
+			Eventually(func() error {
+				resp, err := http.Get("http://" + l.Addr().String() + "/missing")
+				if err == nil {
+					resp.Body.Close()
+				}
+				return err
+			}).ShouldNot(HaveOccurred())
+		})
+
+		It("stops all the servers and runs the hooks once", func() {
+			Ω(service.Shutdown()).Should(BeTrue())
+			Eventually(served).Should(Receive(BeNil()))
+			Eventually(served2).Should(Receive(BeNil()))
+			Ω(hooks).Should(Equal([]string{"metrics", "db"}))
+		})
+	})
+})
@@ -0,0 +1,188 @@
+package goa
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// DefaultHealthCheckTimeout is the time given to each health check to complete when the service
+// HealthCheckTimeout is zero.
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+type (
+	// HealthCheck is a function that checks the health of a service dependency, e.g. a database
+	// connection. It returns a non-nil error if the dependency is not usable. Health checks
+	// should honor the context deadline.
+	HealthCheck func(ctx context.Context) error
+
+	// HealthReport is the body of the responses sent by the health controller.
+	HealthReport struct {
+		// Status is "ok" if all the checks succeeded, "fail" otherwise.
+		Status string `json:"status"`
+		// ShuttingDown is true if the service is shutting down, only set by the readiness
+		// endpoint.
+		ShuttingDown bool `json:"shutting_down,omitempty"`
+		// Checks contains the result of each health check indexed by name.
+		Checks map[string]*HealthCheckResult `json:"checks,omitempty"`
+	}
+
+	// HealthCheckResult is the result of a single health check.
+	HealthCheckResult struct {
+		// Status is "ok" if the check succeeded, "fail" otherwise.
+		Status string `json:"status"`
+		// Error is the message of the error returned by the check if any.
+		Error string `json:"error,omitempty"`
+		// Duration is the time it took to run the check.
+		Duration string `json:"duration"`
+	}
+
+	// healthChecks holds the health checks registered with a service.
+	healthChecks struct {
+		sync.Mutex
+		checks       map[string]HealthCheck
+		shuttingDown int32
+	}
+)
+
+// AddHealthCheck registers a health check run by the health controller, see
+// MountHealthController. Registering a check with the name of an existing check replaces it.
+func (service *Service) AddHealthCheck(name string, check HealthCheck) {
+	service.health.Lock()
+	defer service.health.Unlock()
+	if service.health.checks == nil {
+		service.health.checks = make(map[string]HealthCheck)
+	}
+	service.health.checks[name] = check
+}
+
+// MountHealthController mounts a controller that reports the service health. Empty paths are not
+// mounted.
+//
+// The health endpoint - e.g. "/healthz" - runs all the registered checks concurrently and
+// responds with status 200 and a HealthReport if they all succeed, 503 otherwise. Each check is
+// given HealthCheckTimeout to complete.
+//
+// The readiness endpoint - e.g. "/readyz" - behaves like the health endpoint except that it
+// responds with status 503 without running the checks once the service is shutting down (see
+// GracefulService.Shutdown) so that load balancers stop sending traffic before the listener
+// closes.
+//
+// The liveness endpoint - e.g. "/livez" - always responds with status 200 and does not run any
+// check: failing dependencies should not cause the process to be restarted.
+func (service *Service) MountHealthController(healthPath, readyPath, livePath string) {
+	ctrl := service.NewController("health")
+	mount := func(path, action string, h Handler) {
+		if path == "" {
+			return
+		}
+		service.Mux.HandleRoute(ctrl.Route("GET", path, action, nil), ctrl.MuxHandler(action, h, nil))
+		service.Info("mount", "ctrl", "health", "action", action, "route", "GET "+path)
+	}
+	mount(healthPath, "health", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		return writeHealthReport(rw, service.CheckHealth(ctx))
+	})
+	mount(readyPath, "ready", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		if atomic.LoadInt32(&service.health.shuttingDown) == 1 {
+			return writeHealthReport(rw, &HealthReport{Status: "fail", ShuttingDown: true})
+		}
+		return writeHealthReport(rw, service.CheckHealth(ctx))
+	})
+	mount(livePath, "live", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		return writeHealthReport(rw, &HealthReport{Status: "ok"})
+	})
+}
+
+// CheckHealth runs the registered health checks concurrently and returns the report.
+func (service *Service) CheckHealth(ctx context.Context) *HealthReport {
+	service.health.Lock()
+	checks := make(map[string]HealthCheck, len(service.health.checks))
+	names := make([]string, 0, len(service.health.checks))
+	for n, c := range service.health.checks {
+		checks[n] = c
+		names = append(names, n)
+	}
+	service.health.Unlock()
+	sort.Strings(names)
+
+	timeout := service.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	report := &HealthReport{Status: "ok", Checks: make(map[string]*HealthCheckResult, len(checks))}
+	results := make([]*HealthCheckResult, len(names))
+	var wg sync.WaitGroup
+	for i, n := range names {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			results[i] = runHealthCheck(ctx, check, timeout)
+		}(i, checks[n])
+	}
+	wg.Wait()
+	for i, n := range names {
+		if results[i].Status != "ok" {
+			report.Status = "fail"
+			Warn(ctx, "health check failed", "check", n, "err", results[i].Error)
+		}
+		report.Checks[n] = results[i]
+	}
+	return report
+}
+
+// setShuttingDown makes the readiness endpoint report that the service is not ready.
+func (service *Service) setShuttingDown() {
+	atomic.StoreInt32(&service.health.shuttingDown, 1)
+}
+
+// runHealthCheck runs check giving it at most timeout to complete.
+func runHealthCheck(ctx context.Context, check HealthCheck, timeout time.Duration) *HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	started := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("health check did not complete within %s", timeout)
+	}
+	res := &HealthCheckResult{Status: "ok", Duration: time.Since(started).String()}
+	if err != nil {
+		res.Status = "fail"
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// writeHealthReport writes the JSON representation of the report with status 200 if the report
+// status is "ok", 503 otherwise.
+func writeHealthReport(rw http.ResponseWriter, report *HealthReport) error {
+	b, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.WriteHeader(status)
+	_, err = rw.Write(b)
+	return err
+}
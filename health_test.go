@@ -0,0 +1,108 @@
+package goa_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("MountHealthController", func() {
+	var service *goa.GracefulService
+	var path string
+	var rw *httptest.ResponseRecorder
+	var report *goa.HealthReport
+
+	BeforeEach(func() {
+		service = goa.NewGraceful("test", false)
+		service.AddHealthCheck("db", func(ctx context.Context) error { return nil })
+		path = "/healthz"
+	})
+
+	JustBeforeEach(func() {
+		service.MountHealthController("/healthz", "/readyz", "/livez")
+		req, err := http.NewRequest("GET", path, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rw = httptest.NewRecorder()
+		service.Mux.ServeHTTP(rw, req)
+		report = nil
+		Ω(json.Unmarshal(rw.Body.Bytes(), &report)).ShouldNot(HaveOccurred())
+	})
+
+	It("reports the health of the service", func() {
+		Ω(rw.Code).Should(Equal(200))
+		Ω(rw.Header().Get("Content-Type")).Should(Equal("application/json"))
+		Ω(report.Status).Should(Equal("ok"))
+		Ω(report.Checks).Should(HaveKey("db"))
+		Ω(report.Checks["db"].Status).Should(Equal("ok"))
+	})
+
+	Context("with a failing check", func() {
+		BeforeEach(func() {
+			service.AddHealthCheck("cache", func(ctx context.Context) error { return errors.New("connection refused") })
+		})
+
+		It("responds with status 503", func() {
+			Ω(rw.Code).Should(Equal(503))
+			Ω(report.Status).Should(Equal("fail"))
+			Ω(report.Checks["db"].Status).Should(Equal("ok"))
+			Ω(report.Checks["cache"].Status).Should(Equal("fail"))
+			Ω(report.Checks["cache"].Error).Should(Equal("connection refused"))
+		})
+
+		Context("on the liveness endpoint", func() {
+			BeforeEach(func() {
+				path = "/livez"
+			})
+
+			It("does not run the checks", func() {
+				Ω(rw.Code).Should(Equal(200))
+				Ω(report.Checks).Should(BeEmpty())
+			})
+		})
+	})
+
+	Context("with a check that does not complete in time", func() {
+		BeforeEach(func() {
+			service.HealthCheckTimeout = 10 * time.Millisecond
+			service.AddHealthCheck("slow", func(ctx context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			})
+		})
+
+		It("reports the check as failed", func() {
+			Ω(rw.Code).Should(Equal(503))
+			Ω(report.Checks["slow"].Status).Should(Equal("fail"))
+			Ω(report.Checks["slow"].Error).Should(ContainSubstring("did not complete"))
+		})
+	})
+
+	Context("on the readiness endpoint", func() {
+		BeforeEach(func() {
+			path = "/readyz"
+		})
+
+		It("runs the checks", func() {
+			Ω(rw.Code).Should(Equal(200))
+			Ω(report.Checks).Should(HaveKey("db"))
+		})
+
+		Context("once the service is shutting down", func() {
+			BeforeEach(func() {
+				Ω(service.Shutdown()).Should(BeTrue())
+			})
+
+			It("reports that the service is not ready", func() {
+				Ω(rw.Code).Should(Equal(503))
+				Ω(report.ShuttingDown).Should(BeTrue())
+			})
+		})
+	})
+})
@@ -0,0 +1,111 @@
+package goa
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation.
+const listenFDsStart = 3
+
+// Listen creates a listener for the given address. The address may be:
+//
+// * a TCP host/port such as "localhost:8080" or ":8080".
+//
+// * a unix domain socket path prefixed with "unix:" such as "unix:/var/run/cellar.sock". A stale
+// socket file left at that path by a previous process is removed.
+//
+// * an inherited file descriptor prefixed with "fd:" such as "fd:3", e.g. to use a listener
+// created by a parent process during a zero-downtime restart. See also InheritedListeners.
+func Listen(addr string) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(addr, "unix:"):
+		path := strings.TrimPrefix(strings.TrimPrefix(addr, "unix:"), "//")
+		if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+		}
+		return net.Listen("unix", path)
+	case strings.HasPrefix(addr, "fd:"):
+		fd, err := strconv.Atoi(strings.TrimPrefix(addr, "fd:"))
+		if err != nil || fd < 0 {
+			return nil, fmt.Errorf("invalid file descriptor in listen address %#v", addr)
+		}
+		return fileListener(uintptr(fd), addr)
+	default:
+		return net.Listen("tcp", addr)
+	}
+}
+
+// InheritedListeners returns the listeners passed to the process using the systemd socket
+// activation protocol: the LISTEN_FDS environment variable contains the number of file
+// descriptors starting at 3. If LISTEN_PID is set it must match the process ID. The returned
+// slice is empty if the process was not socket activated.
+func InheritedListeners() ([]net.Listener, error) {
+	if pid := os.Getenv("LISTEN_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	nfds := os.Getenv("LISTEN_FDS")
+	if nfds == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(nfds)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS value %#v", nfds)
+	}
+	var names []string
+	if fdnames := os.Getenv("LISTEN_FDNAMES"); fdnames != "" {
+		names = strings.Split(fdnames, ":")
+	}
+	listeners := make([]net.Listener, n)
+	for i := 0; i < n; i++ {
+		name := "LISTEN_FD_" + strconv.Itoa(listenFDsStart+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		l, err := fileListener(uintptr(listenFDsStart+i), name)
+		if err != nil {
+			for _, l := range listeners[:i] {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners[i] = l
+	}
+	return listeners, nil
+}
+
+// Serve accepts HTTP connections on the given listener, see Listen.
+func (service *Service) Serve(l net.Listener) error {
+	service.Info("listen", "transport", "http", "addr", l.Addr().String())
+	return http.Serve(l, service.Mux)
+}
+
+// ServeTLS accepts HTTPS connections on the given listener using the given certificate and
+// key files. The files are reloaded when they change, see NewTLSConfig.
+func (service *Service) ServeTLS(l net.Listener, certFile, keyFile string) error {
+	config, err := NewTLSConfig(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	return service.ServeTLSConfig(l, config)
+}
+
+// fileListener creates a listener from the inherited file descriptor fd.
+func fileListener(fd uintptr, name string) (net.Listener, error) {
+	f := os.NewFile(fd, name)
+	if f == nil {
+		return nil, fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	defer f.Close() // net.FileListener dups the file descriptor
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("file descriptor %d (%s) is not a listener: %s", fd, name, err)
+	}
+	return l, nil
+}
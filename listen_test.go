@@ -0,0 +1,130 @@
+// +build !windows
+
+package goa_test
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Listen", func() {
+	var addr string
+	var listener net.Listener
+	var err error
+
+	JustBeforeEach(func() {
+		listener, err = goa.Listen(addr)
+	})
+
+	AfterEach(func() {
+		if listener != nil {
+			listener.Close()
+		}
+	})
+
+	Context("with a TCP address", func() {
+		BeforeEach(func() {
+			addr = "127.0.0.1:0"
+		})
+
+		It("listens on TCP", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(listener.Addr().Network()).Should(Equal("tcp"))
+		})
+	})
+
+	Context("with a unix socket address", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir, err = ioutil.TempDir("", "goa")
+			Ω(err).ShouldNot(HaveOccurred())
+			addr = "unix:" + filepath.Join(dir, "test.sock")
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("listens on the socket", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(listener.Addr().Network()).Should(Equal("unix"))
+		})
+
+		It("serves requests", func() {
+			service := goa.New("test")
+			service.Mux.Handle("GET", "/", func(rw http.ResponseWriter, req *http.Request, _ url.Values) {
+				rw.WriteHeader(204)
+			})
+			go service.Serve(listener)
+			client := &http.Client{Transport: &http.Transport{
+				Dial: func(_, _ string) (net.Conn, error) {
+					return net.Dial("unix", filepath.Join(dir, "test.sock"))
+				},
+			}}
+			resp, err := client.Get("http://unix/")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp.StatusCode).Should(Equal(204))
+		})
+	})
+
+	Context("with an inherited file descriptor", func() {
+		var tcp *net.TCPListener
+		var f *os.File
+
+		BeforeEach(func() {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			Ω(err).ShouldNot(HaveOccurred())
+			tcp = l.(*net.TCPListener)
+			f, err = tcp.File()
+			Ω(err).ShouldNot(HaveOccurred())
+			addr = "fd:" + strconv.Itoa(int(f.Fd()))
+		})
+
+		AfterEach(func() {
+			tcp.Close()
+		})
+
+		It("listens on the file descriptor", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(listener.Addr().String()).Should(Equal(tcp.Addr().String()))
+		})
+	})
+
+	Context("with an invalid file descriptor", func() {
+		BeforeEach(func() {
+			addr = "fd:foo"
+		})
+
+		It("returns an error", func() {
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("InheritedListeners", func() {
+	BeforeEach(func() {
+		os.Setenv("LISTEN_FDS", "1")
+		os.Setenv("LISTEN_PID", "1")
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_PID")
+	})
+
+	It("ignores the file descriptors passed to another process", func() {
+		listeners, err := goa.InheritedListeners()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(listeners).Should(BeEmpty())
+	})
+})
@@ -2,65 +2,164 @@ package goa
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/net/context"
 )
 
 type (
-	// Logger is the logger interface used by goa to log informational and error messages.
-	// Adapters to different logging backends are provided in the logging package.
+	// Logger is the logger interface used by goa to log debug, informational, warning and error
+	// messages. The key/value pairs given to the logger methods include the values stored in
+	// the request context with LogWith. Adapters to different logging backends are provided in
+	// the logging package sub-directories.
 	Logger interface {
+		// Debug logs a debug message.
+		Debug(msg string, keyvals ...interface{})
 		// Info logs an informational message.
 		Info(msg string, keyvals ...interface{})
+		// Warn logs a warning.
+		Warn(msg string, keyvals ...interface{})
 		// Error logs an error.
 		Error(msg string, keyvals ...interface{})
 	}
 
+	// LogLevel is the severity of a log message.
+	LogLevel int
+
 	// stdLogger uses the stdlib logger.
 	stdLogger struct {
 		*log.Logger
 	}
+
+	// jsonLogger writes JSON lines.
+	jsonLogger struct {
+		sync.Mutex
+		w io.Writer
+	}
+)
+
+const (
+	// LogDebug is the level of debug messages.
+	LogDebug LogLevel = iota
+	// LogInfo is the level of informational messages, this is the default service log level.
+	LogInfo
+	// LogWarn is the level of warnings.
+	LogWarn
+	// LogError is the level of errors.
+	LogError
 )
 
 // ErrMissingLogValue is the value used to log keys with missing values
 const ErrMissingLogValue = "MISSING"
 
+// ParseLogLevel returns the log level with the given name, one of "debug", "info", "warn" or
+// "error".
+func ParseLogLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LogDebug, nil
+	case "info":
+		return LogInfo, nil
+	case "warn", "warning":
+		return LogWarn, nil
+	case "error":
+		return LogError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %#v", name)
+}
+
+// String returns the log level name.
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "debug"
+	case LogInfo:
+		return "info"
+	case LogWarn:
+		return "warn"
+	case LogError:
+		return "error"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// Debug extracts the logger from the given context and calls Debug on it.
+// In general this shouldn't be needed (the client code should already have a handle on the logger)
+// This is mainly useful for "out-of-band" code like middleware.
+func Debug(ctx context.Context, msg string, keyvals ...interface{}) {
+	logit(ctx, LogDebug, msg, keyvals)
+}
+
 // Info extracts the logger from the given context and calls Info on it.
 // In general this shouldn't be needed (the client code should already have a handle on the logger)
 // This is mainly useful for "out-of-band" code like middleware.
 func Info(ctx context.Context, msg string, keyvals ...interface{}) {
-	logit(ctx, msg, keyvals, false)
+	logit(ctx, LogInfo, msg, keyvals)
+}
+
+// Warn extracts the logger from the given context and calls Warn on it.
+// In general this shouldn't be needed (the client code should already have a handle on the logger)
+// This is mainly useful for "out-of-band" code like middleware.
+func Warn(ctx context.Context, msg string, keyvals ...interface{}) {
+	logit(ctx, LogWarn, msg, keyvals)
 }
 
 // Error extracts the logger from the given context and calls Error on it.
 // In general this shouldn't be needed (the client code should already have a handle on the logger)
 // This is mainly useful for "out-of-band" code like middleware.
 func Error(ctx context.Context, msg string, keyvals ...interface{}) {
-	logit(ctx, msg, keyvals, true)
-}
-
-func logit(ctx context.Context, msg string, keyvals []interface{}, aserror bool) {
-	if l := ctx.Value(logKey); l != nil {
-		if logger, ok := l.(Logger); ok {
-			var logctx []interface{}
-			if lctx := ctx.Value(logContextKey); lctx != nil {
-				logctx = lctx.([]interface{})
-			}
-			data := append(logctx, keyvals...)
-			if aserror {
-				logger.Error(msg, data...)
-			} else {
-				logger.Info(msg, data...)
-			}
-		}
+	logit(ctx, LogError, msg, keyvals)
+}
+
+// logit logs the message if its level is greater or equal to the level stored in the context,
+// LogInfo if none.
+func logit(ctx context.Context, level LogLevel, msg string, keyvals []interface{}) {
+	logger, ok := ctx.Value(logKey).(Logger)
+	if !ok {
+		return
+	}
+	min := LogInfo
+	if l, ok := ctx.Value(logLevelKey).(LogLevel); ok {
+		min = l
+	}
+	if level < min {
+		return
+	}
+	var logctx []interface{}
+	if lctx := ctx.Value(logContextKey); lctx != nil {
+		logctx = lctx.([]interface{})
+	}
+	data := make([]interface{}, len(logctx), len(logctx)+len(keyvals))
+	copy(data, logctx)
+	data = append(data, keyvals...)
+	switch level {
+	case LogDebug:
+		logger.Debug(msg, data...)
+	case LogInfo:
+		logger.Info(msg, data...)
+	case LogWarn:
+		logger.Warn(msg, data...)
+	default:
+		logger.Error(msg, data...)
 	}
 }
 
 // LogWith stores logging context to be used by all Log invocations using the returned context.
+// The given key/value pairs are appended to any logging context already present in ctx.
 func LogWith(ctx context.Context, keyvals ...interface{}) context.Context {
-	return context.WithValue(ctx, logContextKey, keyvals)
+	var logctx []interface{}
+	if lctx := ctx.Value(logContextKey); lctx != nil {
+		logctx = lctx.([]interface{})
+	}
+	data := make([]interface{}, len(logctx), len(logctx)+len(keyvals))
+	copy(data, logctx)
+	return context.WithValue(ctx, logContextKey, append(data, keyvals...))
 }
 
 // NewStdLogger returns an implementation of Logger backed by a stdlib Logger.
@@ -68,32 +167,87 @@ func NewStdLogger(logger *log.Logger) Logger {
 	return &stdLogger{Logger: logger}
 }
 
+func (l *stdLogger) Debug(msg string, keyvals ...interface{}) {
+	l.logit("DEBUG", msg, keyvals)
+}
+
 func (l *stdLogger) Info(msg string, keyvals ...interface{}) {
-	l.logit(msg, keyvals, false)
+	l.logit("INFO", msg, keyvals)
+}
+
+func (l *stdLogger) Warn(msg string, keyvals ...interface{}) {
+	l.logit("WARN", msg, keyvals)
 }
 
 func (l *stdLogger) Error(msg string, keyvals ...interface{}) {
-	l.logit(msg, keyvals, true)
+	l.logit("ERROR", msg, keyvals)
 }
 
-func (l *stdLogger) logit(msg string, keyvals []interface{}, iserror bool) {
-	n := (len(keyvals) + 1) / 2
-	var fm bytes.Buffer
-	lvl := "INFO"
-	if iserror {
-		lvl = "ERROR"
+func (l *stdLogger) logit(lvl, msg string, keyvals []interface{}) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "[%s] %s", lvl, msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		var v interface{} = ErrMissingLogValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		fmt.Fprintf(&b, " %v=%v", keyvals[i], v)
 	}
-	fm.WriteString(fmt.Sprintf("[%s] %s", lvl, msg))
-	vals := make([]interface{}, n)
+	l.Logger.Print(b.String())
+}
+
+// NewJSONLogger returns an implementation of Logger that writes one JSON object per line to w.
+// Each object has a "time", "level" and "msg" field and one field per key/value pair. Error
+// values are written using their message, keys that clash with the standard fields are prefixed
+// with "fields.".
+func NewJSONLogger(w io.Writer) Logger {
+	return &jsonLogger{w: w}
+}
+
+func (l *jsonLogger) Debug(msg string, keyvals ...interface{}) {
+	l.logit(LogDebug, msg, keyvals)
+}
+
+func (l *jsonLogger) Info(msg string, keyvals ...interface{}) {
+	l.logit(LogInfo, msg, keyvals)
+}
+
+func (l *jsonLogger) Warn(msg string, keyvals ...interface{}) {
+	l.logit(LogWarn, msg, keyvals)
+}
+
+func (l *jsonLogger) Error(msg string, keyvals ...interface{}) {
+	l.logit(LogError, msg, keyvals)
+}
+
+func (l *jsonLogger) logit(level LogLevel, msg string, keyvals []interface{}) {
+	entry := make(map[string]interface{}, 3+(len(keyvals)+1)/2)
 	for i := 0; i < len(keyvals); i += 2 {
-		k := keyvals[i]
+		k := fmt.Sprintf("%v", keyvals[i])
+		if k == "time" || k == "level" || k == "msg" {
+			k = "fields." + k
+		}
 		var v interface{} = ErrMissingLogValue
 		if i+1 < len(keyvals) {
 			v = keyvals[i+1]
 		}
-		vals[i/2] = v
-		fm.WriteString(" ")
-		fm.WriteString(fmt.Sprintf("%s=%%v", k))
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		entry[k] = v
+	}
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = level.String()
+	entry["msg"] = msg
+	b, err := json.Marshal(entry)
+	if err != nil {
+		// Some values cannot be serialized, use their default format instead
+		for k, v := range entry {
+			entry[k] = fmt.Sprintf("%v", v)
+		}
+		b, _ = json.Marshal(entry)
 	}
-	l.Logger.Printf(fm.String(), vals...)
+	l.Lock()
+	defer l.Unlock()
+	l.w.Write(append(b, '\n'))
 }
@@ -0,0 +1,17 @@
+/*
+Package logging contains the adapters that make it possible to use popular structured logging
+packages as goa logger backends, see goa.Logger. Each adapter lives in its own sub-package so that
+applications only depend on the logging package they use:
+
+	logging/logrus	github.com/sirupsen/logrus
+	logging/log15	gopkg.in/inconshreveable/log15.v2
+	logging/kit	github.com/go-kit/kit/log
+	logging/zap	go.uber.org/zap
+
+The adapters log the key/value pairs given to the goa logger methods - including the values stored
+in the request context with goa.LogWith - as fields of the underlying logger. Example:
+
+	logger := logrus.New()
+	service.UseLogger(goalogrus.New(logger))
+*/
+package logging
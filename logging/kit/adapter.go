@@ -0,0 +1,58 @@
+/*
+Package goakit contains an adapter that makes it possible to configure goa so it uses the go-kit
+log package as logger backend.
+Usage:
+
+	logger := log.NewLogfmtLogger(os.Stderr)
+	// Initialize logger handler using go-kit log package
+	service.UseLogger(goakit.New(logger))
+	// ... Proceed with configuring and starting the goa service
+*/
+package goakit
+
+import (
+	"github.com/go-kit/kit/log"
+	"github.com/goadesign/goa"
+)
+
+// adapter is the go-kit log goa logger adapter.
+type adapter struct {
+	log.Logger
+}
+
+// New wraps a go-kit logger into a goa logger. The messages are logged with the "lvl" and "msg"
+// keys followed by the key/value pairs.
+func New(logger log.Logger) goa.Logger {
+	return &adapter{Logger: logger}
+}
+
+// Debug logs debug messages using go-kit.
+func (a *adapter) Debug(msg string, keyvals ...interface{}) {
+	a.logit("debug", msg, keyvals)
+}
+
+// Info logs informational messages using go-kit.
+func (a *adapter) Info(msg string, keyvals ...interface{}) {
+	a.logit("info", msg, keyvals)
+}
+
+// Warn logs warnings using go-kit.
+func (a *adapter) Warn(msg string, keyvals ...interface{}) {
+	a.logit("warn", msg, keyvals)
+}
+
+// Error logs errors using go-kit.
+func (a *adapter) Error(msg string, keyvals ...interface{}) {
+	a.logit("error", msg, keyvals)
+}
+
+// logit prepends the level and message to the key/value pairs and calls the go-kit logger.
+func (a *adapter) logit(lvl, msg string, keyvals []interface{}) {
+	data := make([]interface{}, 0, len(keyvals)+5)
+	data = append(data, "lvl", lvl, "msg", msg)
+	data = append(data, keyvals...)
+	if len(keyvals)%2 != 0 {
+		data = append(data, goa.ErrMissingLogValue)
+	}
+	a.Logger.Log(data...)
+}
@@ -0,0 +1,46 @@
+/*
+Package goalog15 contains an adapter that makes it possible to configure goa so it uses log15
+as logger backend.
+Usage:
+
+	logger := log15.New()
+	// Initialize logger handler using log15 package
+	service.UseLogger(goalog15.New(logger))
+	// ... Proceed with configuring and starting the goa service
+*/
+package goalog15
+
+import (
+	"github.com/goadesign/goa"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// adapter is the log15 goa logger adapter.
+type adapter struct {
+	log15.Logger
+}
+
+// New wraps a log15 logger into a goa logger.
+func New(logger log15.Logger) goa.Logger {
+	return &adapter{Logger: logger}
+}
+
+// Debug logs debug messages using log15.
+func (a *adapter) Debug(msg string, keyvals ...interface{}) {
+	a.Logger.Debug(msg, keyvals...)
+}
+
+// Info logs informational messages using log15.
+func (a *adapter) Info(msg string, keyvals ...interface{}) {
+	a.Logger.Info(msg, keyvals...)
+}
+
+// Warn logs warnings using log15.
+func (a *adapter) Warn(msg string, keyvals ...interface{}) {
+	a.Logger.Warn(msg, keyvals...)
+}
+
+// Error logs errors using log15.
+func (a *adapter) Error(msg string, keyvals ...interface{}) {
+	a.Logger.Error(msg, keyvals...)
+}
@@ -0,0 +1,67 @@
+/*
+Package goalogrus contains an adapter that makes it possible to configure goa so it uses logrus
+as logger backend.
+Usage:
+
+	logger := logrus.New()
+	// Initialize logger handler using logrus package
+	service.UseLogger(goalogrus.New(logger))
+	// ... Proceed with configuring and starting the goa service
+*/
+package goalogrus
+
+import (
+	"fmt"
+
+	"github.com/goadesign/goa"
+	"github.com/sirupsen/logrus"
+)
+
+// adapter is the logrus goa logger adapter.
+type adapter struct {
+	*logrus.Entry
+}
+
+// New wraps a logrus logger into a goa logger.
+func New(logger *logrus.Logger) goa.Logger {
+	return FromEntry(logrus.NewEntry(logger))
+}
+
+// FromEntry wraps a logrus log entry into a goa logger. The entry fields are logged with each
+// message.
+func FromEntry(entry *logrus.Entry) goa.Logger {
+	return &adapter{Entry: entry}
+}
+
+// Debug logs debug messages using logrus.
+func (a *adapter) Debug(msg string, keyvals ...interface{}) {
+	a.Entry.WithFields(fields(keyvals)).Debug(msg)
+}
+
+// Info logs informational messages using logrus.
+func (a *adapter) Info(msg string, keyvals ...interface{}) {
+	a.Entry.WithFields(fields(keyvals)).Info(msg)
+}
+
+// Warn logs warnings using logrus.
+func (a *adapter) Warn(msg string, keyvals ...interface{}) {
+	a.Entry.WithFields(fields(keyvals)).Warn(msg)
+}
+
+// Error logs errors using logrus.
+func (a *adapter) Error(msg string, keyvals ...interface{}) {
+	a.Entry.WithFields(fields(keyvals)).Error(msg)
+}
+
+// fields converts the goa key/value pairs into logrus fields.
+func fields(keyvals []interface{}) logrus.Fields {
+	res := make(logrus.Fields, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		var v interface{} = goa.ErrMissingLogValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		res[fmt.Sprintf("%v", keyvals[i])] = v
+	}
+	return res
+}
@@ -0,0 +1,61 @@
+/*
+Package goazap contains an adapter that makes it possible to configure goa so it uses zap
+as logger backend.
+Usage:
+
+	logger, err := zap.NewProduction()
+	// Initialize logger handler using zap package
+	service.UseLogger(goazap.New(logger))
+	// ... Proceed with configuring and starting the goa service
+*/
+package goazap
+
+import (
+	"fmt"
+
+	"github.com/goadesign/goa"
+	"go.uber.org/zap"
+)
+
+// adapter is the zap goa logger adapter.
+type adapter struct {
+	*zap.Logger
+}
+
+// New wraps a zap logger into a goa logger.
+func New(logger *zap.Logger) goa.Logger {
+	return &adapter{Logger: logger}
+}
+
+// Debug logs debug messages using zap.
+func (a *adapter) Debug(msg string, keyvals ...interface{}) {
+	a.Logger.Debug(msg, fields(keyvals)...)
+}
+
+// Info logs informational messages using zap.
+func (a *adapter) Info(msg string, keyvals ...interface{}) {
+	a.Logger.Info(msg, fields(keyvals)...)
+}
+
+// Warn logs warnings using zap.
+func (a *adapter) Warn(msg string, keyvals ...interface{}) {
+	a.Logger.Warn(msg, fields(keyvals)...)
+}
+
+// Error logs errors using zap.
+func (a *adapter) Error(msg string, keyvals ...interface{}) {
+	a.Logger.Error(msg, fields(keyvals)...)
+}
+
+// fields converts the goa key/value pairs into zap fields.
+func fields(keyvals []interface{}) []zap.Field {
+	res := make([]zap.Field, 0, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		var v interface{} = goa.ErrMissingLogValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		res = append(res, zap.Any(fmt.Sprintf("%v", keyvals[i]), v))
+	}
+	return res
+}
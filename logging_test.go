@@ -2,6 +2,8 @@ package goa_test
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"log"
 
 	"github.com/goadesign/goa"
@@ -48,5 +50,114 @@ var _ = Describe("StdLogger", func() {
 			logger.Error(msg, data...)
 			Ω(out.String()).Should(ContainSubstring(msg + " data=foo"))
 		})
+
+		It("Debug logs", func() {
+			logger.Debug(msg, data...)
+			Ω(out.String()).Should(ContainSubstring("[DEBUG] " + msg + " data=foo"))
+		})
+
+		It("Warn logs", func() {
+			logger.Warn(msg, data...)
+			Ω(out.String()).Should(ContainSubstring("[WARN] " + msg + " data=foo"))
+		})
+
+		It("does not interpret the message as a format string", func() {
+			logger.Info("100%s done")
+			Ω(out.String()).Should(ContainSubstring("100%s done"))
+		})
+	})
+})
+
+var _ = Describe("JSONLogger", func() {
+	var logger goa.Logger
+	var out bytes.Buffer
+	var entry map[string]interface{}
+
+	BeforeEach(func() {
+		out.Reset()
+		logger = goa.NewJSONLogger(&out)
+		entry = nil
+	})
+
+	decode := func() {
+		Ω(json.Unmarshal(out.Bytes(), &entry)).ShouldNot(HaveOccurred())
+	}
+
+	It("writes one JSON object per line", func() {
+		logger.Warn("message", "data", "foo", "count", 2)
+		Ω(out.String()).Should(HaveSuffix("\n"))
+		decode()
+		Ω(entry).Should(HaveKeyWithValue("level", "warn"))
+		Ω(entry).Should(HaveKeyWithValue("msg", "message"))
+		Ω(entry).Should(HaveKeyWithValue("data", "foo"))
+		Ω(entry).Should(HaveKeyWithValue("count", 2.0))
+		Ω(entry).Should(HaveKey("time"))
+	})
+
+	It("logs the message of error values", func() {
+		logger.Error("failed", "err", errors.New("boom"))
+		decode()
+		Ω(entry).Should(HaveKeyWithValue("err", "boom"))
+	})
+
+	It("does not override the standard fields", func() {
+		logger.Info("message", "msg", "other", "missing")
+		decode()
+		Ω(entry).Should(HaveKeyWithValue("msg", "message"))
+		Ω(entry).Should(HaveKeyWithValue("fields.msg", "other"))
+		Ω(entry).Should(HaveKeyWithValue("missing", goa.ErrMissingLogValue))
+	})
+})
+
+var _ = Describe("ParseLogLevel", func() {
+	It("parses level names", func() {
+		for _, name := range []string{"debug", "info", "warn", "error"} {
+			level, err := goa.ParseLogLevel(name)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(level.String()).Should(Equal(name))
+		}
+	})
+
+	It("returns an error for unknown names", func() {
+		_, err := goa.ParseLogLevel("verbose")
+		Ω(err).Should(HaveOccurred())
+	})
+})
+
+var _ = Describe("LogWith", func() {
+	var service *goa.Service
+	var out bytes.Buffer
+
+	BeforeEach(func() {
+		out.Reset()
+		service = goa.New("test")
+		service.UseLogger(goa.NewJSONLogger(&out))
+	})
+
+	It("logs the context values as fields", func() {
+		ctx := goa.LogWith(service.Context, "req_id", "abc")
+		goa.Info(ctx, "message", "data", "foo")
+		var entry map[string]interface{}
+		Ω(json.Unmarshal(out.Bytes(), &entry)).ShouldNot(HaveOccurred())
+		Ω(entry).Should(HaveKeyWithValue("req_id", "abc"))
+		Ω(entry).Should(HaveKeyWithValue("data", "foo"))
+	})
+
+	It("discards debug messages by default", func() {
+		service.Debug("message")
+		Ω(out.Len()).Should(Equal(0))
+	})
+
+	Context("with a service log level", func() {
+		BeforeEach(func() {
+			service.SetLogLevel(goa.LogWarn)
+		})
+
+		It("discards messages below the level", func() {
+			service.Info("message")
+			Ω(out.Len()).Should(Equal(0))
+			service.Warn("warning")
+			Ω(out.String()).Should(ContainSubstring(`"warning"`))
+		})
 	})
 })
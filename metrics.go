@@ -1,18 +1,59 @@
 package goa
 
 import (
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/armon/go-metrics"
+	"golang.org/x/net/context"
+)
+
+type (
+	// Metrics is the interface used by goa to record metrics. The go-metrics *metrics.Metrics
+	// type implements it so that a service may use any go-metrics sink:
+	//
+	//	service.Metrics, err = metrics.New(metrics.DefaultConfig("cellar"), sink)
+	//
+	// goa records the following metrics, the keys are prefixed with "goa":
+	//
+	//	response.<resource>.<action>.<status>	Counter of responses per action and status
+	//	handler.error.<status>			Counter of errors returned by the handlers
+	//	error.<id>				Counter of typed errors returned by the handlers
+	//	handler.panic				Counter of panics recovered by Recover
+	//	validation.error.<format>		Counter of format validation errors
+	//	ratelimit.rejected.<scope>		Counter of requests rejected by RateLimit
+	//	graceful.restart			Counter of graceful shutdowns
+	//	request.<resource>.<action>.<status>	Request durations, see MeasureRequests
+	//	decode.<content type>			Request body decoding durations
+	//	encode.<content type>			Response body encoding durations
+	Metrics interface {
+		// AddSample adds a sample to an aggregated metric reporting count, min, max, mean,
+		// and std deviation.
+		AddSample(key []string, val float32)
+		// EmitKey emits a key/value pair.
+		EmitKey(key []string, val float32)
+		// IncrCounter increments the counter named by key.
+		IncrCounter(key []string, val float32)
+		// MeasureSince creates a timing metric that records the duration of elapsed time
+		// since start.
+		MeasureSince(key []string, start time.Time)
+		// SetGauge sets the named gauge to the specified value.
+		SetGauge(key []string, val float32)
+	}
+
+	// noMetrics is the Metrics implementation used when no metrics are configured.
+	noMetrics struct{}
 )
 
 // metriks is the local instance of metrics.Metrics
 var metriks *metrics.Metrics
 
-// NewMetrics initializes goa's metrics instance with the supplied
-// configuration and metrics sink
+// NewMetrics initializes the metrics instance used by the package functions below with the
+// supplied configuration and metrics sink. goa also records its own metrics with this instance for
+// the services whose Metrics field is not set.
 func NewMetrics(conf *metrics.Config, sink metrics.MetricSink) (err error) {
-	metriks, err = metrics.NewGlobal(conf, sink)
+	metriks, err = metrics.New(conf, sink)
 	return
 }
 
@@ -64,3 +105,41 @@ func SetGauge(key []string, val float32) {
 		metriks.SetGauge(key, val)
 	}
 }
+
+// MeasureRequests returns a middleware that records the duration of the requests under the
+// "goa.request.<resource>.<action>.<status>" key using the service metrics.
+func MeasureRequests() Middleware {
+	return func(h Handler) Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			started := time.Now()
+			err := h(ctx, rw, req)
+			status := http.StatusOK
+			if err != nil {
+				status = errorStatus(err)
+			} else if resp := Response(ctx); resp != nil && resp.Status != 0 {
+				status = resp.Status
+			}
+			key := []string{"goa", "request", ContextController(ctx), ContextAction(ctx), strconv.Itoa(status)}
+			RequestService(ctx).metrics().MeasureSince(key, started)
+			return err
+		}
+	}
+}
+
+// metrics returns the metrics used to instrument the service, metrics may be called on a nil
+// service.
+func (service *Service) metrics() Metrics {
+	if service != nil && service.Metrics != nil {
+		return service.Metrics
+	}
+	if metriks != nil {
+		return metriks
+	}
+	return noMetrics{}
+}
+
+func (noMetrics) AddSample(key []string, val float32)        {}
+func (noMetrics) EmitKey(key []string, val float32)          {}
+func (noMetrics) IncrCounter(key []string, val float32)      {}
+func (noMetrics) MeasureSince(key []string, start time.Time) {}
+func (noMetrics) SetGauge(key []string, val float32)         {}
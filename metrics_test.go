@@ -0,0 +1,149 @@
+package goa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+// recordingMetrics is a goa.Metrics implementation that records the metric keys.
+type recordingMetrics struct {
+	sync.Mutex
+	counters map[string]float32
+	timings  []string
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{counters: make(map[string]float32)}
+}
+
+func (m *recordingMetrics) AddSample(key []string, val float32) {}
+func (m *recordingMetrics) EmitKey(key []string, val float32)   {}
+func (m *recordingMetrics) SetGauge(key []string, val float32)  {}
+
+func (m *recordingMetrics) IncrCounter(key []string, val float32) {
+	m.Lock()
+	defer m.Unlock()
+	m.counters[strings.Join(key, ".")] += val
+}
+
+func (m *recordingMetrics) MeasureSince(key []string, start time.Time) {
+	m.Lock()
+	defer m.Unlock()
+	m.timings = append(m.timings, strings.Join(key, "."))
+}
+
+var _ = Describe("Metrics", func() {
+	var service *goa.Service
+	var metrics *recordingMetrics
+	var handler goa.Handler
+	var rw *httptest.ResponseRecorder
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		service.Encoder(goa.NewJSONEncoder, "*/*")
+		service.Use(goa.MeasureRequests())
+		metrics = newRecordingMetrics()
+		service.Metrics = metrics
+		handler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			return goa.Response(ctx).Send(ctx, 201, "created")
+		}
+		rw = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		ctrl := service.NewController("bottle")
+		req, err := http.NewRequest("POST", "/bottles", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		ctrl.MuxHandler("create", handler, nil)(rw, req, nil)
+	})
+
+	It("counts responses per action and status", func() {
+		Ω(rw.Code).Should(Equal(201))
+		Ω(metrics.counters).Should(HaveKeyWithValue("goa.response.bottle.create.201", float32(1)))
+	})
+
+	It("records the request durations", func() {
+		Ω(metrics.timings).Should(ContainElement("goa.request.bottle.create.201"))
+		Ω(metrics.timings).Should(ContainElement("goa.encode.*/*"))
+	})
+
+	Context("with a handler that returns an error", func() {
+		BeforeEach(func() {
+			handler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				return &goa.TypedError{ID: goa.ErrMissingParam, Mesg: "missing id"}
+			}
+		})
+
+		It("counts the error", func() {
+			Ω(metrics.counters).Should(HaveKeyWithValue("goa.handler.error.400", float32(1)))
+			Ω(metrics.counters).Should(HaveKey(HavePrefix("goa.error.")))
+			Ω(metrics.counters).Should(HaveKeyWithValue("goa.response.bottle.create.400", float32(1)))
+		})
+	})
+
+	Context("with a handler that returns format validation errors", func() {
+		BeforeEach(func() {
+			handler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				err := goa.ValidateFormat(goa.FormatEmail, "joe")
+				return goa.NewBadRequestError(goa.InvalidFormatError("email", "joe", goa.FormatEmail, err, nil))
+			}
+		})
+
+		It("counts the validation errors with the service metrics", func() {
+			Ω(metrics.counters).Should(HaveKeyWithValue("goa.validation.error.email", float32(1)))
+			Ω(metrics.counters).Should(HaveKeyWithValue("goa.error."+strconv.Itoa(goa.ErrInvalidFormat), float32(1)))
+		})
+	})
+})
+
+var _ = Describe("NewMetrics", func() {
+	var sink *metrics.InmemSink
+
+	BeforeEach(func() {
+		sink = metrics.NewInmemSink(time.Hour, time.Hour)
+		Ω(goa.NewMetrics(metrics.DefaultConfig("test"), sink)).Should(Succeed())
+	})
+
+	It("records the goa metrics of services without Metrics", func() {
+		service := goa.New("test")
+		ctrl := service.NewController("bottle")
+		req, err := http.NewRequest("POST", "/bottles", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		ctrl.MuxHandler("create", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			rw.WriteHeader(201)
+			return nil
+		}, nil)(httptest.NewRecorder(), req, nil)
+		data := sink.Data()
+		Ω(data).ShouldNot(BeEmpty())
+		Ω(data[len(data)-1].Counters).Should(HaveKey("test.goa.response.bottle.create.201"))
+	})
+})
+
+var _ = Describe("ContextAction", func() {
+	It("returns the controller and action names", func() {
+		var ctrlName, actionName string
+		service := goa.New("test")
+		ctrl := service.NewController("bottle")
+		handler := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			ctrlName = goa.ContextController(ctx)
+			actionName = goa.ContextAction(ctx)
+			Ω(ctx.Value("ctrl")).Should(Equal("bottle"))
+			return nil
+		}
+		req, err := http.NewRequest("GET", "/bottles/1", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		ctrl.MuxHandler("show", handler, nil)(httptest.NewRecorder(), req, nil)
+		Ω(ctrlName).Should(Equal("bottle"))
+		Ω(actionName).Should(Equal("show"))
+	})
+})
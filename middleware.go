@@ -1,8 +1,17 @@
 package goa
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"net/http"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/net/context"
 )
@@ -78,3 +87,297 @@ func httpHandlerToMiddleware(m http.HandlerFunc) Middleware {
 		}
 	}
 }
+
+// RequestIDHeader is the name of the header used to transmit the request ID.
+const RequestIDHeader = "X-Request-Id"
+
+// Counter used to create new request ids.
+var reqID int64
+
+// Common prefix to all newly created request ids for this process.
+var reqPrefix string
+
+// Initialize common prefix on process startup.
+func init() {
+	// algorithm taken from https://github.com/zenazn/goji/blob/master/web/middleware/request_id.go#L44-L50
+	var buf [12]byte
+	var b64 string
+	for len(b64) < 10 {
+		rand.Read(buf[:])
+		b64 = base64.StdEncoding.EncodeToString(buf[:])
+		b64 = strings.NewReplacer("+", "", "/", "").Replace(b64)
+	}
+	reqPrefix = string(b64[0:10])
+}
+
+// RequestID is a middleware that injects a request ID into the context of each request.
+// Retrieve it using ContextRequestID. If the incoming request has a RequestIDHeader header then
+// that value is used else a new value is generated. The request ID is also added to the log
+// context via LogWith and written back to the client in the response RequestIDHeader header.
+func RequestID() Middleware {
+	return func(h Handler) Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			id := req.Header.Get(RequestIDHeader)
+			if id == "" {
+				id = fmt.Sprintf("%s-%d", reqPrefix, atomic.AddInt64(&reqID, 1))
+			}
+			ctx = context.WithValue(ctx, reqIDKey, id)
+			ctx = LogWith(ctx, "id", id)
+			rw.Header().Set(RequestIDHeader, id)
+			return h(ctx, rw, req)
+		}
+	}
+}
+
+// ContextRequestID extracts the request ID set by the RequestID middleware from the context.
+// It returns the empty string if there is no request ID in the context.
+func ContextRequestID(ctx context.Context) string {
+	if id := ctx.Value(reqIDKey); id != nil {
+		return id.(string)
+	}
+	return ""
+}
+
+// LogRequest creates a request logger middleware.
+// The middleware logs the request method, path and parameters when the request starts and the
+// response status, length and duration once it completes. It is aware of the RequestID
+// middleware and if registered after it leverages the request ID for logging.
+// If verbose is true then the middleware also logs the request headers.
+func LogRequest(verbose bool) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			startedAt := time.Now()
+			r := Request(ctx)
+			Info(ctx, "started", "method", r.Method, "path", r.URL.String())
+			if verbose && len(r.Header) > 0 {
+				Info(ctx, "headers", flatten(r.Header)...)
+			}
+			if len(r.Params) > 0 {
+				Info(ctx, "params", flatten(r.Params)...)
+			}
+			err := h(ctx, rw, req)
+			resp := Response(ctx)
+			Info(ctx, "completed", "status", resp.Status, "bytes", resp.Length,
+				"time", time.Since(startedAt).String())
+			return err
+		}
+	}
+}
+
+// flatten turns the given header or query string values into a list of key/value pairs suitable
+// for logging. The keys are sorted so that the output is deterministic.
+func flatten(vals map[string][]string) []interface{} {
+	keys := make([]string, len(vals))
+	i := 0
+	for k := range vals {
+		keys[i] = k
+		i++
+	}
+	sort.Strings(keys)
+	keyvals := make([]interface{}, 2*len(keys))
+	for i, k := range keys {
+		keyvals[2*i] = k
+		keyvals[2*i+1] = interface{}(strings.Join(vals[k], ", "))
+	}
+	return keyvals
+}
+
+// Recover is a middleware that recovers panics and maps them to errors. The panic value and stack
+// trace are logged via the context logger and the panic is counted under "goa.handler.panic".
+// The resulting error - a TypedError with id ErrPanic - is returned so that the controller error
+// handler writes the response.
+func Recover() Middleware {
+	return func(h Handler) Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = panicError(ctx, r)
+				}
+			}()
+			return h(ctx, rw, req)
+		}
+	}
+}
+
+// panicError logs the given recovered panic value and the stack trace, counts the panic and
+// returns the corresponding TypedError with id ErrPanic.
+func panicError(ctx context.Context, r interface{}) error {
+	RequestService(ctx).metrics().IncrCounter([]string{"goa", "handler", "panic"}, 1.0)
+	msg := fmt.Sprintf("%v", r)
+	Error(ctx, "panic", "err", msg, "stack", string(debug.Stack()))
+	return &TypedError{ID: ErrPanic, Mesg: msg}
+}
+
+// Timeout is a middleware that sets a deadline on the request context and runs the handler in a
+// separate goroutine, buffering the response it writes the same way http.TimeoutHandler does.
+// If the deadline passes before the handler returns then the middleware returns right away with a
+// ServiceUnavailableError wrapping a TypedError with id ErrRequestTimeout so that the controller
+// error handler writes a response with status 503, any later write of the handler failing with
+// http.ErrHandlerTimeout. Handlers should still honor the context Done channel to release their
+// resources as soon as possible. Since the response is buffered, handlers wrapped by Timeout cannot
+// stream or hijack the connection.
+func Timeout(timeout time.Duration) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			resp := Response(ctx)
+			var dst http.ResponseWriter = rw
+			if resp != nil {
+				dst = resp
+			}
+			tw := &timeoutWriter{header: make(http.Header)}
+			copyHeader(tw.header, dst.Header())
+			inner := &ResponseData{ResponseWriter: tw}
+			hctx := context.WithValue(ctx, respKey, inner)
+			done := make(chan error, 1)
+			panicked := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if r := recover(); r != nil {
+						panicked <- r
+					}
+				}()
+				done <- h(hctx, tw, req)
+			}()
+			select {
+			case r := <-panicked:
+				panic(r)
+			case err := <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				if resp == nil {
+					tw.flush(rw)
+					return err
+				}
+				tw.flush(resp.ResponseWriter)
+				resp.Status = tw.code
+				resp.Length += tw.buf.Len()
+				if inner.CompressedLength > 0 {
+					resp.CompressedLength = inner.CompressedLength
+				}
+				return err
+			case <-ctx.Done():
+				tw.mu.Lock()
+				tw.timedOut = true
+				tw.mu.Unlock()
+				if ctx.Err() != context.DeadlineExceeded {
+					return ctx.Err()
+				}
+				return NewServiceUnavailableError(&TypedError{
+					ID:   ErrRequestTimeout,
+					Mesg: fmt.Sprintf("request did not complete within %s", timeout),
+				})
+			}
+		}
+	}
+}
+
+// timeoutWriter is the response writer given to the handlers wrapped by the Timeout middleware.
+// It buffers the response until the handler returns.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+// Header returns the buffered response headers.
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// WriteHeader records the response status code.
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
+
+// Write buffers the response body, it fails with http.ErrHandlerTimeout once the deadline passed.
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+	return tw.buf.Write(b)
+}
+
+// flush writes the buffered response to rw, the caller must hold the lock.
+func (tw *timeoutWriter) flush(rw http.ResponseWriter) {
+	dst := rw.Header()
+	for k := range dst {
+		if _, ok := tw.header[k]; !ok {
+			delete(dst, k)
+		}
+	}
+	copyHeader(dst, tw.header)
+	if tw.code != 0 {
+		rw.WriteHeader(tw.code)
+	}
+	if tw.buf.Len() > 0 {
+		rw.Write(tw.buf.Bytes())
+	}
+}
+
+// copyHeader copies the values of src into dst, the value slices are copied so that appending to
+// the values of one header does not modify the other.
+func copyHeader(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = append([]string(nil), v...)
+	}
+}
+
+// RequireAcceptable is a middleware that responds with 406 Not Acceptable to requests whose Accept
+// header matches neither the given media types nor the content types of the service encoders. The
+// middleware returns a HTTPError with code "not_acceptable" so that the controller error handler
+// writes the response. The code generated by goagen mounts the middleware on the actions whose
+// design define a NotAcceptable response, the media types being the ones of the action responses.
+func RequireAcceptable(mediaTypes ...string) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			accept := req.Header.Get("Accept")
+			service := RequestService(ctx)
+			if accept == "" || service == nil {
+				return h(ctx, rw, req)
+			}
+			ranges := parseAccept(accept)
+			for _, mt := range mediaTypes {
+				if quality(ranges, mt) > 0 {
+					return h(ctx, rw, req)
+				}
+			}
+			if _, ok := service.Negotiate(accept, ""); ok {
+				return h(ctx, rw, req)
+			}
+			return NewHTTPError(http.StatusNotAcceptable, "not_acceptable",
+				"none of the media types in %q are supported", accept)
+		}
+	}
+}
+
+// RequireIfMatch is a middleware that rejects the requests that do not carry the If-Match header.
+// The middleware returns a TypedError with id ErrPreconditionRequired so that the controller error
+// handler writes a response with status 428. The code generated by goagen mounts the middleware on
+// the actions whose design use RequireIfMatch.
+func RequireIfMatch() Middleware {
+	return func(h Handler) Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			if req.Header.Get("If-Match") == "" {
+				return &TypedError{
+					ID:   ErrPreconditionRequired,
+					Mesg: "missing If-Match header, retrieve the resource to get its entity tag",
+				}
+			}
+			return h(ctx, rw, req)
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package goa_test
 
 import (
+	"bytes"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -152,3 +157,348 @@ var _ = Describe("NewMiddleware", func() {
 
 	})
 })
+
+var _ = Describe("RequestID", func() {
+	const reqID = "request id"
+	var ctx context.Context
+	var rw http.ResponseWriter
+	var req *http.Request
+	var newCtx context.Context
+
+	BeforeEach(func() {
+		service := goa.New("test")
+		ctrl := service.NewController("foo")
+		var err error
+		req, err = http.NewRequest("GET", "/goo", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rw = &TestResponseWriter{ParentHeader: http.Header{}}
+		ctx = goa.NewContext(ctrl.Context, rw, req, nil)
+	})
+
+	JustBeforeEach(func() {
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			newCtx = ctx
+			return nil
+		}
+		Ω(goa.RequestID()(h)(ctx, rw, req)).ShouldNot(HaveOccurred())
+	})
+
+	Context("with a request ID header", func() {
+		BeforeEach(func() {
+			req.Header.Set(goa.RequestIDHeader, reqID)
+		})
+
+		It("uses the header value", func() {
+			Ω(goa.ContextRequestID(newCtx)).Should(Equal(reqID))
+		})
+
+		It("sets the response header", func() {
+			Ω(rw.Header().Get(goa.RequestIDHeader)).Should(Equal(reqID))
+		})
+	})
+
+	Context("with no request ID header", func() {
+		It("creates a new request ID", func() {
+			id := goa.ContextRequestID(newCtx)
+			Ω(id).ShouldNot(BeEmpty())
+			Ω(rw.Header().Get(goa.RequestIDHeader)).Should(Equal(id))
+		})
+	})
+})
+
+var _ = Describe("LogRequest", func() {
+	var ctx context.Context
+	var rw http.ResponseWriter
+	var req *http.Request
+	var out bytes.Buffer
+
+	BeforeEach(func() {
+		out.Reset()
+		service := goa.New("test")
+		service.UseLogger(goa.NewStdLogger(log.New(&out, "", 0)))
+		service.Encoder(goa.NewJSONEncoder, "*/*")
+		ctrl := service.NewController("foo")
+		var err error
+		req, err = http.NewRequest("POST", "/goo?sort=asc", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.Header.Set(goa.RequestIDHeader, "42")
+		rw = &TestResponseWriter{ParentHeader: http.Header{}}
+		params := url.Values{"sort": []string{"asc"}}
+		ctx = goa.NewContext(ctrl.Context, rw, req, params)
+	})
+
+	JustBeforeEach(func() {
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			return goa.Response(ctx).Send(ctx, 200, "ok")
+		}
+		lg := goa.RequestID()(goa.LogRequest(true)(h))
+		Ω(lg(ctx, rw, req)).ShouldNot(HaveOccurred())
+	})
+
+	It("logs the request and response", func() {
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		Ω(lines).Should(HaveLen(4))
+		Ω(lines[0]).Should(Equal("[INFO] started id=42 method=POST path=/goo?sort=asc"))
+		Ω(lines[1]).Should(ContainSubstring("[INFO] headers id=42 X-Request-Id=42"))
+		Ω(lines[2]).Should(Equal("[INFO] params id=42 sort=asc"))
+		Ω(lines[3]).Should(HavePrefix("[INFO] completed id=42 status=200 bytes=5 time="))
+	})
+})
+
+var _ = Describe("Recover", func() {
+	var ctx context.Context
+	var rw http.ResponseWriter
+	var req *http.Request
+	var out bytes.Buffer
+	var err error
+
+	BeforeEach(func() {
+		out.Reset()
+		service := goa.New("test")
+		service.UseLogger(goa.NewStdLogger(log.New(&out, "", 0)))
+		service.Encoder(goa.NewJSONEncoder, "application/json")
+		ctrl := service.NewController("foo")
+		req, err = http.NewRequest("GET", "/goo", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rw = &TestResponseWriter{ParentHeader: http.Header{}}
+		ctx = goa.NewContext(ctrl.Context, rw, req, nil)
+	})
+
+	JustBeforeEach(func() {
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			panic("boom")
+		}
+		err = goa.Recover()(h)(ctx, rw, req)
+	})
+
+	It("recovers and returns a typed error", func() {
+		Ω(err).Should(HaveOccurred())
+		Ω(err).Should(BeAssignableToTypeOf(&goa.TypedError{}))
+		tErr := err.(*goa.TypedError)
+		Ω(tErr.ID).Should(Equal(goa.ErrorID(goa.ErrPanic)))
+		Ω(tErr.Mesg).Should(Equal("boom"))
+	})
+
+	It("logs the panic and the stack trace", func() {
+		Ω(out.String()).Should(ContainSubstring("[ERROR] panic err=boom stack="))
+		Ω(out.String()).Should(ContainSubstring("middleware_test.go"))
+	})
+
+	It("does not write the panic value to the response", func() {
+		goa.DefaultErrorHandler(ctx, rw, req, err)
+		Ω(rw.(*TestResponseWriter).Status).Should(Equal(500))
+		Ω(string(rw.(*TestResponseWriter).Body)).Should(ContainSubstring("Internal Server Error"))
+		Ω(string(rw.(*TestResponseWriter).Body)).ShouldNot(ContainSubstring("boom"))
+	})
+})
+
+var _ = Describe("Timeout", func() {
+	const timeout = 10 * time.Millisecond
+	var ctx context.Context
+	var rw http.ResponseWriter
+	var req *http.Request
+	var h goa.Handler
+	var err error
+
+	BeforeEach(func() {
+		service := goa.New("test")
+		ctrl := service.NewController("foo")
+		req, err = http.NewRequest("GET", "/goo", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rw = &TestResponseWriter{ParentHeader: http.Header{}}
+		ctx = goa.NewContext(ctrl.Context, rw, req, nil)
+	})
+
+	JustBeforeEach(func() {
+		err = goa.Timeout(timeout)(h)(ctx, rw, req)
+	})
+
+	Context("with a handler that completes in time", func() {
+		BeforeEach(func() {
+			h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				_, ok := ctx.Deadline()
+				Ω(ok).Should(BeTrue())
+				rw.Header().Set("X-Foo", "bar")
+				rw.WriteHeader(200)
+				rw.Write([]byte("ok"))
+				return nil
+			}
+		})
+
+		It("does not return an error", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		It("writes the buffered response", func() {
+			Ω(rw.(*TestResponseWriter).Status).Should(Equal(200))
+			Ω(string(rw.(*TestResponseWriter).Body)).Should(Equal("ok"))
+			Ω(rw.Header().Get("X-Foo")).Should(Equal("bar"))
+			Ω(goa.Response(ctx).Status).Should(Equal(200))
+			Ω(goa.Response(ctx).Length).Should(Equal(2))
+		})
+	})
+
+	Context("with a handler that does not complete in time", func() {
+		BeforeEach(func() {
+			h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+		})
+
+		It("returns a service unavailable error", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err).Should(BeAssignableToTypeOf(&goa.ServiceUnavailableError{}))
+			actual := err.(*goa.ServiceUnavailableError).Actual
+			Ω(actual).Should(BeAssignableToTypeOf(&goa.TypedError{}))
+			Ω(actual.(*goa.TypedError).ID).Should(Equal(goa.ErrorID(goa.ErrRequestTimeout)))
+		})
+	})
+
+	Context("with a handler that writes the body only", func() {
+		BeforeEach(func() {
+			rw.Header().Set("Vary", "Origin")
+			h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				rw.Header()["Vary"][0] = "Accept"
+				rw.Write([]byte("ok"))
+				return nil
+			}
+		})
+
+		It("records the status and length in the response data", func() {
+			Ω(rw.(*TestResponseWriter).Status).Should(Equal(200))
+			Ω(goa.Response(ctx).Status).Should(Equal(200))
+			Ω(goa.Response(ctx).Length).Should(Equal(2))
+			Ω(rw.Header().Get("Vary")).Should(Equal("Accept"))
+		})
+	})
+
+	Context("with a handler that ignores the deadline", func() {
+		var release chan struct{}
+		var werr chan error
+
+		BeforeEach(func() {
+			release = make(chan struct{})
+			werr = make(chan error, 1)
+			rw.Header().Set("Vary", "Origin")
+			h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				<-release
+				rw.Header()["Vary"][0] = "Accept"
+				_, err := rw.Write([]byte("late"))
+				werr <- err
+				return nil
+			}
+		})
+
+		It("returns as soon as the deadline passes", func() {
+			Ω(err).Should(BeAssignableToTypeOf(&goa.ServiceUnavailableError{}))
+			Ω(rw.(*TestResponseWriter).Status).Should(Equal(0))
+			close(release)
+			Eventually(werr).Should(Receive(Equal(http.ErrHandlerTimeout)))
+			Ω(rw.(*TestResponseWriter).Body).Should(BeEmpty())
+			Ω(rw.Header().Get("Vary")).Should(Equal("Origin"))
+		})
+	})
+})
+
+var _ = Describe("RequireAcceptable", func() {
+	var ctx context.Context
+	var rw http.ResponseWriter
+	var req *http.Request
+	var called bool
+	var err error
+
+	BeforeEach(func() {
+		service := goa.New("test")
+		service.Encoder(goa.NewJSONEncoder, "application/json")
+		ctrl := service.NewController("foo")
+		req, err = http.NewRequest("GET", "/goo", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rw = &TestResponseWriter{ParentHeader: http.Header{}}
+		ctx = goa.NewContext(ctrl.Context, rw, req, nil)
+		called = false
+	})
+
+	JustBeforeEach(func() {
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			called = true
+			return nil
+		}
+		err = goa.RequireAcceptable("application/vnd.goa.bottle+json")(h)(ctx, rw, req)
+	})
+
+	Context("with a request accepting the action media type", func() {
+		BeforeEach(func() {
+			req.Header.Set("Accept", "application/vnd.goa.bottle+json")
+		})
+
+		It("calls the handler", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(called).Should(BeTrue())
+		})
+	})
+
+	Context("with a request accepting an encoder media type", func() {
+		BeforeEach(func() {
+			req.Header.Set("Accept", "application/*")
+		})
+
+		It("calls the handler", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(called).Should(BeTrue())
+		})
+	})
+
+	Context("with a request accepting no supported media type", func() {
+		BeforeEach(func() {
+			req.Header.Set("Accept", "image/png")
+		})
+
+		It("returns a not acceptable error", func() {
+			Ω(called).Should(BeFalse())
+			Ω(err).Should(BeAssignableToTypeOf(&goa.HTTPError{}))
+			Ω(err.(*goa.HTTPError).Status).Should(Equal(406))
+			Ω(err.(*goa.HTTPError).Code).Should(Equal("not_acceptable"))
+		})
+	})
+})
+
+var _ = Describe("RequireIfMatch", func() {
+	var req *http.Request
+	var called bool
+	var err error
+
+	BeforeEach(func() {
+		req, err = http.NewRequest("PUT", "/bottles/1", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		called = false
+	})
+
+	JustBeforeEach(func() {
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			called = true
+			return nil
+		}
+		rw := &TestResponseWriter{ParentHeader: http.Header{}}
+		ctx := goa.NewContext(context.Background(), rw, req, nil)
+		err = goa.RequireIfMatch()(h)(ctx, rw, req)
+	})
+
+	Context("with a request carrying the If-Match header", func() {
+		BeforeEach(func() {
+			req.Header.Set("If-Match", `"1"`)
+		})
+
+		It("calls the handler", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(called).Should(BeTrue())
+		})
+	})
+
+	It("returns a precondition required error", func() {
+		Ω(called).Should(BeFalse())
+		Ω(err).Should(BeAssignableToTypeOf(&goa.TypedError{}))
+		Ω(err.(*goa.TypedError).ID).Should(Equal(goa.ErrorID(goa.ErrPreconditionRequired)))
+		Ω(err.(*goa.TypedError).ID.Status()).Should(Equal(428))
+	})
+})
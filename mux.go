@@ -3,6 +3,8 @@ package goa
 import (
 	"net/http"
 	"net/url"
+	"sort"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -19,32 +21,99 @@ type (
 		http.Handler
 		// Handle sets the MuxHandler for a given HTTP method and path.
 		Handle(method, path string, handle MuxHandler)
+		// HandleRoute sets the MuxHandler for the route method and path and records the
+		// route so that it is listed by Routes.
+		HandleRoute(route *Route, handle MuxHandler)
+		// HandleNotFound sets the MuxHandler invoked for requests that don't match any
+		// handler registered with Handle. The values argument given to the handler is nil.
+		HandleNotFound(handle MuxHandler)
+		// HandleMethodNotAllowed sets the MuxHandler invoked for requests whose path matches
+		// handlers registered with Handle but not for the request method. The values argument
+		// given to the handler contains the allowed methods under the "allowed" key, the
+		// mux also sets the "Allow" response header.
+		HandleMethodNotAllowed(handle MuxHandler)
 		// Lookup returns the MuxHandler associated with the given HTTP method and path.
 		Lookup(method, path string) MuxHandler
+		// Routes returns the routes registered with Handle and HandleRoute in the order
+		// they were registered.
+		Routes() []*Route
+	}
+
+	// Route describes a request handler registered with a ServeMux.
+	Route struct {
+		// Method is the route HTTP method.
+		Method string `json:"method"`
+		// Path is the route path, it may contain wildcards, e.g. "/bottles/:id".
+		Path string `json:"path"`
+		// Resource is the name of the controller that handles the requests, empty if the
+		// route was registered with Handle.
+		Resource string `json:"resource,omitempty"`
+		// Action is the name of the controller action.
+		Action string `json:"action,omitempty"`
+		// Middleware is the number of controller middleware applied to the requests.
+		Middleware int `json:"middleware"`
+		// Metadata is the action design metadata if any.
+		Metadata map[string][]string `json:"metadata,omitempty"`
+		// Version is the API version served by the route, empty if the route serves all
+		// versions. See VersionMux.
+		Version string `json:"version,omitempty"`
 	}
 
 	// Muxer implements an adapter that given a request handler can produce a mux handler.
+	// Controller implements Muxer.
 	Muxer interface {
 		MuxHandler(string, Handler, Unmarshaler) MuxHandler
+		LimitedMuxHandler(string, Handler, Unmarshaler, int64) MuxHandler
+		Route(method, path, action string, metadata map[string][]string) *Route
 	}
 
-	// mux is the default ServeMux implementation.
+	// mux is the default ServeMux implementation. On top of dispatching requests to the
+	// registered handlers it:
+	//
+	// * serves HEAD requests with the GET handler registered for the same path if there is no
+	// HEAD handler, the response body is discarded.
+	//
+	// * responds to OPTIONS requests with the "Allow" header listing the methods registered for
+	// the request path if there is no OPTIONS handler.
+	//
+	// * invokes the method not allowed handler if the request path matches handlers registered
+	// for other methods and the not found handler otherwise.
 	mux struct {
-		router  *httprouter.Router
-		handles map[string]MuxHandler
+		router           *httprouter.Router
+		handles          map[string]MuxHandler
+		routes           []*Route
+		methods          map[string]bool
+		notFound         MuxHandler
+		methodNotAllowed MuxHandler
+	}
+
+	// headResponseWriter discards the body of the responses to HEAD requests served by GET
+	// handlers.
+	headResponseWriter struct {
+		http.ResponseWriter
 	}
 )
 
 // NewMux returns a Mux.
 func NewMux() ServeMux {
-	return &mux{
+	m := &mux{
 		router:  httprouter.New(),
 		handles: make(map[string]MuxHandler),
+		methods: make(map[string]bool),
 	}
+	m.router.HandleMethodNotAllowed = false
+	m.router.NotFound = http.HandlerFunc(m.serveNotFound)
+	return m
 }
 
 // Handle sets the handler for the given verb and path.
 func (m *mux) Handle(method, path string, handle MuxHandler) {
+	m.HandleRoute(&Route{Method: method, Path: path}, handle)
+}
+
+// HandleRoute sets the handler for the route verb and path and records the route.
+func (m *mux) HandleRoute(route *Route, handle MuxHandler) {
+	method, path := route.Method, route.Path
 	hthandle := func(rw http.ResponseWriter, req *http.Request, htparams httprouter.Params) {
 		params := req.URL.Query()
 		for _, p := range htparams {
@@ -53,7 +122,20 @@ func (m *mux) Handle(method, path string, handle MuxHandler) {
 		handle(rw, req, params)
 	}
 	m.handles[method+path] = handle
+	m.methods[method] = true
 	m.router.Handle(method, path, hthandle)
+	m.routes = append(m.routes, route)
+}
+
+// HandleNotFound sets the handler invoked for requests that do not match any registered handler.
+func (m *mux) HandleNotFound(handle MuxHandler) {
+	m.notFound = handle
+}
+
+// HandleMethodNotAllowed sets the handler invoked for requests whose path only matches handlers
+// registered for other methods.
+func (m *mux) HandleMethodNotAllowed(handle MuxHandler) {
+	m.methodNotAllowed = handle
 }
 
 // Lookup returns the MuxHandler associated with the given method and path.
@@ -61,7 +143,95 @@ func (m *mux) Lookup(method, path string) MuxHandler {
 	return m.handles[method+path]
 }
 
+// Routes returns the registered routes.
+func (m *mux) Routes() []*Route {
+	routes := make([]*Route, len(m.routes))
+	copy(routes, m.routes)
+	return routes
+}
+
 // ServeHTTP is the function called back by the underlying HTTP server to handle incoming requests.
 func (m *mux) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case "HEAD":
+		if h, _, _ := m.router.Lookup("HEAD", req.URL.Path); h == nil {
+			if h, ps, _ := m.router.Lookup("GET", req.URL.Path); h != nil {
+				h(&headResponseWriter{rw}, req, ps)
+				return
+			}
+		}
+	case "OPTIONS":
+		if h, _, _ := m.router.Lookup("OPTIONS", req.URL.Path); h == nil {
+			if allowed := m.allowed(req.URL.Path); len(allowed) > 0 {
+				serveOptions(rw, allowed)
+				return
+			}
+		}
+	}
 	m.router.ServeHTTP(rw, req)
 }
+
+// serveNotFound handles the requests that do not match any handler.
+func (m *mux) serveNotFound(rw http.ResponseWriter, req *http.Request) {
+	serveUnmatched(rw, req, m.allowed(req.URL.Path), m.notFound, m.methodNotAllowed)
+}
+
+// allowed returns the sorted list of methods that have a handler for the given path.
+func (m *mux) allowed(path string) []string {
+	set := make(map[string]bool)
+	for method := range m.methods {
+		if h, _, _ := m.router.Lookup(method, path); h != nil {
+			set[method] = true
+		}
+	}
+	return allowedMethods(set)
+}
+
+// serveUnmatched invokes methodNotAllowed if allowed is not empty and notFound otherwise. It falls
+// back to plain text responses if the handlers are nil.
+func serveUnmatched(rw http.ResponseWriter, req *http.Request, allowed []string, notFound, methodNotAllowed MuxHandler) {
+	if len(allowed) > 0 {
+		rw.Header().Set("Allow", strings.Join(allowed, ", "))
+		if methodNotAllowed != nil {
+			methodNotAllowed(rw, req, url.Values{"allowed": allowed})
+			return
+		}
+		http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if notFound != nil {
+		notFound(rw, req, url.Values{})
+		return
+	}
+	http.NotFound(rw, req)
+}
+
+// serveOptions responds to an OPTIONS request with the given allowed methods.
+func serveOptions(rw http.ResponseWriter, allowed []string) {
+	rw.Header().Set("Allow", strings.Join(allowed, ", "))
+	rw.Header().Set("Content-Length", "0")
+	rw.WriteHeader(http.StatusOK)
+}
+
+// allowedMethods returns the sorted list of methods in set. The list includes HEAD if set
+// contains GET and OPTIONS if it is not empty.
+func allowedMethods(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	if set["GET"] {
+		set["HEAD"] = true
+	}
+	set["OPTIONS"] = true
+	allowed := make([]string, 0, len(set))
+	for method := range set {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// Write discards the response body.
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
@@ -11,6 +11,9 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// Controller must implement Muxer for the generated mount code to compile.
+var _ goa.Muxer = (*goa.Controller)(nil)
+
 var _ = Describe("Mux", func() {
 	var mux goa.ServeMux
 
@@ -66,4 +69,69 @@ var _ = Describe("Mux", func() {
 		})
 	})
 
+	Context("with a GET handler", func() {
+		var notFound, notAllowed bool
+		var allowed []string
+
+		BeforeEach(func() {
+			var err error
+			req, err = http.NewRequest("GET", "/foo", nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			notFound, notAllowed, allowed = false, false, nil
+			mux.Handle("GET", "/foo", func(rw http.ResponseWriter, req *http.Request, vals url.Values) {
+				rw.Header().Set("Content-Type", "text/plain")
+				rw.WriteHeader(200)
+				rw.Write([]byte("foo"))
+			})
+			mux.Handle("POST", "/foo", func(rw http.ResponseWriter, req *http.Request, vals url.Values) {
+				rw.WriteHeader(201)
+			})
+		})
+
+		Context("receiving a HEAD request", func() {
+			BeforeEach(func() {
+				req.Method = "HEAD"
+			})
+
+			It("uses the GET handler and discards the body", func() {
+				Ω(rw.Status).Should(Equal(200))
+				Ω(rw.ParentHeader.Get("Content-Type")).Should(Equal("text/plain"))
+				Ω(rw.Body).Should(BeEmpty())
+			})
+		})
+
+		Context("receiving an OPTIONS request", func() {
+			BeforeEach(func() {
+				req.Method = "OPTIONS"
+			})
+
+			It("responds with the allowed methods", func() {
+				Ω(rw.Status).Should(Equal(200))
+				Ω(rw.ParentHeader.Get("Allow")).Should(Equal("GET, HEAD, OPTIONS, POST"))
+			})
+		})
+
+		Context("receiving a request with a method that is not allowed", func() {
+			BeforeEach(func() {
+				req.Method = "DELETE"
+				mux.HandleMethodNotAllowed(func(rw http.ResponseWriter, req *http.Request, vals url.Values) {
+					notAllowed = true
+					allowed = vals["allowed"]
+					rw.WriteHeader(405)
+				})
+				mux.HandleNotFound(func(rw http.ResponseWriter, req *http.Request, vals url.Values) {
+					notFound = true
+				})
+			})
+
+			It("invokes the method not allowed handler", func() {
+				Ω(notAllowed).Should(BeTrue())
+				Ω(notFound).Should(BeFalse())
+				Ω(allowed).Should(Equal([]string{"GET", "HEAD", "OPTIONS", "POST"}))
+				Ω(rw.Status).Should(Equal(405))
+				Ω(rw.ParentHeader.Get("Allow")).Should(Equal("GET, HEAD, OPTIONS, POST"))
+			})
+		})
+	})
+
 })
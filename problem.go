@@ -0,0 +1,142 @@
+package goa
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// ProblemMediaType is the media type of the responses written by ProblemErrorHandler.
+const ProblemMediaType = "application/problem+json"
+
+type (
+	// Problem is the RFC 7807 representation of an error.
+	Problem struct {
+		// Type is a URI that identifies the problem type, "about:blank" if none.
+		Type string
+		// Title is a short human readable summary of the problem type.
+		Title string
+		// Status is the HTTP status code.
+		Status int
+		// Detail is a human readable explanation specific to this occurrence of the problem.
+		Detail string
+		// Instance is a URI that identifies this occurrence of the problem.
+		Instance string
+		// Code is the stable error code if any, see HTTPError.
+		Code string
+		// InvalidParams lists the validation errors if any.
+		InvalidParams []*InvalidParam
+		// Fields lists additional problem fields indexed by name.
+		Fields map[string]interface{}
+	}
+
+	// InvalidParam describes a single request validation error.
+	InvalidParam struct {
+		// Name is the name of the invalid parameter, header or attribute if known.
+		Name string `json:"name,omitempty"`
+		// Reason describes why the value is invalid.
+		Reason string `json:"reason"`
+		// ID is the goa error id, see ErrorID.
+		ID ErrorID `json:"id,omitempty"`
+	}
+)
+
+// ProblemErrorHandler writes RFC 7807 "application/problem+json" responses. The response status
+// code is computed the same way as with DefaultErrorHandler, see the package documentation. The
+// "code" and additional fields of HTTPError errors are added to the problem document, the errors
+// contained in a MultiError are listed under "invalid-params". The details of errors that result
+// in 5xx responses are logged but not written to the response.
+func ProblemErrorHandler(ctx context.Context, rw http.ResponseWriter, req *http.Request, e error) {
+	p := NewProblem(e)
+	p.Instance = req.URL.RequestURI()
+	if p.Status >= 500 {
+		Error(ctx, e.Error())
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		Error(ctx, "failed to serialize problem", "err", err)
+		b = []byte(`{"title":"Internal Server Error","status":500}`)
+		p.Status = 500
+	}
+	if resp := Response(ctx); resp != nil {
+		// Record the response status and length
+		rw = resp
+	}
+	rw.Header().Set("Content-Type", ProblemMediaType)
+	rw.WriteHeader(p.Status)
+	rw.Write(b)
+}
+
+// NewProblem builds the problem document that describes the given error. The document of
+// errors that result in 5xx responses has no detail.
+func NewProblem(e error) *Problem {
+	status := errorStatus(e)
+	p := &Problem{Type: "about:blank", Title: http.StatusText(status), Status: status}
+	actual := e
+	switch err := e.(type) {
+	case *BadRequestError:
+		actual = err.Actual
+	case *UnauthorizedError:
+		actual = err.Actual
+	case *ForbiddenError:
+		actual = err.Actual
+	case *ServiceUnavailableError:
+		actual = err.Actual
+	}
+	switch err := actual.(type) {
+	case *HTTPError:
+		if err.Type != "" {
+			p.Type = err.Type
+		}
+		p.Code = err.Code
+		p.Detail = err.Detail
+		p.Fields = err.Fields
+	case *TypedError:
+		p.Title = err.ID.Title()
+		if url := err.ID.URL(); url != "" {
+			p.Type = url
+		}
+		p.Detail = err.Mesg
+	case MultiError:
+		p.InvalidParams = make([]*InvalidParam, len(err))
+		for i, e := range err {
+			if te, ok := e.(*TypedError); ok {
+				p.InvalidParams[i] = &InvalidParam{Name: te.Field, Reason: te.Mesg, ID: te.ID}
+			} else {
+				p.InvalidParams[i] = &InvalidParam{Reason: e.Error()}
+			}
+		}
+	default:
+		p.Detail = actual.Error()
+	}
+	if status >= 500 {
+		// The details of server errors are logged by the error handler, not written.
+		p.Detail = ""
+	}
+	return p
+}
+
+// MarshalJSON implements the json marshaler interface.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]interface{}, len(p.Fields)+7)
+	for k, v := range p.Fields {
+		fields[k] = v
+	}
+	fields["type"] = p.Type
+	fields["title"] = p.Title
+	fields["status"] = p.Status
+	if p.Detail != "" {
+		fields["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		fields["instance"] = p.Instance
+	}
+	if p.Code != "" {
+		fields["code"] = p.Code
+	}
+	if len(p.InvalidParams) > 0 {
+		fields["invalid-params"] = p.InvalidParams
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,96 @@
+package goa_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("ProblemErrorHandler", func() {
+	var err error
+	var rw *httptest.ResponseRecorder
+	var problem map[string]interface{}
+
+	JustBeforeEach(func() {
+		req, e := http.NewRequest("GET", "/bottles/1?full=true", nil)
+		Ω(e).ShouldNot(HaveOccurred())
+		rw = httptest.NewRecorder()
+		goa.ProblemErrorHandler(context.Background(), rw, req, err)
+		problem = nil
+		Ω(json.Unmarshal(rw.Body.Bytes(), &problem)).ShouldNot(HaveOccurred())
+	})
+
+	Context("with a HTTP error", func() {
+		BeforeEach(func() {
+			err = goa.NewHTTPError(404, "bottle_not_found", "no bottle with id %d", 1).
+				WithType("https://goa.design/errors/bottle_not_found").
+				WithField("id", 1)
+		})
+
+		It("writes a problem document with the error status, code and fields", func() {
+			Ω(rw.Code).Should(Equal(404))
+			Ω(rw.Header().Get("Content-Type")).Should(Equal(goa.ProblemMediaType))
+			Ω(problem).Should(Equal(map[string]interface{}{
+				"type":     "https://goa.design/errors/bottle_not_found",
+				"title":    "Not Found",
+				"status":   float64(404),
+				"detail":   "no bottle with id 1",
+				"instance": "/bottles/1?full=true",
+				"code":     "bottle_not_found",
+				"id":       float64(1),
+			}))
+		})
+	})
+
+	Context("with validation errors", func() {
+		BeforeEach(func() {
+			merr := goa.MissingParamError("id", nil)
+			merr = goa.ReportError(merr, errors.New("boom"))
+			err = goa.NewBadRequestError(merr)
+		})
+
+		It("lists the errors as invalid params", func() {
+			Ω(rw.Code).Should(Equal(400))
+			Ω(problem["type"]).Should(Equal("about:blank"))
+			Ω(problem["invalid-params"]).Should(Equal([]interface{}{
+				map[string]interface{}{
+					"name":   "id",
+					"reason": `missing required parameter "id"`,
+					"id":     float64(goa.ErrMissingParam),
+				},
+				map[string]interface{}{
+					"reason": "boom",
+				},
+			}))
+		})
+	})
+
+	Context("with an internal error", func() {
+		BeforeEach(func() {
+			err = errors.New("database password is 1234")
+		})
+
+		It("does not leak the error details", func() {
+			Ω(rw.Code).Should(Equal(500))
+			Ω(problem).ShouldNot(HaveKey("detail"))
+			Ω(problem["title"]).Should(Equal("Internal Server Error"))
+		})
+	})
+
+	Context("with a recovered panic", func() {
+		BeforeEach(func() {
+			err = &goa.TypedError{ID: goa.ErrPanic, Mesg: "runtime error: nil pointer dereference"}
+		})
+
+		It("does not leak the panic value", func() {
+			Ω(rw.Code).Should(Equal(500))
+			Ω(problem).ShouldNot(HaveKey("detail"))
+		})
+	})
+})
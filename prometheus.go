@@ -0,0 +1,300 @@
+package goa
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"golang.org/x/net/context"
+)
+
+type (
+	// PrometheusSink is a go-metrics sink that keeps the counters, gauges and samples in memory
+	// and renders them using the Prometheus text exposition format. It also implements Metrics
+	// so that it may be used directly as a service Metrics.
+	//
+	// The metric names are built by joining the key elements with underscores. The elements of
+	// the keys of the metrics recorded by goa that identify the resource, action, status etc.
+	// are rendered as labels, so for example the key "goa.response.bottle.show.200" results in:
+	//
+	//	goa_response_total{resource="bottle",action="show",status="200"} 1
+	//
+	// Counters get the "_total" suffix and samples - including timings - are rendered as
+	// summaries with "_count" and "_sum" series. Timings are recorded in milliseconds.
+	PrometheusSink struct {
+		sync.Mutex
+		series map[string]*promSeries
+	}
+
+	// promSeries is a time series stored by PrometheusSink.
+	promSeries struct {
+		kind   string // "counter", "gauge" or "summary"
+		name   string
+		labels string
+		value  float64
+		count  uint64
+	}
+
+	// promKey describes the labels encoded in the keys of the metrics recorded by goa.
+	promKey struct {
+		prefix []string
+		labels []string
+	}
+
+	// fanoutMetrics records metrics using multiple Metrics.
+	fanoutMetrics []Metrics
+)
+
+// promKeys lists the keys of the metrics recorded by goa whose trailing elements are labels.
+var promKeys = []promKey{
+	{[]string{"goa", "response"}, []string{"resource", "action", "status"}},
+	{[]string{"goa", "request"}, []string{"resource", "action", "status"}},
+	{[]string{"goa", "handler", "error"}, []string{"status"}},
+	{[]string{"goa", "error"}, []string{"id"}},
+	{[]string{"goa", "decode"}, []string{"content_type"}},
+	{[]string{"goa", "encode"}, []string{"content_type"}},
+	{[]string{"goa", "validation", "error"}, []string{"format"}},
+	{[]string{"goa", "ratelimit", "rejected"}, []string{"scope"}},
+}
+
+var (
+	// promEscaper escapes label values.
+	promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// NewPrometheusSink returns an empty PrometheusSink.
+func NewPrometheusSink() *PrometheusSink {
+	return &PrometheusSink{series: make(map[string]*promSeries)}
+}
+
+// MountMetricsController mounts a controller that renders the service metrics using the
+// Prometheus text exposition format under the given path. The metrics are read from the
+// service Metrics if it is a PrometheusSink. Otherwise MountMetricsController creates a
+// PrometheusSink and adds it to the service Metrics or to the instance initialized with NewMetrics.
+func (service *Service) MountMetricsController(path string) {
+	sink, ok := service.Metrics.(*PrometheusSink)
+	if !ok {
+		sink = NewPrometheusSink()
+		if m := service.metrics(); m == (noMetrics{}) {
+			service.Metrics = sink
+		} else {
+			service.Metrics = fanoutMetrics{m, sink}
+		}
+	}
+	ctrl := service.NewController("metrics")
+	handle := ctrl.MuxHandler("show", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		sink.ServeHTTP(rw, req)
+		return nil
+	}, nil)
+	service.Mux.HandleRoute(ctrl.Route("GET", path, "show", nil), handle)
+	service.Info("mount", "ctrl", "metrics", "action", "show", "route", "GET "+path)
+}
+
+// ServeHTTP renders the metrics using the Prometheus text exposition format.
+func (p *PrometheusSink) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	rw.WriteHeader(200)
+	p.WriteTo(rw)
+}
+
+// WriteTo writes the metrics to w using the Prometheus text exposition format. The metrics are
+// sorted by name and labels.
+func (p *PrometheusSink) WriteTo(w io.Writer) (int64, error) {
+	p.Lock()
+	all := make([]promSeries, 0, len(p.series))
+	for _, s := range p.series {
+		all = append(all, *s)
+	}
+	p.Unlock()
+	sort.Sort(byNameAndLabels(all))
+	var b bytes.Buffer
+	for i, s := range all {
+		if i == 0 || all[i-1].name != s.name {
+			fmt.Fprintf(&b, "# TYPE %s %s\n", s.name, s.kind)
+		}
+		if s.kind == "summary" {
+			fmt.Fprintf(&b, "%s_count%s %d\n", s.name, s.labels, s.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", s.name, s.labels, formatFloat(s.value))
+			continue
+		}
+		fmt.Fprintf(&b, "%s%s %s\n", s.name, s.labels, formatFloat(s.value))
+	}
+	n, err := w.Write(b.Bytes())
+	return int64(n), err
+}
+
+// SetGauge implements metrics.MetricSink.
+func (p *PrometheusSink) SetGauge(key []string, val float32) {
+	p.SetGaugeWithLabels(key, val, nil)
+}
+
+// SetGaugeWithLabels implements metrics.MetricSink.
+func (p *PrometheusSink) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.record("gauge", key, labels, func(s *promSeries) { s.value = float64(val) })
+}
+
+// EmitKey implements metrics.MetricSink, the value is recorded as a gauge.
+func (p *PrometheusSink) EmitKey(key []string, val float32) {
+	p.SetGaugeWithLabels(key, val, nil)
+}
+
+// IncrCounter implements metrics.MetricSink.
+func (p *PrometheusSink) IncrCounter(key []string, val float32) {
+	p.IncrCounterWithLabels(key, val, nil)
+}
+
+// IncrCounterWithLabels implements metrics.MetricSink.
+func (p *PrometheusSink) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.record("counter", key, labels, func(s *promSeries) { s.value += float64(val) })
+}
+
+// AddSample implements metrics.MetricSink.
+func (p *PrometheusSink) AddSample(key []string, val float32) {
+	p.AddSampleWithLabels(key, val, nil)
+}
+
+// AddSampleWithLabels implements metrics.MetricSink.
+func (p *PrometheusSink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.record("summary", key, labels, func(s *promSeries) {
+		s.count++
+		s.value += float64(val)
+	})
+}
+
+// MeasureSince implements Metrics, it records the elapsed time in milliseconds as a sample.
+func (p *PrometheusSink) MeasureSince(key []string, start time.Time) {
+	p.AddSample(key, float32(time.Since(start).Nanoseconds())/float32(time.Millisecond))
+}
+
+// record updates the time series identified by the key and labels using fn.
+func (p *PrometheusSink) record(kind string, key []string, labels []metrics.Label, fn func(*promSeries)) {
+	name, lbls := promName(key, labels)
+	if kind == "counter" && !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	id := name + lbls
+	p.Lock()
+	defer p.Unlock()
+	s, ok := p.series[id]
+	if !ok {
+		s = &promSeries{kind: kind, name: name, labels: lbls}
+		p.series[id] = s
+	}
+	fn(s)
+}
+
+// promName computes the Prometheus metric name and labels for the given key.
+func promName(key []string, labels []metrics.Label) (string, string) {
+	var names, values []string
+	nameKey := key
+	for _, k := range promKeys {
+		if i := indexOf(key, k.prefix); i >= 0 && len(key) == i+len(k.prefix)+len(k.labels) {
+			nameKey = key[:i+len(k.prefix)]
+			names = append(names, k.labels...)
+			values = append(values, key[i+len(k.prefix):]...)
+			break
+		}
+	}
+	for _, l := range labels {
+		names = append(names, l.Name)
+		values = append(values, l.Value)
+	}
+	parts := make([]string, len(nameKey))
+	for i, k := range nameKey {
+		parts[i] = promSanitize(k)
+	}
+	name := strings.Join(parts, "_")
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	if len(names) == 0 {
+		return name, ""
+	}
+	pairs := make([]string, len(names))
+	for i, n := range names {
+		pairs[i] = promSanitize(n) + `="` + promEscaper.Replace(values[i]) + `"`
+	}
+	return name, "{" + strings.Join(pairs, ",") + "}"
+}
+
+// indexOf returns the index of the first occurrence of prefix in key, -1 if there is none.
+func indexOf(key, prefix []string) int {
+	for i := 0; i+len(prefix) <= len(key); i++ {
+		match := true
+		for j, p := range prefix {
+			if key[i+j] != p {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// promSanitize replaces the characters that may not appear in Prometheus metric and label names
+// with underscores.
+func promSanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// formatFloat formats a sample value.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// byNameAndLabels sorts time series by name and labels.
+type byNameAndLabels []promSeries
+
+func (b byNameAndLabels) Len() int      { return len(b) }
+func (b byNameAndLabels) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byNameAndLabels) Less(i, j int) bool {
+	if b[i].name != b[j].name {
+		return b[i].name < b[j].name
+	}
+	return b[i].labels < b[j].labels
+}
+
+func (f fanoutMetrics) AddSample(key []string, val float32) {
+	for _, m := range f {
+		m.AddSample(key, val)
+	}
+}
+
+func (f fanoutMetrics) EmitKey(key []string, val float32) {
+	for _, m := range f {
+		m.EmitKey(key, val)
+	}
+}
+
+func (f fanoutMetrics) IncrCounter(key []string, val float32) {
+	for _, m := range f {
+		m.IncrCounter(key, val)
+	}
+}
+
+func (f fanoutMetrics) MeasureSince(key []string, start time.Time) {
+	for _, m := range f {
+		m.MeasureSince(key, start)
+	}
+}
+
+func (f fanoutMetrics) SetGauge(key []string, val float32) {
+	for _, m := range f {
+		m.SetGauge(key, val)
+	}
+}
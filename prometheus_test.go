@@ -0,0 +1,93 @@
+package goa_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("PrometheusSink", func() {
+	var sink *goa.PrometheusSink
+	var out string
+
+	BeforeEach(func() {
+		sink = goa.NewPrometheusSink()
+	})
+
+	JustBeforeEach(func() {
+		var b bytes.Buffer
+		_, err := sink.WriteTo(&b)
+		Ω(err).ShouldNot(HaveOccurred())
+		out = b.String()
+	})
+
+	Context("with goa metrics", func() {
+		BeforeEach(func() {
+			sink.IncrCounter([]string{"cellar", "goa", "response", "bottle", "show", "200"}, 1)
+			sink.IncrCounter([]string{"cellar", "goa", "response", "bottle", "show", "200"}, 1)
+			sink.IncrCounter([]string{"cellar", "goa", "response", "bottle", "create", "201"}, 1)
+			sink.AddSample([]string{"cellar", "goa", "encode", "application/json"}, 2)
+			sink.AddSample([]string{"cellar", "goa", "encode", "application/json"}, 3)
+		})
+
+		It("renders the key elements as labels", func() {
+			Ω(out).Should(Equal(`# TYPE cellar_goa_encode summary
+cellar_goa_encode_count{content_type="application/json"} 2
+cellar_goa_encode_sum{content_type="application/json"} 5
+# TYPE cellar_goa_response_total counter
+cellar_goa_response_total{resource="bottle",action="create",status="201"} 1
+cellar_goa_response_total{resource="bottle",action="show",status="200"} 2
+`))
+		})
+	})
+
+	Context("with custom metrics", func() {
+		BeforeEach(func() {
+			sink.SetGauge([]string{"cellar", "db.connections"}, 4)
+			sink.SetGauge([]string{"cellar", "db.connections"}, 3)
+			sink.IncrCounterWithLabels([]string{"cellar", "hits"}, 1, []metrics.Label{{Name: "path", Value: `/a"b`}})
+		})
+
+		It("sanitizes the names and escapes the label values", func() {
+			Ω(out).Should(ContainSubstring("# TYPE cellar_db_connections gauge\ncellar_db_connections 3\n"))
+			Ω(out).Should(ContainSubstring(`cellar_hits_total{path="/a\"b"} 1`))
+		})
+	})
+})
+
+var _ = Describe("MountMetricsController", func() {
+	var service *goa.Service
+	var rw *httptest.ResponseRecorder
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		service.MountMetricsController("/metrics")
+		ctrl := service.NewController("bottle")
+		h := ctrl.MuxHandler("show", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			rw.WriteHeader(200)
+			return nil
+		}, nil)
+		service.Mux.Handle("GET", "/bottles/:id", h)
+		service.Metrics.MeasureSince([]string{"goa", "request", "bottle", "show", "200"}, time.Now())
+		for _, path := range []string{"/bottles/1", "/metrics"} {
+			req, err := http.NewRequest("GET", path, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			rw = httptest.NewRecorder()
+			service.Mux.ServeHTTP(rw, req)
+		}
+	})
+
+	It("renders the service metrics", func() {
+		Ω(rw.Code).Should(Equal(200))
+		Ω(rw.Header().Get("Content-Type")).Should(HavePrefix("text/plain; version=0.0.4"))
+		Ω(rw.Body.String()).Should(ContainSubstring(`goa_response_total{resource="bottle",action="show",status="200"} 1`))
+		Ω(rw.Body.String()).Should(ContainSubstring(`goa_request_count{resource="bottle",action="show",status="200"} 1`))
+	})
+})
@@ -0,0 +1,217 @@
+package goa
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type (
+	// RateLimitKeyFunc is the function used by the RateLimit middleware to identify the client
+	// making the request. Requests sharing the same key share the same token bucket. An empty
+	// key causes the middleware to use the client IP instead.
+	RateLimitKeyFunc func(ctx context.Context, req *http.Request) string
+
+	// RateLimitStore is the interface implemented by the token bucket stores used by the
+	// RateLimit middleware. The default store keeps the buckets in memory, implement this
+	// interface to share buckets between multiple instances of a service.
+	RateLimitStore interface {
+		// Take removes a token from the bucket identified by key. The bucket holds at most
+		// requests tokens and is refilled at a rate of requests tokens per period.
+		Take(key string, requests int, per time.Duration) (*RateLimitStatus, error)
+	}
+
+	// RateLimitStatus is the state of a token bucket returned by RateLimitStore.Take.
+	RateLimitStatus struct {
+		// Allowed is true if a token was available.
+		Allowed bool
+		// Remaining is the number of tokens left in the bucket.
+		Remaining int
+		// RetryAfter is the duration until the next token is available, zero if Allowed is
+		// true.
+		RetryAfter time.Duration
+		// Reset is the duration until the bucket is full again.
+		Reset time.Duration
+	}
+
+	// memoryRateLimitStore is the in-memory implementation of RateLimitStore.
+	memoryRateLimitStore struct {
+		sync.Mutex
+		buckets map[string]*bucket
+		evicted time.Time
+		// lru lists the buckets from the most to the least recently used.
+		lru *list.List
+		// max is the maximum number of buckets.
+		max int
+	}
+
+	// bucket is a token bucket.
+	bucket struct {
+		tokens float64
+		last   time.Time
+		// per is the rate limit period of the bucket.
+		per time.Duration
+		// key is the key of the bucket.
+		key string
+		// elem is the element of the bucket in the store LRU list.
+		elem *list.Element
+	}
+)
+
+// DefaultMaxRateLimitBuckets is the maximum number of token buckets kept by the store returned by
+// NewMemoryRateLimitStore.
+const DefaultMaxRateLimitBuckets = 100000
+
+// NewMemoryRateLimitStore returns a RateLimitStore that keeps at most DefaultMaxRateLimitBuckets
+// token buckets in memory, see NewBoundedMemoryRateLimitStore.
+func NewMemoryRateLimitStore() RateLimitStore {
+	return NewBoundedMemoryRateLimitStore(DefaultMaxRateLimitBuckets)
+}
+
+// NewBoundedMemoryRateLimitStore returns a RateLimitStore that keeps at most maxBuckets token
+// buckets in memory. The least recently used bucket is dropped when a new bucket is needed and the
+// store is full so that the memory used by the store does not depend on the number of clients.
+func NewBoundedMemoryRateLimitStore(maxBuckets int) RateLimitStore {
+	if maxBuckets < 1 {
+		maxBuckets = 1
+	}
+	return &memoryRateLimitStore{buckets: make(map[string]*bucket), lru: list.New(), max: maxBuckets}
+}
+
+// RateLimit returns a middleware that limits the number of requests made by a client to requests
+// per period using a token bucket. scope identifies the set of actions sharing the limit, e.g. the
+// resource name for limits defined on a resource. key identifies the client, ClientIPRateLimitKey
+// is used if nil. The buckets are kept in the service RateLimitStore.
+//
+// Requests that exceed the limit are not handled, the middleware returns a TypedError with id
+// ErrRateLimited instead so that the controller error handler writes a response with status 429.
+// The middleware sets the Retry-After header on such responses and the X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset headers on all responses. The requests are handled
+// normally if the store fails.
+func RateLimit(requests int, per time.Duration, scope string, key RateLimitKeyFunc) Middleware {
+	if key == nil {
+		key = ClientIPRateLimitKey
+	}
+	return func(h Handler) Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			service := RequestService(ctx)
+			if service == nil || service.RateLimitStore == nil {
+				return h(ctx, rw, req)
+			}
+			k := key(ctx, req)
+			if k == "" {
+				k = ClientIPRateLimitKey(ctx, req)
+			}
+			status, err := service.RateLimitStore.Take(scope+":"+k, requests, per)
+			if err != nil {
+				Error(ctx, "rate limit store failed", "err", err)
+				return h(ctx, rw, req)
+			}
+			header := rw.Header()
+			header.Set("X-RateLimit-Limit", strconv.Itoa(requests))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+			header.Set("X-RateLimit-Reset", strconv.Itoa(seconds(status.Reset)))
+			if !status.Allowed {
+				service.metrics().IncrCounter([]string{"goa", "ratelimit", "rejected", scope}, 1.0)
+				header.Set("Retry-After", strconv.Itoa(seconds(status.RetryAfter)))
+				return &TypedError{
+					ID:   ErrRateLimited,
+					Mesg: fmt.Sprintf("rate limit of %d requests per %s exceeded", requests, per),
+				}
+			}
+			return h(ctx, rw, req)
+		}
+	}
+}
+
+// ClientIPRateLimitKey is a RateLimitKeyFunc that identifies clients by IP address. It does not
+// take the X-Forwarded-For header into account as clients may set it to any value, use a custom
+// key function for services running behind a trusted proxy.
+func ClientIPRateLimitKey(ctx context.Context, req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// APIKeyRateLimitKey returns a RateLimitKeyFunc that identifies clients by API key. in is the
+// location of the key, "header" or "query", and name the name of the header or query string
+// parameter. Only keys validated by the APIKeyAuth, JWTAuth or OAuth2Auth middleware are used so
+// that clients cannot get a new bucket by sending random keys, the RateLimit middleware must thus
+// run after the security middleware. The middleware falls back to the client IP for requests that
+// do not carry a validated key.
+func APIKeyRateLimitKey(in, name string) RateLimitKeyFunc {
+	return func(ctx context.Context, req *http.Request) string {
+		return contextCredential(ctx, in, name)
+	}
+}
+
+// Take implements RateLimitStore.
+func (s *memoryRateLimitStore) Take(key string, requests int, per time.Duration) (*RateLimitStatus, error) {
+	if requests <= 0 || per <= 0 {
+		return nil, fmt.Errorf("invalid rate limit of %d requests per %s", requests, per)
+	}
+	now := time.Now()
+	rate := float64(requests) / float64(per) // tokens per nanosecond
+	s.Lock()
+	defer s.Unlock()
+	b, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= s.max {
+			s.evict(now)
+		}
+		if len(s.buckets) >= s.max {
+			s.remove(s.lru.Back().Value.(*bucket))
+		}
+		b = &bucket{tokens: float64(requests), last: now, per: per, key: key}
+		b.elem = s.lru.PushFront(b)
+		s.buckets[key] = b
+	} else {
+		b.tokens = math.Min(float64(requests), b.tokens+float64(now.Sub(b.last))*rate)
+		b.last = now
+		b.per = per
+		s.lru.MoveToFront(b.elem)
+	}
+	status := &RateLimitStatus{}
+	if b.tokens >= 1 {
+		b.tokens--
+		status.Allowed = true
+	} else {
+		status.RetryAfter = time.Duration((1 - b.tokens) / rate)
+	}
+	status.Remaining = int(b.tokens)
+	status.Reset = time.Duration((float64(requests) - b.tokens) / rate)
+	if now.Sub(s.evicted) > per {
+		s.evict(now)
+	}
+	return status, nil
+}
+
+// evict removes the buckets that were not used for two of their periods, these are full again.
+func (s *memoryRateLimitStore) evict(now time.Time) {
+	s.evicted = now
+	for _, b := range s.buckets {
+		if now.Sub(b.last) > 2*b.per {
+			s.remove(b)
+		}
+	}
+}
+
+// remove removes the given bucket from the store.
+func (s *memoryRateLimitStore) remove(b *bucket) {
+	delete(s.buckets, b.key)
+	s.lru.Remove(b.elem)
+}
+
+// seconds rounds the given duration up to the second.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,170 @@
+package goa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("RateLimit", func() {
+	var key goa.RateLimitKeyFunc
+	var auth goa.Middleware
+	var ctx context.Context
+	var calls int
+	var h goa.Handler
+
+	BeforeEach(func() {
+		key = nil
+		auth = nil
+		calls = 0
+		service := goa.New("test")
+		ctrl := service.NewController("foo")
+		ctx = ctrl.Context
+	})
+
+	JustBeforeEach(func() {
+		handler := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			calls++
+			return nil
+		}
+		h = goa.RateLimit(2, time.Hour, "foo", key)(handler)
+		if auth != nil {
+			h = auth(h)
+		}
+	})
+
+	sendKey := func(remoteAddr, apiKey string) (*httptest.ResponseRecorder, error) {
+		req, err := http.NewRequest("GET", "/goo", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Api-Key", apiKey)
+		rw := httptest.NewRecorder()
+		return rw, h(goa.NewContext(ctx, rw, req, nil), rw, req)
+	}
+
+	send := func(remoteAddr string) (*httptest.ResponseRecorder, error) {
+		return sendKey(remoteAddr, "key")
+	}
+
+	It("handles the requests within the limit", func() {
+		rw, err := send("10.0.0.1:1234")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(rw.Header().Get("X-RateLimit-Limit")).Should(Equal("2"))
+		Ω(rw.Header().Get("X-RateLimit-Remaining")).Should(Equal("1"))
+		Ω(rw.Header().Get("X-RateLimit-Reset")).Should(Equal("1800"))
+		Ω(calls).Should(Equal(1))
+	})
+
+	It("rejects the requests exceeding the limit", func() {
+		send("10.0.0.1:1234")
+		send("10.0.0.1:1235")
+		rw, err := send("10.0.0.1:1236")
+		Ω(err).Should(BeAssignableToTypeOf(&goa.TypedError{}))
+		Ω(err.(*goa.TypedError).ID).Should(Equal(goa.ErrorID(goa.ErrRateLimited)))
+		Ω(err.(*goa.TypedError).ID.Status()).Should(Equal(429))
+		Ω(rw.Header().Get("Retry-After")).Should(Equal("1800"))
+		Ω(rw.Header().Get("X-RateLimit-Remaining")).Should(Equal("0"))
+		Ω(calls).Should(Equal(2))
+	})
+
+	It("keeps a bucket per client IP", func() {
+		send("10.0.0.1:1234")
+		send("10.0.0.1:1235")
+		_, err := send("10.0.0.2:1234")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(calls).Should(Equal(3))
+	})
+
+	Context("keyed by API key", func() {
+		BeforeEach(func() {
+			key = goa.APIKeyRateLimitKey("header", "X-Api-Key")
+		})
+
+		It("uses the client IP for keys that are not validated", func() {
+			sendKey("10.0.0.1:1234", "a")
+			sendKey("10.0.0.1:1234", "b")
+			_, err := sendKey("10.0.0.1:1234", "c")
+			Ω(err).Should(HaveOccurred())
+			Ω(calls).Should(Equal(2))
+		})
+
+		Context("validated by the security middleware", func() {
+			BeforeEach(func() {
+				auth = goa.APIKeyAuth("header", "X-Api-Key", func(ctx context.Context, key string) error {
+					return nil
+				})
+			})
+
+			It("shares the bucket between IPs", func() {
+				send("10.0.0.1:1234")
+				send("10.0.0.2:1234")
+				_, err := send("10.0.0.3:1234")
+				Ω(err).Should(HaveOccurred())
+				Ω(calls).Should(Equal(2))
+			})
+		})
+	})
+})
+
+var _ = Describe("MemoryRateLimitStore", func() {
+	var store goa.RateLimitStore
+
+	BeforeEach(func() {
+		store = goa.NewMemoryRateLimitStore()
+	})
+
+	It("refills the bucket over time", func() {
+		const per = 20 * time.Millisecond
+		for i := 0; i < 2; i++ {
+			status, err := store.Take("key", 2, per)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(status.Allowed).Should(BeTrue())
+		}
+		status, err := store.Take("key", 2, per)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(status.Allowed).Should(BeFalse())
+		Ω(status.RetryAfter).Should(BeNumerically(">", 0))
+		time.Sleep(status.RetryAfter)
+		status, err = store.Take("key", 2, per)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(status.Allowed).Should(BeTrue())
+	})
+
+	It("evicts the buckets according to their own period", func() {
+		status, err := store.Take("hourly", 1, time.Hour)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(status.Allowed).Should(BeTrue())
+		for i := 0; i < 2; i++ {
+			time.Sleep(5 * time.Millisecond)
+			_, err = store.Take("other", 1, time.Millisecond)
+			Ω(err).ShouldNot(HaveOccurred())
+		}
+		status, err = store.Take("hourly", 1, time.Hour)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(status.Allowed).Should(BeFalse())
+	})
+
+	It("drops the least recently used buckets once full", func() {
+		store = goa.NewBoundedMemoryRateLimitStore(2)
+		for _, key := range []string{"a", "b", "a", "c"} {
+			_, err := store.Take(key, 1, time.Hour)
+			Ω(err).ShouldNot(HaveOccurred())
+		}
+		status, err := store.Take("a", 1, time.Hour)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(status.Allowed).Should(BeFalse())
+		status, err = store.Take("b", 1, time.Hour)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(status.Allowed).Should(BeTrue())
+	})
+
+	It("rejects invalid limits", func() {
+		_, err := store.Take("key", 0, time.Second)
+		Ω(err).Should(HaveOccurred())
+	})
+})
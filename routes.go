@@ -0,0 +1,62 @@
+package goa
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"golang.org/x/net/context"
+)
+
+// Route returns the description of the route with the given method and path handled by the
+// controller action with the given name. metadata is the action design metadata if any. This
+// method is mainly intended for use by the generated code together with ServeMux.HandleRoute.
+func (ctrl *Controller) Route(method, path, action string, metadata map[string][]string) *Route {
+	return &Route{
+		Method:     method,
+		Path:       path,
+		Resource:   ctrl.Name,
+		Action:     action,
+		Middleware: len(ctrl.Middleware),
+		Metadata:   metadata,
+	}
+}
+
+// Routes returns the routes mounted on the service mux sorted by path and method.
+func (service *Service) Routes() []*Route {
+	routes := service.Mux.Routes()
+	sort.Sort(byPathAndMethod(routes))
+	return routes
+}
+
+// MountRoutesController mounts a controller that lists the service routes under the given path,
+// e.g. "/_routes". The response body is a JSON array of Route objects sorted by path and method.
+// The routes controller is intended for debugging and operations tooling, it should not be
+// exposed publicly.
+func (service *Service) MountRoutesController(path string) {
+	ctrl := service.NewController("routes")
+	handle := ctrl.MuxHandler("list", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		b, err := json.Marshal(service.Routes())
+		if err != nil {
+			return err
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusOK)
+		_, err = rw.Write(b)
+		return err
+	}, nil)
+	service.Mux.HandleRoute(ctrl.Route("GET", path, "list", nil), handle)
+	service.Info("mount", "ctrl", "routes", "action", "list", "route", "GET "+path)
+}
+
+// byPathAndMethod sorts routes by path and method.
+type byPathAndMethod []*Route
+
+func (b byPathAndMethod) Len() int      { return len(b) }
+func (b byPathAndMethod) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byPathAndMethod) Less(i, j int) bool {
+	if b[i].Path != b[j].Path {
+		return b[i].Path < b[j].Path
+	}
+	return b[i].Method < b[j].Method
+}
@@ -0,0 +1,64 @@
+package goa_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("Routes", func() {
+	var service *goa.Service
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		service.Use(goa.RequestID())
+		ctrl := service.NewController("bottle")
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error { return nil }
+		md := map[string][]string{"swagger:tag": {"bottle"}}
+		service.Mux.HandleRoute(ctrl.Route("POST", "/bottles", "create", nil), ctrl.MuxHandler("create", h, nil))
+		service.Mux.HandleRoute(ctrl.Route("GET", "/bottles/:id", "show", md), ctrl.MuxHandler("show", h, nil))
+		service.Mux.Handle("GET", "/raw", func(http.ResponseWriter, *http.Request, url.Values) {})
+	})
+
+	It("lists the mounted routes sorted by path and method", func() {
+		routes := service.Routes()
+		Ω(routes).Should(HaveLen(3))
+		Ω(*routes[0]).Should(Equal(goa.Route{Method: "POST", Path: "/bottles", Resource: "bottle", Action: "create", Middleware: 1}))
+		Ω(*routes[1]).Should(Equal(goa.Route{
+			Method:     "GET",
+			Path:       "/bottles/:id",
+			Resource:   "bottle",
+			Action:     "show",
+			Middleware: 1,
+			Metadata:   map[string][]string{"swagger:tag": {"bottle"}},
+		}))
+		Ω(*routes[2]).Should(Equal(goa.Route{Method: "GET", Path: "/raw"}))
+	})
+
+	Context("with the routes controller", func() {
+		BeforeEach(func() {
+			service.MountRoutesController("/_routes")
+		})
+
+		It("serves the routes", func() {
+			req, err := http.NewRequest("GET", "/_routes", nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			rw := httptest.NewRecorder()
+			service.Mux.ServeHTTP(rw, req)
+			Ω(rw.Code).Should(Equal(200))
+			Ω(rw.Header().Get("Content-Type")).Should(Equal("application/json"))
+			var routes []*goa.Route
+			Ω(json.Unmarshal(rw.Body.Bytes(), &routes)).Should(Succeed())
+			Ω(routes).Should(HaveLen(4))
+			Ω(routes[0].Path).Should(Equal("/_routes"))
+			Ω(routes[0].Resource).Should(Equal("routes"))
+			Ω(routes[0].Action).Should(Equal("list"))
+		})
+	})
+})
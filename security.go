@@ -0,0 +1,158 @@
+package goa
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+type (
+	// BasicAuthValidator is the function called by the BasicAuth middleware to validate the
+	// request credentials. It returns a non nil error if the credentials are invalid.
+	BasicAuthValidator func(ctx context.Context, username, password string) error
+
+	// APIKeyValidator is the function called by the APIKeyAuth middleware to validate the
+	// request API key. It returns a non nil error if the key is invalid.
+	APIKeyValidator func(ctx context.Context, key string) error
+
+	// TokenValidator is the function called by the JWTAuth and OAuth2Auth middleware to
+	// validate the request token. scopes lists the scopes required by the action, the function
+	// should return a ForbiddenError if the token is valid but does not grant all the scopes.
+	TokenValidator func(ctx context.Context, token string, scopes []string) error
+)
+
+// WithRequiredScopes returns a child context that records the scopes required by the action
+// handling the request. The code generated by goagen calls this function prior to calling the
+// security middleware of actions that require scopes.
+func WithRequiredScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, scopesKey, scopes)
+}
+
+// ContextRequiredScopes returns the scopes required by the action handling the request, nil if
+// none.
+func ContextRequiredScopes(ctx context.Context) []string {
+	if s := ctx.Value(scopesKey); s != nil {
+		return s.([]string)
+	}
+	return nil
+}
+
+// BasicAuth returns a middleware that authenticates requests using HTTP basic auth. The middleware
+// responds with 401 if the request is missing credentials or if validate returns an error.
+func BasicAuth(validate BasicAuthValidator) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			user, pass, ok := req.BasicAuth()
+			if !ok {
+				rw.Header().Set("WWW-Authenticate", "Basic")
+				return unauthorized("missing basic auth credentials")
+			}
+			if err := validate(ctx, user, pass); err != nil {
+				rw.Header().Set("WWW-Authenticate", "Basic")
+				return securityError(err)
+			}
+			return h(ctx, rw, req)
+		}
+	}
+}
+
+// APIKeyAuth returns a middleware that authenticates requests using an API key. in is the location
+// of the key, either "header" or "query" and name the name of the header or query string
+// parameter. The middleware responds with 401 if the request is missing the key or if validate
+// returns an error.
+func APIKeyAuth(in, name string, validate APIKeyValidator) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			key := credential(req, in, name)
+			if key == "" {
+				return unauthorized(fmt.Sprintf("missing API key %s %#v", in, name))
+			}
+			if err := validate(ctx, key); err != nil {
+				return securityError(err)
+			}
+			return h(withCredential(ctx, in, name, key), rw, req)
+		}
+	}
+}
+
+// JWTAuth returns a middleware that authenticates requests using JSON Web Tokens. in is the
+// location of the token, either "header" or "query" and name the name of the header or query
+// string parameter. The "Bearer" prefix is stripped from the header value if present. The
+// middleware responds with 401 if the request is missing the token or if validate returns an
+// error. The actual token validation (signature, expiry, scopes etc.) is done by validate.
+func JWTAuth(in, name string, validate TokenValidator) Middleware {
+	return tokenAuth(in, name, validate)
+}
+
+// OAuth2Auth returns a middleware that authenticates requests using OAuth2 bearer access tokens
+// read from the "Authorization" header. The middleware responds with 401 if the request is
+// missing the token or if validate returns an error.
+func OAuth2Auth(validate TokenValidator) Middleware {
+	return tokenAuth("header", "Authorization", validate)
+}
+
+// tokenAuth implements the JWTAuth and OAuth2Auth middleware.
+func tokenAuth(in, name string, validate TokenValidator) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			token := credential(req, in, name)
+			if in == "header" && len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+				token = strings.TrimSpace(token[7:])
+			}
+			if token == "" {
+				rw.Header().Set("WWW-Authenticate", "Bearer")
+				return unauthorized(fmt.Sprintf("missing token %s %#v", in, name))
+			}
+			if err := validate(ctx, token, ContextRequiredScopes(ctx)); err != nil {
+				rw.Header().Set("WWW-Authenticate", "Bearer")
+				return securityError(err)
+			}
+			return h(withCredential(ctx, in, name, token), rw, req)
+		}
+	}
+}
+
+// validatedCredential is the credential of the request validated by the security middleware.
+type validatedCredential struct {
+	in, name, value string
+}
+
+// withCredential returns a child context that records the credential read from the given header
+// or query string parameter once validated.
+func withCredential(ctx context.Context, in, name, value string) context.Context {
+	return context.WithValue(ctx, credentialKey, &validatedCredential{in: in, name: name, value: value})
+}
+
+// contextCredential returns the validated credential read from the given header or query string
+// parameter, the empty string if there is none.
+func contextCredential(ctx context.Context, in, name string) string {
+	if c, ok := ctx.Value(credentialKey).(*validatedCredential); ok && c.in == in && c.name == name {
+		return c.value
+	}
+	return ""
+}
+
+// credential reads the value of the given header or query string parameter.
+func credential(req *http.Request, in, name string) string {
+	if in == "query" {
+		return req.URL.Query().Get(name)
+	}
+	return req.Header.Get(name)
+}
+
+// unauthorized creates an UnauthorizedError with the given message.
+func unauthorized(msg string) error {
+	return NewUnauthorizedError(&TypedError{ID: ErrUnauthorized, Mesg: msg})
+}
+
+// securityError wraps the error returned by a security validator into an UnauthorizedError unless
+// it already is an UnauthorizedError or a ForbiddenError.
+func securityError(err error) error {
+	switch err.(type) {
+	case *UnauthorizedError, *ForbiddenError:
+		return err
+	}
+	return unauthorized(err.Error())
+}
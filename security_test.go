@@ -0,0 +1,173 @@
+package goa_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("Security middleware", func() {
+	var req *http.Request
+	var rw *httptest.ResponseRecorder
+	var called bool
+	var handlerErr error
+
+	handler := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		called = true
+		return nil
+	}
+
+	BeforeEach(func() {
+		var err error
+		req, err = http.NewRequest("GET", "/foo", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rw = httptest.NewRecorder()
+		called = false
+		handlerErr = nil
+	})
+
+	Describe("BasicAuth", func() {
+		var validate goa.BasicAuthValidator
+
+		BeforeEach(func() {
+			validate = func(ctx context.Context, user, pass string) error {
+				if user != "goa" || pass != "secret" {
+					return errors.New("invalid credentials")
+				}
+				return nil
+			}
+		})
+
+		JustBeforeEach(func() {
+			handlerErr = goa.BasicAuth(validate)(handler)(context.Background(), rw, req)
+		})
+
+		Context("with valid credentials", func() {
+			BeforeEach(func() {
+				req.SetBasicAuth("goa", "secret")
+			})
+
+			It("calls the handler", func() {
+				Ω(handlerErr).ShouldNot(HaveOccurred())
+				Ω(called).Should(BeTrue())
+			})
+		})
+
+		Context("with invalid credentials", func() {
+			BeforeEach(func() {
+				req.SetBasicAuth("goa", "wrong")
+			})
+
+			It("returns an unauthorized error", func() {
+				Ω(handlerErr).Should(BeAssignableToTypeOf(&goa.UnauthorizedError{}))
+				Ω(called).Should(BeFalse())
+				Ω(rw.Header().Get("WWW-Authenticate")).Should(Equal("Basic"))
+			})
+		})
+
+		Context("with no credentials", func() {
+			It("returns an unauthorized error", func() {
+				Ω(handlerErr).Should(BeAssignableToTypeOf(&goa.UnauthorizedError{}))
+				Ω(called).Should(BeFalse())
+			})
+		})
+	})
+
+	Describe("APIKeyAuth", func() {
+		var in string
+		var key string
+
+		BeforeEach(func() {
+			key = ""
+		})
+
+		JustBeforeEach(func() {
+			validate := func(ctx context.Context, k string) error {
+				key = k
+				return nil
+			}
+			handlerErr = goa.APIKeyAuth(in, "api_key", validate)(handler)(context.Background(), rw, req)
+		})
+
+		Context("with a key in the query string", func() {
+			BeforeEach(func() {
+				in = "query"
+				req.URL.RawQuery = "api_key=foo"
+			})
+
+			It("validates the key", func() {
+				Ω(handlerErr).ShouldNot(HaveOccurred())
+				Ω(key).Should(Equal("foo"))
+				Ω(called).Should(BeTrue())
+			})
+		})
+
+		Context("with a missing key", func() {
+			BeforeEach(func() {
+				in = "header"
+			})
+
+			It("returns an unauthorized error", func() {
+				Ω(handlerErr).Should(BeAssignableToTypeOf(&goa.UnauthorizedError{}))
+				Ω(called).Should(BeFalse())
+			})
+		})
+	})
+
+	Describe("JWTAuth", func() {
+		var validateErr error
+		var token string
+		var scopes []string
+		var ctx context.Context
+
+		BeforeEach(func() {
+			validateErr = nil
+			token = ""
+			scopes = nil
+			ctx = context.Background()
+			req.Header.Set("Authorization", "Bearer tok")
+		})
+
+		JustBeforeEach(func() {
+			validate := func(ctx context.Context, t string, s []string) error {
+				token = t
+				scopes = s
+				return validateErr
+			}
+			handlerErr = goa.JWTAuth("header", "Authorization", validate)(handler)(ctx, rw, req)
+		})
+
+		It("strips the bearer prefix", func() {
+			Ω(handlerErr).ShouldNot(HaveOccurred())
+			Ω(token).Should(Equal("tok"))
+			Ω(called).Should(BeTrue())
+		})
+
+		Context("with required scopes", func() {
+			BeforeEach(func() {
+				ctx = goa.WithRequiredScopes(ctx, []string{"api:read"})
+			})
+
+			It("passes the scopes to the validator", func() {
+				Ω(scopes).Should(Equal([]string{"api:read"}))
+			})
+		})
+
+		Context("with a validator returning a forbidden error", func() {
+			BeforeEach(func() {
+				validateErr = goa.NewForbiddenError(errors.New("missing scope"))
+			})
+
+			It("returns the error as is", func() {
+				Ω(handlerErr).Should(Equal(validateErr))
+				Ω(called).Should(BeFalse())
+				Ω(rw.Header().Get("WWW-Authenticate")).Should(Equal("Bearer"))
+			})
+		})
+	})
+})
@@ -1,6 +1,7 @@
 package goa
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"log"
@@ -10,6 +11,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/net/context"
 )
@@ -48,8 +50,23 @@ type (
 		Middleware []Middleware
 		// Service-wide error handler
 		ErrorHandler ErrorHandler
+		// MaxBodySize is the maximum number of bytes read from request bodies, zero means no
+		// limit. Requests with larger bodies get a response with status 413. Actions may define
+		// their own limit, see Controller.LimitedMuxHandler.
+		MaxBodySize int64
+		// RateLimitStore keeps the token buckets used by the RateLimit middleware, it defaults
+		// to an in-memory store.
+		RateLimitStore RateLimitStore
+		// Metrics records the service metrics. The instance initialized with NewMetrics is used
+		// if nil, no metric is recorded if there is none either. See Metrics for the list of
+		// metrics recorded by goa.
+		Metrics Metrics
+		// HealthCheckTimeout is the time given to each health check to complete, see
+		// AddHealthCheck. DefaultHealthCheckTimeout is used if zero.
+		HealthCheckTimeout time.Duration
 
 		cancel                context.CancelFunc
+		health                healthChecks            // Registered health checks
 		decoderPools          map[string]*decoderPool // Registered decoders for the service
 		encoderPools          map[string]*encoderPool // Registered encoders for the service
 		encodableContentTypes []string                // List of contentTypes for response negotiation
@@ -84,17 +101,36 @@ func New(name string) *Service {
 	stdlog := log.New(os.Stderr, "", log.LstdFlags)
 	ctx := context.WithValue(context.Background(), logKey, NewStdLogger(stdlog))
 	ctx, cancel := context.WithCancel(ctx)
-	return &Service{
-		Name:         name,
-		ErrorHandler: DefaultErrorHandler,
-		Context:      ctx,
-		Mux:          NewMux(),
+	service := &Service{
+		Name:           name,
+		ErrorHandler:   DefaultErrorHandler,
+		Context:        ctx,
+		RateLimitStore: NewMemoryRateLimitStore(),
 
 		cancel:                cancel,
 		decoderPools:          map[string]*decoderPool{},
 		encoderPools:          map[string]*encoderPool{},
 		encodableContentTypes: []string{},
 	}
+	service.UseMux(NewMux())
+	return service
+}
+
+// UseMux makes the service use the given mux, e.g. a mux returned by NewTreeMux. The routes
+// already registered with the current mux are registered with the new mux. The new mux handles
+// requests that do not match any route with the service error handler.
+func (service *Service) UseMux(mux ServeMux) {
+	if service.Mux != nil {
+		for _, r := range service.Mux.Routes() {
+			lookup := service.Mux.Lookup
+			if vm, ok := service.Mux.(*VersionMux); ok {
+				lookup = vm.Version(r.Version).Lookup
+			}
+			mux.HandleRoute(r, lookup(r.Method, r.Path))
+		}
+	}
+	service.Mux = mux
+	service.mountUnmatched()
 }
 
 // CancelAll sends a cancel signals to all request handlers via the context.
@@ -109,11 +145,25 @@ func (service *Service) CancelAll() {
 // Controller specific middleware should be mounted using the Controller type Use method instead.
 func (service *Service) Use(m Middleware) {
 	service.Middleware = append(service.Middleware, m)
+	service.mountUnmatched()
 }
 
 // UseLogger sets the logger used internally by the service and by Log.
 func (service *Service) UseLogger(logger Logger) {
 	service.Context = context.WithValue(service.Context, logKey, logger)
+	service.mountUnmatched()
+}
+
+// SetLogLevel sets the minimum level of the messages logged by the service and by Log, messages
+// with a lower level are discarded. The default level is LogInfo. As with UseLogger the level
+// applies to the controllers created after the call.
+func (service *Service) SetLogLevel(level LogLevel) {
+	service.Context = context.WithValue(service.Context, logLevelKey, level)
+}
+
+// Debug logs the message and values at odd indeces using the keys at even indeces of the keyvals slice.
+func (service *Service) Debug(msg string, keyvals ...interface{}) {
+	Debug(service.Context, msg, keyvals...)
 }
 
 // Info logs the message and values at odd indeces using the keys at even indeces of the keyvals slice.
@@ -121,32 +171,80 @@ func (service *Service) Info(msg string, keyvals ...interface{}) {
 	Info(service.Context, msg, keyvals...)
 }
 
+// Warn logs the warning and values at odd indeces using the keys at even indeces of the keyvals slice.
+func (service *Service) Warn(msg string, keyvals ...interface{}) {
+	Warn(service.Context, msg, keyvals...)
+}
+
 // Error logs the error and values at odd indeces using the keys at even indeces of the keyvals slice.
 func (service *Service) Error(msg string, keyvals ...interface{}) {
 	Error(service.Context, msg, keyvals...)
 }
 
-// ListenAndServe starts a HTTP server and sets up a listener on the given host/port.
+// ListenAndServe starts a HTTP server and sets up a listener on the given address, see Listen for
+// the supported address formats.
 func (service *Service) ListenAndServe(addr string) error {
-	service.Info("listen", "transport", "http", "addr", addr)
-	return http.ListenAndServe(addr, service.Mux)
+	l, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+	return service.Serve(l)
 }
 
-// ListenAndServeTLS starts a HTTPS server and sets up a listener on the given host/port.
+// ListenAndServeTLS starts a HTTPS server and sets up a listener on the given address, see Listen
+// for the supported address formats.
 func (service *Service) ListenAndServeTLS(addr, certFile, keyFile string) error {
-	service.Info("listen", "transport", "https", "addr", addr)
-	return http.ListenAndServeTLS(addr, certFile, keyFile, service.Mux)
+	l, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+	return service.ServeTLS(l, certFile, keyFile)
+}
+
+// mountUnmatched registers the handlers of the requests that do not match any handler registered
+// with the service mux. The errors go through the service middleware and error handler like
+// errors returned by actions so that they get logged and counted. The handlers capture the service
+// middleware and context so they are built again each time these change.
+func (service *Service) mountUnmatched() {
+	if service.Mux == nil {
+		return
+	}
+	ctrl := service.NewController("mux")
+	ctrl.ErrorHandler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request, err error) {
+		if service.ErrorHandler != nil {
+			service.ErrorHandler(ctx, rw, req, err)
+		}
+	}
+	service.Mux.HandleNotFound(ctrl.MuxHandler("not_found", notFound, nil))
+	service.Mux.HandleMethodNotAllowed(ctrl.MuxHandler("method_not_allowed", methodNotAllowed, nil))
+}
+
+// notFound handles the requests that do not match any handler registered with the service mux.
+func notFound(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+	return &TypedError{ID: ErrNotFound, Mesg: fmt.Sprintf("no handler for %s %s", req.Method, req.URL.Path)}
+}
+
+// methodNotAllowed handles the requests whose path matches handlers registered with the service
+// mux for other methods. The mux sets the "Allow" response header and the "allowed" parameter.
+func methodNotAllowed(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+	allowed := Request(ctx).Params["allowed"]
+	return &TypedError{
+		ID:   ErrMethodNotAllowed,
+		Mesg: fmt.Sprintf("method %s not allowed for %s, allowed methods: %s", req.Method, req.URL.Path, strings.Join(allowed, ", ")),
+	}
 }
 
 // NewController returns a controller for the given resource. This method is mainly intended for
 // use by the generated code. User code shouldn't have to call it directly.
 func (service *Service) NewController(resName string) *Controller {
 	ctx := context.WithValue(service.Context, serviceKey, service)
+	// The "ctrl" key predates the private context keys, it is kept for compatibility.
+	ctx = context.WithValue(ctx, "ctrl", resName)
 	return &Controller{
 		Name:         resName,
 		Middleware:   service.Middleware,
 		ErrorHandler: service.ErrorHandler,
-		Context:      context.WithValue(ctx, "ctrl", resName),
+		Context:      ctx,
 	}
 }
 
@@ -194,7 +292,7 @@ func (service *Service) ServeFiles(path, filename string) error {
 		http.ServeFile(Response(ctx), r.Request, fullpath)
 		return nil
 	}, nil)
-	service.Mux.Handle("GET", path, handle)
+	service.Mux.HandleRoute(ctrl.Route("GET", path, "Serve", nil), handle)
 	return nil
 }
 
@@ -208,11 +306,15 @@ func (ctrl *Controller) Use(m Middleware) {
 // HandleError invokes the controller error handler or - if there isn't one - the service error
 // handler.
 func (ctrl *Controller) HandleError(ctx context.Context, rw http.ResponseWriter, req *http.Request, err error) {
-	status := 500
-	if _, ok := err.(*BadRequestError); ok {
-		status = 400
+	status := errorStatus(err)
+	m := RequestService(ctx).metrics()
+	m.IncrCounter([]string{"goa", "handler", "error", strconv.Itoa(status)}, 1.0)
+	for _, te := range typedErrors(err) {
+		m.IncrCounter([]string{"goa", "error", strconv.Itoa(int(te.ID))}, 1.0)
+		if te.format != "" {
+			m.IncrCounter([]string{"goa", "validation", "error", string(te.format)}, 1.0)
+		}
 	}
-	go IncrCounter([]string{"goa", "handler", "error", strconv.Itoa(status)}, 1.0)
 	if ctrl.ErrorHandler != nil {
 		ctrl.ErrorHandler(ctx, rw, req, err)
 	}
@@ -220,10 +322,23 @@ func (ctrl *Controller) HandleError(ctx context.Context, rw http.ResponseWriter,
 
 // MuxHandler wraps a request handler into a MuxHandler. The MuxHandler initializes the
 // request context by loading the request state, invokes the handler and in case of error invokes
-// the controller (if there is one) or Service error handler.
+// the controller (if there is one) or Service error handler. The request body size is limited by
+// the service MaxBodySize. A panic in the payload unmarshaler is handled by the error handler with
+// a TypedError with id ErrPanic.
 // This function is intended for the controller generated code. User code should not need to call
 // it directly.
 func (ctrl *Controller) MuxHandler(name string, hdlr Handler, unm Unmarshaler) MuxHandler {
+	return ctrl.LimitedMuxHandler(name, hdlr, unm, 0)
+}
+
+// LimitedMuxHandler behaves like MuxHandler except that it limits the request body size to
+// maxBodySize bytes instead of the service MaxBodySize. A zero value means that the service
+// MaxBodySize applies while a negative value disables the limit. Requests whose body exceeds the
+// limit are handled by the error handler with a TypedError with id ErrRequestBodyTooLarge which
+// results in a response with status 413.
+// This function is intended for the controller generated code. User code should not need to call
+// it directly.
+func (ctrl *Controller) LimitedMuxHandler(name string, hdlr Handler, unm Unmarshaler, maxBodySize int64) MuxHandler {
 	// Setup middleware outside of closure
 	middleware := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
 		if !Response(ctx).Written() {
@@ -238,26 +353,61 @@ func (ctrl *Controller) MuxHandler(name string, hdlr Handler, unm Unmarshaler) M
 	for i := range chain {
 		middleware = chain[ml-i-1](middleware)
 	}
-	baseCtx := LogWith(ctrl.Context, "action", name)
+	baseCtx := LogWith(context.WithValue(ctrl.Context, actionKey, name), "action", name)
 	return func(rw http.ResponseWriter, req *http.Request, params url.Values) {
 		// Build context
 		ctx := NewContext(baseCtx, rw, req, params)
 
-		// Load body if any
+		// Limit body size
+		limit := maxBodySize
+		if limit == 0 {
+			if service := RequestService(ctx); service != nil {
+				limit = service.MaxBodySize
+			}
+		}
+		var body *limitedReader
 		var err error
-		if req.ContentLength > 0 && unm != nil {
-			err = unm(ctx, req)
+		if limit > 0 && req.ContentLength != 0 {
+			if req.ContentLength > limit {
+				err = bodyTooLargeError(limit)
+			} else {
+				body = &limitedReader{ReadCloser: http.MaxBytesReader(rw, req.Body, limit), limit: limit}
+				req.Body = body
+			}
+		}
+
+		// Load body if any, a ContentLength of -1 means that the length is unknown (e.g. chunked
+		// transfer encoding)
+		hasBody := req.ContentLength > 0
+		if req.ContentLength < 0 && unm != nil {
+			br := bufio.NewReader(req.Body)
+			_, perr := br.Peek(1)
+			hasBody = perr != io.EOF
+			req.Body = &bufferedBody{Reader: br, Closer: req.Body}
+		}
+		if err == nil && hasBody && unm != nil {
+			err = unmarshal(ctx, req, unm)
+			if body != nil && body.exceeded {
+				err = bodyTooLargeError(limit)
+			}
 		}
 
 		// Handle invalid payload
 		handler := middleware
 		if err != nil {
-			handler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
-				msg := "invalid encoding: " + err.Error()
-				rw.Header().Set("Content-Type", "application/json")
-				rw.WriteHeader(400)
-				rw.Write([]byte(fmt.Sprintf(`{"kind":"invalid request","msg":%q}`, msg)))
-				return nil
+			if te, ok := err.(*TypedError); ok && (te.ID == ErrRequestBodyTooLarge || te.ID == ErrPanic) {
+				handler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+					ctrl.HandleError(ctx, rw, req, err)
+					return nil
+				}
+			} else {
+				handler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+					msg := "invalid encoding: " + err.Error()
+					rw.Header().Set("Content-Type", "application/json")
+					rw.WriteHeader(400)
+					rw.Write([]byte(fmt.Sprintf(`{"kind":"invalid request","msg":%q}`, msg)))
+					return nil
+				}
 			}
 			for i := range chain {
 				handler = chain[ml-i-1](handler)
@@ -265,33 +415,155 @@ func (ctrl *Controller) MuxHandler(name string, hdlr Handler, unm Unmarshaler) M
 		}
 
 		// Invoke middleware chain, wrap writer to capture response status and length
-		handler(ctx, Response(ctx), req)
+		if err := handler(ctx, Response(ctx), req); err != nil {
+			// Errors returned by middleware (e.g. Recover) also go through the error handler
+			ctrl.HandleError(ctx, rw, req, err)
+		}
+
+		// Release the files uploaded with the request, see FileUpload
+		if payload := Request(ctx).Payload; payload != nil {
+			CloseFiles(payload)
+		}
+
+		// Count response, net/http writes a 200 if the handler did not write anything
+		status := Response(ctx).Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		key := []string{"goa", "response", ctrl.Name, name, strconv.Itoa(status)}
+		RequestService(ctx).metrics().IncrCounter(key, 1.0)
+	}
+}
+
+// unmarshal calls the unmarshaler, it recovers panics like the Recover middleware does so that
+// they are handled by the controller error handler.
+func unmarshal(ctx context.Context, req *http.Request, unm Unmarshaler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = panicError(ctx, r)
+		}
+	}()
+	return unm(ctx, req)
+}
+
+// limitedReader wraps the reader returned by http.MaxBytesReader to record whether the request
+// body exceeded the limit, decoders do not necessarily return the read error as is.
+type limitedReader struct {
+	io.ReadCloser
+	limit    int64
+	n        int64
+	exceeded bool
+}
+
+// Read reads from the underlying reader and records whether the limit was reached.
+func (l *limitedReader) Read(b []byte) (int, error) {
+	n, err := l.ReadCloser.Read(b)
+	l.n += int64(n)
+	if err != nil && err != io.EOF && l.n >= l.limit {
+		l.exceeded = true
+	}
+	return n, err
+}
+
+// bodyLimit returns the maximum size of the given request body as set by MuxHandler, zero if the
+// body size is not limited.
+func bodyLimit(body io.Reader) int64 {
+	switch b := body.(type) {
+	case *limitedReader:
+		return b.limit
+	case *bufferedBody:
+		if l, ok := b.Closer.(*limitedReader); ok {
+			return l.limit
+		}
+	}
+	return 0
+}
+
+// decompressedReader limits the size of decompressed request bodies, the body size limit only
+// applies to the compressed bytes otherwise.
+type decompressedReader struct {
+	io.Reader
+	limit    int64
+	n        int64
+	exceeded bool
+}
+
+// Read reads from the underlying reader and fails once more than limit bytes were read.
+func (d *decompressedReader) Read(b []byte) (int, error) {
+	if d.exceeded {
+		return 0, bodyTooLargeError(d.limit)
+	}
+	n, err := d.Reader.Read(b)
+	d.n += int64(n)
+	if d.n > d.limit {
+		d.exceeded = true
+		return n, bodyTooLargeError(d.limit)
+	}
+	return n, err
+}
+
+// bufferedBody is a request body read through a buffer.
+type bufferedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyTooLargeError returns the error produced when a request body exceeds the given limit.
+func bodyTooLargeError(limit int64) error {
+	return &TypedError{
+		ID:   ErrRequestBodyTooLarge,
+		Mesg: fmt.Sprintf("request body exceeds the maximum size of %d bytes", limit),
 	}
 }
 
 // DefaultErrorHandler returns a 400 response for request validation errors (instances of
-// BadRequestError) and a 500 response for other errors. It writes the error message to the
-// response body in both cases.
+// BadRequestError), a 503 response for instances of ServiceUnavailableError and a 500 response
+// for other errors. It writes the error message to the response body except for 5xx responses
+// where the message is logged and the body only contains the status text so that internal
+// details - e.g. the value of a recovered panic - do not reach clients.
 func DefaultErrorHandler(ctx context.Context, rw http.ResponseWriter, req *http.Request, e error) {
-	status := 500
-	if _, ok := e.(*BadRequestError); ok {
-		status = 400
-	} else {
-		Error(ctx, e.Error())
+	status := errorStatus(e)
+	body := e.Error()
+	if status >= 500 {
+		Error(ctx, body)
+		body = http.StatusText(status)
 	}
-	Response(ctx).Send(ctx, status, e.Error())
+	Response(ctx).Send(ctx, status, body)
 }
 
 // TerseErrorHandler behaves like DefaultErrorHandler except that it does not write to the response
-// body for internal errors.
+// body for server errors, i.e. errors whose status is 500 or more.
 func TerseErrorHandler(ctx context.Context, rw http.ResponseWriter, req *http.Request, e error) {
-	status := 500
+	status := errorStatus(e)
 	var body interface{}
-	if _, ok := e.(*BadRequestError); ok {
-		status = 400
+	if status < 500 {
 		body = e.Error()
-	} else {
+	}
+	if status >= 500 {
 		Error(ctx, e.Error())
 	}
 	Response(ctx).Send(ctx, status, body)
 }
+
+// errorStatus returns the HTTP status code of the response corresponding to the given error.
+func errorStatus(e error) int {
+	switch actual := e.(type) {
+	case *HTTPError:
+		if actual.Status == 0 {
+			return 500
+		}
+		return actual.Status
+	case *TypedError:
+		return actual.ID.Status()
+	case *BadRequestError:
+		return 400
+	case *UnauthorizedError:
+		return 401
+	case *ForbiddenError:
+		return 403
+	case *ServiceUnavailableError:
+		return 503
+	default:
+		return 500
+	}
+}
@@ -4,13 +4,13 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 
 	"golang.org/x/net/context"
 
 	"github.com/goadesign/goa"
-	"github.com/goadesign/middleware"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -41,7 +41,7 @@ var _ = Describe("Application", func() {
 			var m goa.Middleware
 
 			BeforeEach(func() {
-				m = middleware.RequestID()
+				m = goa.RequestID()
 			})
 
 			JustBeforeEach(func() {
@@ -51,11 +51,58 @@ var _ = Describe("Application", func() {
 			It("adds the middleware", func() {
 				ctrl := s.NewController("test")
 				Ω(ctrl.Middleware).Should(HaveLen(1))
-				Ω(ctrl.Middleware[0]).Should(BeAssignableToTypeOf(middleware.RequestID()))
+				Ω(ctrl.Middleware[0]).Should(BeAssignableToTypeOf(goa.RequestID()))
 			})
 		})
 	})
 
+	Describe("Mux", func() {
+		var handled error
+		var rw *TestResponseWriter
+
+		BeforeEach(func() {
+			handled = nil
+			s.ErrorHandler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request, err error) {
+				handled = err
+				goa.DefaultErrorHandler(ctx, rw, req, err)
+			}
+			ctrl := s.NewController("test")
+			s.Mux.Handle("GET", "/foo", ctrl.MuxHandler("show", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				return nil
+			}, nil))
+			rw = &TestResponseWriter{ParentHeader: http.Header{}}
+		})
+
+		It("handles unknown paths with the error handler", func() {
+			req, err := http.NewRequest("GET", "/bar", nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			s.Mux.ServeHTTP(rw, req)
+			Ω(rw.Status).Should(Equal(404))
+			Ω(handled).Should(BeAssignableToTypeOf(&goa.TypedError{}))
+			Ω(handled.(*goa.TypedError).ID).Should(Equal(goa.ErrorID(goa.ErrNotFound)))
+		})
+
+		It("handles methods that are not allowed with the error handler", func() {
+			req, err := http.NewRequest("DELETE", "/foo", nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			s.Mux.ServeHTTP(rw, req)
+			Ω(rw.Status).Should(Equal(405))
+			Ω(rw.ParentHeader.Get("Allow")).Should(Equal("GET, HEAD, OPTIONS"))
+			Ω(handled).Should(BeAssignableToTypeOf(&goa.TypedError{}))
+			Ω(handled.(*goa.TypedError).ID).Should(Equal(goa.ErrorID(goa.ErrMethodNotAllowed)))
+			Ω(handled.(*goa.TypedError).Mesg).Should(ContainSubstring("GET, HEAD, OPTIONS"))
+		})
+
+		It("runs the service middleware on unmatched requests", func() {
+			s.Use(goa.RequestID())
+			req, err := http.NewRequest("GET", "/bar", nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			s.Mux.ServeHTTP(rw, req)
+			Ω(rw.Status).Should(Equal(404))
+			Ω(rw.ParentHeader.Get(goa.RequestIDHeader)).ShouldNot(BeEmpty())
+		})
+	})
+
 	Describe("MuxHandler", func() {
 		var handler goa.Handler
 		var unmarshaler goa.Unmarshaler
@@ -165,6 +212,20 @@ var _ = Describe("Application", func() {
 						Ω(errorHandlerCalled).Should(BeTrue())
 					})
 				})
+
+				Context("by panicking with the Recover middleware", func() {
+					BeforeEach(func() {
+						errorHandlerCalled = false
+						s.Use(goa.Recover())
+						handler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+							panic("boom")
+						}
+					})
+
+					It("triggers the error handler", func() {
+						Ω(errorHandlerCalled).Should(BeTrue())
+					})
+				})
 			})
 
 			Context("with different payload types", func() {
@@ -213,11 +274,157 @@ var _ = Describe("Application", func() {
 						Ω(goa.Request(ctx).Payload).Should(BeNil())
 					})
 				})
+
+				Context("with a chunked body", func() {
+					BeforeEach(func() {
+						r.ContentLength = -1
+					})
+
+					It("loads the payload", func() {
+						Ω(goa.Request(ctx).Payload).Should(Equal(decodedContent))
+					})
+				})
+
+				Context("with an empty chunked body", func() {
+					BeforeEach(func() {
+						r.Body = ioutil.NopCloser(bytes.NewReader(nil))
+						r.ContentLength = -1
+					})
+
+					It("does not call the unmarshaler", func() {
+						Ω(goa.Request(ctx).Payload).Should(BeNil())
+					})
+				})
+			})
+
+			Context("with a payload that has uploaded files", func() {
+				var payload *formPayload
+
+				BeforeEach(func() {
+					var buf bytes.Buffer
+					mw := multipart.NewWriter(&buf)
+					fw, _ := mw.CreateFormFile("avatar", "avatar.png")
+					fw.Write([]byte("avatar content"))
+					mw.Close()
+					s.Decoder(goa.NewMultipartDecoder, "multipart/form-data")
+					r.Header.Set("Content-Type", mw.FormDataContentType())
+					r.Body = ioutil.NopCloser(&buf)
+					r.ContentLength = int64(buf.Len())
+					unmarshaler = func(c context.Context, req *http.Request) error {
+						payload = &formPayload{}
+						if err := goa.RequestService(c).DecodeRequest(req, payload); err != nil {
+							return err
+						}
+						goa.Request(c).Payload = payload
+						return nil
+					}
+				})
+
+				It("closes the files once the handler returns", func() {
+					Ω(payload.Avatar).ShouldNot(BeNil())
+					content, err := ioutil.ReadAll(payload.Avatar)
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(content).Should(BeEmpty())
+				})
+			})
+
+			Context("with an unmarshaler that panics", func() {
+				var handlerErr error
+
+				BeforeEach(func() {
+					content := []byte(`{"hello": "world"}`)
+					s.ErrorHandler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request, err error) {
+						handlerErr = err
+					}
+					r.Body = ioutil.NopCloser(bytes.NewReader(content))
+					r.ContentLength = int64(len(content))
+					unmarshaler = func(c context.Context, req *http.Request) error {
+						panic("boom")
+					}
+				})
+
+				It("handles the panic with the error handler", func() {
+					Ω(handlerErr).Should(BeAssignableToTypeOf(&goa.TypedError{}))
+					Ω(handlerErr.(*goa.TypedError).ID).Should(Equal(goa.ErrorID(goa.ErrPanic)))
+				})
+			})
+
+			Context("with a body larger than the service maximum body size", func() {
+				var handlerErr error
+
+				BeforeEach(func() {
+					content := []byte(`{"hello": "world"}`)
+					s.MaxBodySize = 10
+					s.ErrorHandler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request, err error) {
+						handlerErr = err
+					}
+					r.Body = ioutil.NopCloser(bytes.NewReader(content))
+					r.ContentLength = int64(len(content))
+					unmarshaler = func(c context.Context, req *http.Request) error {
+						var payload interface{}
+						return goa.RequestService(c).DecodeRequest(req, &payload)
+					}
+				})
+
+				It("returns a request body too large error", func() {
+					Ω(handlerErr).Should(BeAssignableToTypeOf(&goa.TypedError{}))
+					Ω(handlerErr.(*goa.TypedError).ID).Should(Equal(goa.ErrorID(goa.ErrRequestBodyTooLarge)))
+				})
+
+				Context("sent with chunked encoding", func() {
+					BeforeEach(func() {
+						r.ContentLength = -1
+					})
+
+					It("returns a request body too large error", func() {
+						Ω(handlerErr).Should(BeAssignableToTypeOf(&goa.TypedError{}))
+						Ω(handlerErr.(*goa.TypedError).ID).Should(Equal(goa.ErrorID(goa.ErrRequestBodyTooLarge)))
+					})
+				})
 			})
 		})
 	})
 })
 
+var _ = Describe("TerseErrorHandler", func() {
+	var err error
+	var rw *TestResponseWriter
+
+	JustBeforeEach(func() {
+		s := goa.New("test")
+		s.Encoder(goa.NewJSONEncoder, "*/*")
+		s.ErrorHandler = goa.TerseErrorHandler
+		ctrl := s.NewController("test")
+		rw = &TestResponseWriter{ParentHeader: http.Header{}}
+		req, _ := http.NewRequest("GET", "/", nil)
+		ctrl.MuxHandler("show", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			return err
+		}, nil)(rw, req, url.Values{})
+	})
+
+	Context("with a client error", func() {
+		BeforeEach(func() {
+			err = goa.NewHTTPError(404, "not_found", "no bottle with id 1")
+		})
+
+		It("writes the error message", func() {
+			Ω(rw.Status).Should(Equal(404))
+			Ω(string(rw.Body)).Should(ContainSubstring("no bottle with id 1"))
+		})
+	})
+
+	Context("with a server error other than 500", func() {
+		BeforeEach(func() {
+			err = goa.NewServiceUnavailableError(&goa.TypedError{ID: goa.ErrRequestTimeout, Mesg: "internal details"})
+		})
+
+		It("does not write the error message", func() {
+			Ω(rw.Status).Should(Equal(503))
+			Ω(string(rw.Body)).ShouldNot(ContainSubstring("internal details"))
+		})
+	})
+})
+
 func TErrorHandler(witness *bool) goa.ErrorHandler {
 	return func(ctx context.Context, rw http.ResponseWriter, req *http.Request, err error) {
 		*witness = true
@@ -0,0 +1,190 @@
+package goa
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultCertCheckInterval is the default interval at which CertReloader checks whether the
+// certificate files changed.
+const DefaultCertCheckInterval = 10 * time.Second
+
+// CertReloader loads a certificate and key pair from disk and reloads it when the files change
+// so that certificates may be renewed without restarting the service. Use its GetCertificate
+// method as the tls.Config GetCertificate function, see NewTLSConfig.
+type CertReloader struct {
+	// CheckInterval is the minimum interval between two checks of the files modification
+	// times, DefaultCertCheckInterval if zero.
+	CheckInterval time.Duration
+
+	certFile, keyFile string
+	mu                sync.RWMutex
+	cert              *tls.Certificate
+	modTime           time.Time
+	checked           time.Time
+}
+
+// NewCertReloader loads the given certificate and key files and returns a CertReloader that
+// reloads them when they change.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the certificate and key files. The current certificate is kept if loading fails.
+func (r *CertReloader) Reload() error {
+	modTime, err := r.filesModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.checked = time.Now()
+	return nil
+}
+
+// GetCertificate returns the current certificate, reloading it first if the files changed since
+// the last load. It implements the tls.Config GetCertificate function.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	interval := r.CheckInterval
+	if interval == 0 {
+		interval = DefaultCertCheckInterval
+	}
+	r.mu.RLock()
+	cert, modTime, checked := r.cert, r.modTime, r.checked
+	r.mu.RUnlock()
+	if time.Since(checked) < interval {
+		return cert, nil
+	}
+	r.mu.Lock()
+	r.checked = time.Now()
+	r.mu.Unlock()
+	if mt, err := r.filesModTime(); err == nil && mt.After(modTime) {
+		if err := r.Reload(); err != nil {
+			// Keep serving the current certificate, the files may be partially written.
+			return cert, nil
+		}
+		r.mu.RLock()
+		cert = r.cert
+		r.mu.RUnlock()
+	}
+	return cert, nil
+}
+
+// filesModTime returns the most recent modification time of the certificate and key files.
+func (r *CertReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return latest, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// NewTLSConfig returns a TLS configuration that serves the given certificate and key files and
+// reloads them when they change, see CertReloader. The configuration enables HTTP/2 and requires
+// TLS 1.2 or later. Use RequireClientCert to enable client certificate authentication.
+func NewTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	reloader, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}, nil
+}
+
+// RequireClientCert configures config so that clients must present a certificate signed by one
+// of the certificate authorities in the given PEM bundle (mutual TLS). The verified certificate
+// is available to the handlers via RequestData.PeerCertificate.
+func RequireClientCert(config *tls.Config, caFile string) error {
+	pool, err := LoadCertPool(caFile)
+	if err != nil {
+		return err
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	return nil
+}
+
+// LoadCertPool loads the certificates contained in the given PEM file.
+func LoadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// ListenAndServeTLSConfig starts a HTTPS server using the given TLS configuration and sets up a
+// listener on the given address, see Listen for the supported address formats.
+func (service *Service) ListenAndServeTLSConfig(addr string, config *tls.Config) error {
+	l, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+	return service.ServeTLSConfig(l, config)
+}
+
+// ServeTLSConfig accepts HTTPS connections on the given listener using the given TLS
+// configuration.
+func (service *Service) ServeTLSConfig(l net.Listener, config *tls.Config) error {
+	service.Info("listen", "transport", "https", "addr", l.Addr().String())
+	return http.Serve(tls.NewListener(l, config), service.Mux)
+}
+
+// PeerCertificate returns the client certificate verified during the TLS handshake, nil if the
+// request was not made over TLS or if the client did not present a verified certificate.
+func (r *RequestData) PeerCertificate() *x509.Certificate {
+	if r.Request == nil || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// PeerIdentity returns the identity of the client that presented a verified certificate: the
+// certificate subject common name or if empty its first URI or DNS subject alternative name.
+// PeerIdentity returns the empty string if there is no verified client certificate.
+func (r *RequestData) PeerIdentity() string {
+	cert := r.PeerCertificate()
+	if cert == nil {
+		return ""
+	}
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return ""
+}
@@ -0,0 +1,193 @@
+package goa_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+// testCert is a certificate and its key.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert creates a certificate with the given common name signed by parent or self-signed
+// if parent is nil.
+func newTestCert(cn string, parent *testCert, isCA bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Ω(err).ShouldNot(HaveOccurred())
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	Ω(err).ShouldNot(HaveOccurred())
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	Ω(err).ShouldNot(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Ω(err).ShouldNot(HaveOccurred())
+	return &testCert{cert: cert, key: key}
+}
+
+// write writes the certificate and key PEM files in dir and returns their paths.
+func (c *testCert) write(dir, name string) (string, string) {
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	Ω(ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600)).Should(Succeed())
+	der, err := x509.MarshalECPrivateKey(c.key)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)).Should(Succeed())
+	return certFile, keyFile
+}
+
+var _ = Describe("TLS", func() {
+	var dir string
+	var ca *testCert
+	var caFile, serverCert, serverKey, clientCert, clientKey string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "goa")
+		Ω(err).ShouldNot(HaveOccurred())
+		ca = newTestCert("ca", nil, true)
+		caFile, _ = ca.write(dir, "ca")
+		serverCert, serverKey = newTestCert("server", ca, false).write(dir, "server")
+		clientCert, clientKey = newTestCert("alice", ca, false).write(dir, "client")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Describe("ServeTLSConfig with client certificates", func() {
+		var url string
+		var identity string
+
+		BeforeEach(func() {
+			identity = ""
+			service := goa.New("test")
+			ctrl := service.NewController("test")
+			service.Mux.Handle("GET", "/", ctrl.MuxHandler("show", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				identity = goa.Request(ctx).PeerIdentity()
+				rw.WriteHeader(204)
+				return nil
+			}, nil))
+			config, err := goa.NewTLSConfig(serverCert, serverKey)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(goa.RequireClientCert(config, caFile)).Should(Succeed())
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			Ω(err).ShouldNot(HaveOccurred())
+			url = "https://" + l.Addr().String() + "/"
+			go service.ServeTLSConfig(l, config)
+		})
+
+		It("exposes the verified client identity", func() {
+			client := goa.NewClient()
+			client.TLSCertFile = clientCert
+			client.TLSKeyFile = clientKey
+			client.TLSCAFile = caFile
+			req, err := http.NewRequest("GET", url, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			resp, err := client.Do(req)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp.StatusCode).Should(Equal(204))
+			Ω(identity).Should(Equal("alice"))
+		})
+
+		It("rejects clients without certificate", func() {
+			client := goa.NewClient()
+			client.TLSCAFile = caFile
+			req, err := http.NewRequest("GET", url, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			_, err = client.Do(req)
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	Describe("Client UseTLSConfig", func() {
+		It("keeps the settings of the client transport", func() {
+			client := goa.NewClient()
+			client.Client = &http.Client{Transport: &http.Transport{
+				DisableKeepAlives:   true,
+				MaxIdleConnsPerHost: 3,
+				TLSHandshakeTimeout: time.Second,
+			}}
+			config := &tls.Config{ServerName: "goa"}
+			client.UseTLSConfig(config)
+			transport, ok := client.Client.Transport.(*http.Transport)
+			Ω(ok).Should(BeTrue())
+			Ω(transport.TLSClientConfig).Should(Equal(config))
+			Ω(transport.DisableKeepAlives).Should(BeTrue())
+			Ω(transport.MaxIdleConnsPerHost).Should(Equal(3))
+			Ω(transport.TLSHandshakeTimeout).Should(Equal(time.Second))
+		})
+
+		It("keeps the settings of the default transport", func() {
+			client := goa.NewClient()
+			client.Client = &http.Client{}
+			config := &tls.Config{ServerName: "goa"}
+			client.UseTLSConfig(config)
+			transport, ok := client.Client.Transport.(*http.Transport)
+			Ω(ok).Should(BeTrue())
+			Ω(transport).ShouldNot(BeIdenticalTo(http.DefaultTransport))
+			Ω(transport.TLSClientConfig).Should(Equal(config))
+			def := http.DefaultTransport.(*http.Transport)
+			Ω(transport.DialContext).ShouldNot(BeNil())
+			Ω(transport.MaxIdleConns).Should(Equal(def.MaxIdleConns))
+			Ω(transport.IdleConnTimeout).Should(Equal(def.IdleConnTimeout))
+			Ω(transport.TLSHandshakeTimeout).Should(Equal(def.TLSHandshakeTimeout))
+			Ω(transport.ExpectContinueTimeout).Should(Equal(def.ExpectContinueTimeout))
+			Ω(def.TLSClientConfig).ShouldNot(Equal(config))
+		})
+	})
+
+	Describe("CertReloader", func() {
+		It("reloads the certificate when the files change", func() {
+			reloader, err := goa.NewCertReloader(serverCert, serverKey)
+			Ω(err).ShouldNot(HaveOccurred())
+			reloader.CheckInterval = time.Nanosecond
+			cert, err := reloader.GetCertificate(&tls.ClientHelloInfo{})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			renewed := newTestCert("renewed", ca, false)
+			renewed.write(dir, "server")
+			later := time.Now().Add(time.Minute)
+			Ω(os.Chtimes(serverCert, later, later)).Should(Succeed())
+
+			cert2, err := reloader.GetCertificate(&tls.ClientHelloInfo{})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cert2).ShouldNot(Equal(cert))
+			leaf, err := x509.ParseCertificate(cert2.Certificate[0])
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(leaf.Subject.CommonName).Should(Equal("renewed"))
+		})
+	})
+})
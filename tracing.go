@@ -0,0 +1,263 @@
+package goa
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Trace context headers.
+const (
+	// TraceParentHeader is the name of the W3C trace context header that identifies the parent
+	// span.
+	TraceParentHeader = "traceparent"
+	// TraceStateHeader is the name of the W3C trace context header that carries vendor specific
+	// trace data.
+	TraceStateHeader = "tracestate"
+	// B3Header is the name of the single B3 propagation header.
+	B3Header = "b3"
+	// B3TraceIDHeader is the name of the B3 propagation header that carries the trace ID.
+	B3TraceIDHeader = "X-B3-TraceId"
+	// B3SpanIDHeader is the name of the B3 propagation header that carries the span ID.
+	B3SpanIDHeader = "X-B3-SpanId"
+	// B3SampledHeader is the name of the B3 propagation header that carries the sampling
+	// decision.
+	B3SampledHeader = "X-B3-Sampled"
+)
+
+type (
+	// Span records the handling of a request by an action. Spans that belong to the same trace
+	// share the same trace ID.
+	Span struct {
+		// TraceID is the 32 hex characters ID of the trace.
+		TraceID string
+		// SpanID is the 16 hex characters ID of the span.
+		SpanID string
+		// ParentID is the ID of the parent span, empty for root spans.
+		ParentID string
+		// Name is the span name, "<resource>.<action>" for spans started by Trace.
+		Name string
+		// Sampled is true if the span is exported.
+		Sampled bool
+		// TraceState is the value of the W3C tracestate header received with the request.
+		TraceState string
+		// Start is the time the span started.
+		Start time.Time
+		// End is the time the span ended.
+		End time.Time
+		// Status is the response status code.
+		Status int
+		// Error is the message of the error returned by the middleware mounted after Trace if
+		// any. Errors returned by the action handlers are handled before and only show up in
+		// Status.
+		Error string
+	}
+
+	// TraceExporter is the interface implemented by the span exporters used by the Trace
+	// middleware.
+	TraceExporter interface {
+		// Export is called once the span ends. Implementations should not block.
+		Export(span *Span)
+	}
+
+	// MemoryTraceExporter is a TraceExporter that keeps the exported spans in memory. It is
+	// intended for tests.
+	MemoryTraceExporter struct {
+		sync.Mutex
+		spans []*Span
+	}
+)
+
+// Trace returns a middleware that starts a span named "<resource>.<action>" for each request.
+// The span is a child of the span identified by the W3C traceparent header or by the B3 headers
+// of the request if any, it starts a new trace otherwise. The span is stored in the request
+// context, retrieve it with ContextSpan. The trace and span IDs are also added to the log context
+// via LogWith. Client.Do propagates the span of the outgoing request context so that requests made
+// with req.WithContext(ctx) while handling a request continue the trace.
+//
+// The span is given to the exporter once the request has been handled if it is sampled. exporter
+// may be nil in which case the middleware only propagates the trace context.
+func Trace(exporter TraceExporter) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			span := &Span{
+				SpanID:  newTraceID(8),
+				Name:    ContextController(ctx) + "." + ContextAction(ctx),
+				Sampled: true,
+				Start:   time.Now(),
+			}
+			if parent := ExtractSpan(req.Header); parent != nil {
+				span.TraceID = parent.TraceID
+				span.ParentID = parent.SpanID
+				span.Sampled = parent.Sampled
+				span.TraceState = parent.TraceState
+			} else {
+				span.TraceID = newTraceID(16)
+			}
+			ctx = WithSpan(ctx, span)
+			ctx = LogWith(ctx, "trace", span.TraceID, "span", span.SpanID)
+			err := h(ctx, rw, req)
+			span.End = time.Now()
+			if err != nil {
+				span.Status = errorStatus(err)
+				span.Error = err.Error()
+			} else if resp := Response(ctx); resp != nil {
+				span.Status = resp.Status
+			}
+			if exporter != nil && span.Sampled {
+				exporter.Export(span)
+			}
+			return err
+		}
+	}
+}
+
+// ContextSpan extracts the span started by the Trace middleware from the context. It returns nil
+// if there is no span in the context.
+func ContextSpan(ctx context.Context) *Span {
+	if s := ctx.Value(spanKey); s != nil {
+		return s.(*Span)
+	}
+	return nil
+}
+
+// WithSpan returns a copy of ctx that contains the given span. Use it to propagate traces from
+// code that does not handle requests, e.g. background jobs.
+func WithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey, span)
+}
+
+// ExtractSpan returns the span identified by the W3C traceparent header or by the B3 headers,
+// nil if there is none or if the headers are invalid. Only the TraceID, SpanID, Sampled and
+// TraceState fields of the returned span are set.
+func ExtractSpan(header http.Header) *Span {
+	if tp := header.Get(TraceParentHeader); tp != "" {
+		// version "-" trace-id "-" parent-id "-" trace-flags
+		parts := strings.Split(strings.TrimSpace(tp), "-")
+		if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[3]) != 2 {
+			return nil
+		}
+		if parts[0] == "00" && len(parts) != 4 {
+			return nil
+		}
+		if !validTraceID(parts[1], 32) || !validTraceID(parts[2], 16) {
+			return nil
+		}
+		flags, err := hex.DecodeString(parts[3])
+		if err != nil {
+			return nil
+		}
+		return &Span{
+			TraceID:    parts[1],
+			SpanID:     parts[2],
+			Sampled:    flags[0]&1 == 1,
+			TraceState: header.Get(TraceStateHeader),
+		}
+	}
+	var traceID, spanID, sampled string
+	if b3 := header.Get(B3Header); b3 != "" {
+		// traceid "-" spanid ["-" sampled ["-" parentspanid]]
+		parts := strings.Split(strings.TrimSpace(b3), "-")
+		if len(parts) < 2 {
+			return nil
+		}
+		traceID, spanID = parts[0], parts[1]
+		if len(parts) > 2 {
+			sampled = parts[2]
+		}
+	} else {
+		traceID = header.Get(B3TraceIDHeader)
+		spanID = header.Get(B3SpanIDHeader)
+		sampled = header.Get(B3SampledHeader)
+	}
+	if traceID == "" {
+		return nil
+	}
+	if len(traceID) == 16 {
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+	traceID, spanID = strings.ToLower(traceID), strings.ToLower(spanID)
+	if !validTraceID(traceID, 32) || !validTraceID(spanID, 16) {
+		return nil
+	}
+	return &Span{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: sampled != "0" && sampled != "false",
+	}
+}
+
+// Inject sets the W3C trace context and B3 headers that identify the span in the given header.
+func (s *Span) Inject(header http.Header) {
+	flags, sampled := "00", "0"
+	if s.Sampled {
+		flags, sampled = "01", "1"
+	}
+	header.Set(TraceParentHeader, fmt.Sprintf("00-%s-%s-%s", s.TraceID, s.SpanID, flags))
+	if s.TraceState != "" {
+		header.Set(TraceStateHeader, s.TraceState)
+	}
+	header.Set(B3Header, fmt.Sprintf("%s-%s-%s", s.TraceID, s.SpanID, sampled))
+}
+
+// Duration returns the span duration, zero if the span has not ended.
+func (s *Span) Duration() time.Duration {
+	if s.End.IsZero() {
+		return 0
+	}
+	return s.End.Sub(s.Start)
+}
+
+// NewMemoryTraceExporter returns an empty MemoryTraceExporter.
+func NewMemoryTraceExporter() *MemoryTraceExporter {
+	return &MemoryTraceExporter{}
+}
+
+// Export implements TraceExporter.
+func (e *MemoryTraceExporter) Export(span *Span) {
+	e.Lock()
+	defer e.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns the spans exported so far in the order they ended.
+func (e *MemoryTraceExporter) Spans() []*Span {
+	e.Lock()
+	defer e.Unlock()
+	spans := make([]*Span, len(e.spans))
+	copy(spans, e.spans)
+	return spans
+}
+
+// Reset removes all the exported spans.
+func (e *MemoryTraceExporter) Reset() {
+	e.Lock()
+	defer e.Unlock()
+	e.spans = nil
+}
+
+// newTraceID returns a random ID of n bytes encoded in hex.
+func newTraceID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validTraceID returns true if id is made of n lowercase hex characters and is not all zeros.
+func validTraceID(id string, n int) bool {
+	if len(id) != n || id == strings.Repeat("0", n) {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
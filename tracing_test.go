@@ -0,0 +1,149 @@
+package goa_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("Trace", func() {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentID = "00f067aa0ba902b7"
+
+	var exporter *goa.MemoryTraceExporter
+	var header http.Header
+	var handler goa.Handler
+	var span *goa.Span
+	var logs bytes.Buffer
+
+	BeforeEach(func() {
+		exporter = goa.NewMemoryTraceExporter()
+		header = make(http.Header)
+		span = nil
+		logs.Reset()
+		handler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			span = goa.ContextSpan(ctx)
+			goa.Info(ctx, "handling")
+			rw.WriteHeader(204)
+			return nil
+		}
+	})
+
+	JustBeforeEach(func() {
+		service := goa.New("test")
+		service.UseLogger(goa.NewJSONLogger(&logs))
+		service.Use(goa.Trace(exporter))
+		ctrl := service.NewController("bottle")
+		req, err := http.NewRequest("GET", "/bottles/1", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.Header = header
+		ctrl.MuxHandler("show", handler, nil)(httptest.NewRecorder(), req, nil)
+	})
+
+	It("starts a new trace", func() {
+		Ω(span).ShouldNot(BeNil())
+		Ω(span.Name).Should(Equal("bottle.show"))
+		Ω(span.TraceID).Should(MatchRegexp("^[0-9a-f]{32}$"))
+		Ω(span.SpanID).Should(MatchRegexp("^[0-9a-f]{16}$"))
+		Ω(span.ParentID).Should(BeEmpty())
+	})
+
+	It("exports the span", func() {
+		Ω(exporter.Spans()).Should(HaveLen(1))
+		exported := exporter.Spans()[0]
+		Ω(exported).Should(Equal(span))
+		Ω(exported.Status).Should(Equal(204))
+		Ω(exported.End).ShouldNot(BeZero())
+	})
+
+	It("adds the trace and span IDs to the log context", func() {
+		Ω(logs.String()).Should(ContainSubstring(`"trace":"` + span.TraceID + `"`))
+		Ω(logs.String()).Should(ContainSubstring(`"span":"` + span.SpanID + `"`))
+	})
+
+	Context("with a W3C traceparent header", func() {
+		BeforeEach(func() {
+			header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+			header.Set("tracestate", "congo=t61rcWkgMzE")
+		})
+
+		It("continues the trace", func() {
+			Ω(span.TraceID).Should(Equal(traceID))
+			Ω(span.ParentID).Should(Equal(parentID))
+			Ω(span.SpanID).ShouldNot(Equal(parentID))
+			Ω(span.TraceState).Should(Equal("congo=t61rcWkgMzE"))
+			Ω(span.Sampled).Should(BeTrue())
+		})
+
+		Context("that is not sampled", func() {
+			BeforeEach(func() {
+				header.Set("traceparent", "00-"+traceID+"-"+parentID+"-00")
+			})
+
+			It("does not export the span", func() {
+				Ω(span).ShouldNot(BeNil())
+				Ω(exporter.Spans()).Should(BeEmpty())
+			})
+		})
+	})
+
+	Context("with B3 headers", func() {
+		BeforeEach(func() {
+			header.Set("X-B3-TraceId", "a3ce929d0e0e4736")
+			header.Set("X-B3-SpanId", parentID)
+			header.Set("X-B3-Sampled", "1")
+		})
+
+		It("continues the trace", func() {
+			Ω(span.TraceID).Should(Equal("0000000000000000a3ce929d0e0e4736"))
+			Ω(span.ParentID).Should(Equal(parentID))
+		})
+	})
+
+	Context("with an invalid traceparent header", func() {
+		BeforeEach(func() {
+			header.Set("traceparent", "00-"+traceID+"-0000000000000000-01")
+		})
+
+		It("starts a new trace", func() {
+			Ω(span.TraceID).ShouldNot(Equal(traceID))
+			Ω(span.ParentID).Should(BeEmpty())
+		})
+	})
+
+	Context("with a handler that returns an error", func() {
+		BeforeEach(func() {
+			handler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				return goa.NewHTTPError(404, "not_found", "bottle not found")
+			}
+		})
+
+		It("records the response status", func() {
+			Ω(exporter.Spans()).Should(HaveLen(1))
+			Ω(exporter.Spans()[0].Status).Should(Equal(404))
+		})
+	})
+})
+
+var _ = Describe("Client", func() {
+	It("propagates the span of the request context", func() {
+		var received http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			received = req.Header
+		}))
+		defer server.Close()
+		span := &goa.Span{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}
+		ctx := goa.WithSpan(context.Background(), span)
+		req, err := http.NewRequest("GET", server.URL, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = goa.NewClient().Do(req.WithContext(ctx))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(received.Get("traceparent")).Should(Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+		Ω(goa.ExtractSpan(received).SpanID).Should(Equal(span.SpanID))
+	})
+})
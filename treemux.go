@@ -0,0 +1,268 @@
+package goa
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type (
+	// treeMux is a ServeMux built on a tree router that supports overlapping routes. Paths are
+	// split into segments, each segment of a route path may be:
+	//
+	// * a static segment such as "users".
+	//
+	// * a parameter such as ":id" that matches any segment.
+	//
+	// * a parameter constrained by a regular expression such as ":id([0-9]+)" that only matches
+	// segments that match the whole expression.
+	//
+	// * a catch-all parameter such as "*filepath" that matches the rest of the path, it must be
+	// the last segment.
+	//
+	// When multiple routes match a request path static segments have priority over constrained
+	// parameters which have priority over unconstrained parameters which have priority over
+	// catch-all parameters. Parameters at the same position may have different names so that
+	// for example "/users/me", "/users/:id([0-9]+)" and "/users/:name" may all be registered.
+	//
+	// treeMux handles HEAD, OPTIONS, method not allowed and not found requests like the default
+	// mux.
+	treeMux struct {
+		root             *treeNode
+		handles          map[string]MuxHandler
+		routes           []*Route
+		notFound         MuxHandler
+		methodNotAllowed MuxHandler
+	}
+
+	// treeNode is a node of the treeMux routing tree, it corresponds to a path segment.
+	treeNode struct {
+		// segment is the route path segment, e.g. "users" or ":id([0-9]+)".
+		segment string
+		// name is the parameter name for parameter nodes.
+		name string
+		// constraint is the parameter regular expression for constrained parameter nodes.
+		constraint *regexp.Regexp
+		// static lists the child nodes of static segments indexed by segment.
+		static map[string]*treeNode
+		// params lists the child nodes of parameter segments in priority order.
+		params []*treeNode
+		// catchAll is the child node of the catch-all segment if any.
+		catchAll *treeNode
+		// handles lists the handlers of the routes that end at this node indexed by method.
+		handles map[string]MuxHandler
+	}
+)
+
+// NewTreeMux returns a ServeMux that supports routes that overlap, e.g. "/users/me" and
+// "/users/:id", and parameters constrained by regular expressions, e.g. "/users/:id([0-9]+)".
+// Use Service.UseMux to make a service use it.
+func NewTreeMux() ServeMux {
+	return &treeMux{
+		root:    &treeNode{},
+		handles: make(map[string]MuxHandler),
+	}
+}
+
+// Handle sets the handler for the given verb and path.
+func (m *treeMux) Handle(method, path string, handle MuxHandler) {
+	m.HandleRoute(&Route{Method: method, Path: path}, handle)
+}
+
+// HandleRoute sets the handler for the route verb and path and records the route. It panics if
+// the path is invalid or if a handler is already registered for the same method and path.
+func (m *treeMux) HandleRoute(route *Route, handle MuxHandler) {
+	if !strings.HasPrefix(route.Path, "/") {
+		panic(fmt.Sprintf("goa: path %#v must start with /", route.Path))
+	}
+	n := m.root
+	segments := splitPath(route.Path)
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, "*") && i != len(segments)-1 {
+			panic(fmt.Sprintf("goa: catch-all parameter must be the last segment of path %#v", route.Path))
+		}
+		n = n.child(seg)
+	}
+	if n.handles == nil {
+		n.handles = make(map[string]MuxHandler)
+	}
+	if _, ok := n.handles[route.Method]; ok {
+		panic(fmt.Sprintf("goa: a handler is already registered for %s %s", route.Method, route.Path))
+	}
+	n.handles[route.Method] = handle
+	m.handles[route.Method+route.Path] = handle
+	m.routes = append(m.routes, route)
+}
+
+// HandleNotFound sets the handler invoked for requests that do not match any registered handler.
+func (m *treeMux) HandleNotFound(handle MuxHandler) {
+	m.notFound = handle
+}
+
+// HandleMethodNotAllowed sets the handler invoked for requests whose path only matches handlers
+// registered for other methods.
+func (m *treeMux) HandleMethodNotAllowed(handle MuxHandler) {
+	m.methodNotAllowed = handle
+}
+
+// Lookup returns the MuxHandler associated with the given method and path.
+func (m *treeMux) Lookup(method, path string) MuxHandler {
+	return m.handles[method+path]
+}
+
+// Routes returns the registered routes.
+func (m *treeMux) Routes() []*Route {
+	routes := make([]*Route, len(m.routes))
+	copy(routes, m.routes)
+	return routes
+}
+
+// ServeHTTP is the function called back by the underlying HTTP server to handle incoming requests.
+func (m *treeMux) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	segments := splitPath(req.URL.Path)
+	if h, params := m.root.lookup(req.Method, segments); h != nil {
+		h(rw, req, m.values(req, params))
+		return
+	}
+	switch req.Method {
+	case "HEAD":
+		if h, params := m.root.lookup("GET", segments); h != nil {
+			h(&headResponseWriter{rw}, req, m.values(req, params))
+			return
+		}
+	case "OPTIONS":
+		if allowed := m.allowed(segments); len(allowed) > 0 {
+			serveOptions(rw, allowed)
+			return
+		}
+	}
+	serveUnmatched(rw, req, m.allowed(segments), m.notFound, m.methodNotAllowed)
+}
+
+// values returns the request querystring values merged with the path parameter values.
+func (m *treeMux) values(req *http.Request, params [][2]string) url.Values {
+	values := req.URL.Query()
+	for _, p := range params {
+		values.Set(p[0], p[1])
+	}
+	return values
+}
+
+// allowed returns the sorted list of methods that have a handler for the given path.
+func (m *treeMux) allowed(segments []string) []string {
+	set := make(map[string]bool)
+	m.root.match(segments, nil, func(n *treeNode, _ [][2]string) bool {
+		for method := range n.handles {
+			set[method] = true
+		}
+		return false
+	})
+	return allowedMethods(set)
+}
+
+// child returns the child node for the given route path segment, creating it if needed.
+func (n *treeNode) child(seg string) *treeNode {
+	switch {
+	case strings.HasPrefix(seg, "*"):
+		if n.catchAll == nil {
+			n.catchAll = &treeNode{segment: seg, name: seg[1:]}
+		} else if n.catchAll.segment != seg {
+			panic(fmt.Sprintf("goa: catch-all parameter %#v conflicts with %#v", seg, n.catchAll.segment))
+		}
+		return n.catchAll
+	case strings.HasPrefix(seg, ":"):
+		for _, p := range n.params {
+			if p.segment == seg {
+				return p
+			}
+		}
+		c := &treeNode{segment: seg, name: seg[1:]}
+		if i := strings.Index(seg, "("); i > 0 {
+			if !strings.HasSuffix(seg, ")") {
+				panic(fmt.Sprintf("goa: invalid parameter constraint in %#v", seg))
+			}
+			c.name = seg[1:i]
+			re, err := regexp.Compile("^(?:" + seg[i+1:len(seg)-1] + ")$")
+			if err != nil {
+				panic(fmt.Sprintf("goa: invalid parameter constraint in %#v: %s", seg, err))
+			}
+			c.constraint = re
+		}
+		if c.name == "" {
+			panic(fmt.Sprintf("goa: missing parameter name in %#v", seg))
+		}
+		// Constrained parameters are tried first, in registration order.
+		i := len(n.params)
+		if c.constraint != nil {
+			for i = 0; i < len(n.params) && n.params[i].constraint != nil; i++ {
+			}
+		}
+		n.params = append(n.params, nil)
+		copy(n.params[i+1:], n.params[i:])
+		n.params[i] = c
+		return c
+	default:
+		if n.static == nil {
+			n.static = make(map[string]*treeNode)
+		}
+		c, ok := n.static[seg]
+		if !ok {
+			c = &treeNode{segment: seg}
+			n.static[seg] = c
+		}
+		return c
+	}
+}
+
+// lookup returns the handler for the given method and path segments and the path parameter
+// values, nil if there is none.
+func (n *treeNode) lookup(method string, segments []string) (MuxHandler, [][2]string) {
+	var handle MuxHandler
+	var values [][2]string
+	n.match(segments, nil, func(n *treeNode, params [][2]string) bool {
+		if h, ok := n.handles[method]; ok {
+			handle, values = h, params
+			return true
+		}
+		return false
+	})
+	return handle, values
+}
+
+// match calls fn with each node that matches the given path segments in priority order until fn
+// returns true. params contains the parameter values collected so far. match returns true if fn
+// did.
+func (n *treeNode) match(segments []string, params [][2]string, fn func(*treeNode, [][2]string) bool) bool {
+	if len(segments) == 0 {
+		return len(n.handles) > 0 && fn(n, params)
+	}
+	seg := segments[0]
+	if c, ok := n.static[seg]; ok {
+		if c.match(segments[1:], params, fn) {
+			return true
+		}
+	}
+	if seg != "" {
+		for _, c := range n.params {
+			if c.constraint != nil && !c.constraint.MatchString(seg) {
+				continue
+			}
+			p := append(params[:len(params):len(params)], [2]string{c.name, seg})
+			if c.match(segments[1:], p, fn) {
+				return true
+			}
+		}
+	}
+	if c := n.catchAll; c != nil && len(c.handles) > 0 {
+		p := append(params[:len(params):len(params)], [2]string{c.name, "/" + strings.Join(segments, "/")})
+		return fn(c, p)
+	}
+	return false
+}
+
+// splitPath returns the segments of the given path.
+func splitPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
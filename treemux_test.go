@@ -0,0 +1,139 @@
+package goa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TreeMux", func() {
+	var mux goa.ServeMux
+	var matched string
+	var params url.Values
+
+	handle := func(name string) goa.MuxHandler {
+		return func(rw http.ResponseWriter, req *http.Request, vals url.Values) {
+			matched = name
+			params = vals
+			rw.WriteHeader(200)
+			rw.Write([]byte(name))
+		}
+	}
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		matched, params = "", nil
+		req, err := http.NewRequest(method, path, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rw := httptest.NewRecorder()
+		mux.ServeHTTP(rw, req)
+		return rw
+	}
+
+	BeforeEach(func() {
+		mux = goa.NewTreeMux()
+		mux.Handle("GET", "/", handle("root"))
+		mux.Handle("GET", "/users/:name", handle("name"))
+		mux.Handle("GET", "/users/me", handle("me"))
+		mux.Handle("GET", "/users/:id([0-9]+)", handle("id"))
+		mux.Handle("DELETE", "/users/:userID/posts/:postID", handle("post"))
+		mux.Handle("GET", "/assets/*filepath", handle("assets"))
+	})
+
+	It("gives priority to static segments", func() {
+		serve("GET", "/users/me")
+		Ω(matched).Should(Equal("me"))
+	})
+
+	It("gives priority to constrained parameters", func() {
+		serve("GET", "/users/42?sort=asc")
+		Ω(matched).Should(Equal("id"))
+		Ω(params.Get("id")).Should(Equal("42"))
+		Ω(params.Get("sort")).Should(Equal("asc"))
+	})
+
+	It("matches unconstrained parameters", func() {
+		serve("GET", "/users/joe")
+		Ω(matched).Should(Equal("name"))
+		Ω(params.Get("name")).Should(Equal("joe"))
+	})
+
+	It("supports different parameter names at the same position", func() {
+		serve("DELETE", "/users/joe/posts/1")
+		Ω(matched).Should(Equal("post"))
+		Ω(params.Get("userID")).Should(Equal("joe"))
+		Ω(params.Get("postID")).Should(Equal("1"))
+	})
+
+	It("matches catch-all parameters", func() {
+		serve("GET", "/assets/css/main.css")
+		Ω(matched).Should(Equal("assets"))
+		Ω(params.Get("filepath")).Should(Equal("/css/main.css"))
+	})
+
+	It("matches the root path", func() {
+		serve("GET", "/")
+		Ω(matched).Should(Equal("root"))
+	})
+
+	It("serves HEAD requests with GET handlers", func() {
+		rw := serve("HEAD", "/users/me")
+		Ω(matched).Should(Equal("me"))
+		Ω(rw.Code).Should(Equal(200))
+		Ω(rw.Body.Len()).Should(Equal(0))
+	})
+
+	It("responds to OPTIONS requests", func() {
+		rw := serve("OPTIONS", "/users/joe/posts/1")
+		Ω(rw.Code).Should(Equal(200))
+		Ω(rw.Header().Get("Allow")).Should(Equal("DELETE, OPTIONS"))
+	})
+
+	It("responds with 405 to requests with a method that is not allowed", func() {
+		rw := serve("POST", "/users/me")
+		Ω(matched).Should(BeEmpty())
+		Ω(rw.Code).Should(Equal(405))
+		Ω(rw.Header().Get("Allow")).Should(Equal("GET, HEAD, OPTIONS"))
+	})
+
+	It("responds with 404 to requests that do not match", func() {
+		rw := serve("GET", "/bottles")
+		Ω(matched).Should(BeEmpty())
+		Ω(rw.Code).Should(Equal(404))
+	})
+
+	It("panics when registering the same route twice", func() {
+		Ω(func() { mux.Handle("GET", "/users/me", handle("me")) }).Should(Panic())
+	})
+
+	It("panics with invalid constraints", func() {
+		Ω(func() { mux.Handle("GET", "/bottles/:id([0-9]+", handle("id")) }).Should(Panic())
+		Ω(func() { mux.Handle("GET", "/bottles/:id([)", handle("id")) }).Should(Panic())
+	})
+
+	Context("used by a service", func() {
+		var service *goa.Service
+
+		BeforeEach(func() {
+			service = goa.New("test")
+			service.Mux.Handle("GET", "/health", handle("health"))
+			service.UseMux(mux)
+		})
+
+		It("keeps the routes registered with the previous mux", func() {
+			Ω(service.Mux).Should(Equal(mux))
+			serve("GET", "/health")
+			Ω(matched).Should(Equal("health"))
+			Ω(service.Routes()).Should(HaveLen(7))
+		})
+
+		It("handles not found requests with the service error handler", func() {
+			rw := serve("GET", "/bottles")
+			Ω(rw.Code).Should(Equal(404))
+			Ω(rw.Header().Get("Content-Type")).ShouldNot(HavePrefix("text/plain"))
+		})
+	})
+})
@@ -0,0 +1,270 @@
+package goa
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// VersionHeader is the name of the request header that contains the targeted API version by
+// default, see HeaderSelectVersionFunc.
+const VersionHeader = "X-Api-Version"
+
+type (
+	// SelectVersionFunc computes the API version targeted by a request. It returns the empty
+	// string if the request does not target a specific version.
+	SelectVersionFunc func(*http.Request) string
+
+	// VersionMux is a ServeMux that dispatches requests to a different ServeMux for each API
+	// version so that a service may serve multiple versions of the same resources. The version
+	// targeted by a request is computed by the SelectVersion function, see for example
+	// HeaderSelectVersionFunc, MediaTypeSelectVersionFunc and PathSelectVersionFunc.
+	//
+	// Routes that do not belong to a version (i.e. whose Version field is empty) are registered
+	// with a mux shared by all versions: requests that do not match a route of the targeted
+	// version - or that target an unknown version - are handled by the shared mux. This makes it
+	// possible to mount controllers that do not depend on the version such as the health or
+	// metrics controllers once.
+	VersionMux struct {
+		// SelectVersion computes the version targeted by a request.
+		SelectVersion SelectVersionFunc
+		// DefaultVersion is the version used for requests for which SelectVersion returns
+		// the empty string if any.
+		DefaultVersion string
+		// NewMux creates the mux of each version and the shared mux, NewMux if nil.
+		NewMux func() ServeMux
+
+		shared           ServeMux
+		versions         map[string]*versionedMux
+		notFound         MuxHandler
+		methodNotAllowed MuxHandler
+	}
+
+	// versionedMux is the mux of a single API version.
+	versionedMux struct {
+		ServeMux
+		version string
+	}
+)
+
+// NewVersionMux returns a VersionMux that uses the given function to compute the version
+// targeted by requests. Requests that do not target a version are handled by the shared mux
+// unless DefaultVersion is set.
+func NewVersionMux(selectVersion SelectVersionFunc) *VersionMux {
+	return &VersionMux{
+		SelectVersion: selectVersion,
+		versions:      make(map[string]*versionedMux),
+	}
+}
+
+// Version returns the mux of the given API version, creating it if needed. Routes registered
+// with the returned mux have their Version field set. Version returns the shared mux if version
+// is empty.
+func (m *VersionMux) Version(version string) ServeMux {
+	if version == "" {
+		return m.sharedMux()
+	}
+	if v, ok := m.versions[version]; ok {
+		return v
+	}
+	v := &versionedMux{ServeMux: m.newMux(), version: version}
+	m.versions[version] = v
+	m.setHandlers(v)
+	return v
+}
+
+// Versions returns the sorted list of versions that have a mux.
+func (m *VersionMux) Versions() []string {
+	versions := make([]string, 0, len(m.versions))
+	for v := range m.versions {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Handle sets the handler for the given verb and path in the shared mux.
+func (m *VersionMux) Handle(method, path string, handle MuxHandler) {
+	m.sharedMux().Handle(method, path, handle)
+}
+
+// HandleRoute sets the handler for the route in the mux of the route version.
+func (m *VersionMux) HandleRoute(route *Route, handle MuxHandler) {
+	m.Version(route.Version).HandleRoute(route, handle)
+}
+
+// HandleNotFound sets the handler invoked for requests that do not match any registered handler.
+func (m *VersionMux) HandleNotFound(handle MuxHandler) {
+	m.notFound = handle
+	m.sharedMux().HandleNotFound(handle)
+}
+
+// HandleMethodNotAllowed sets the handler invoked for requests whose path only matches handlers
+// registered for other methods.
+func (m *VersionMux) HandleMethodNotAllowed(handle MuxHandler) {
+	m.methodNotAllowed = handle
+	m.sharedMux().HandleMethodNotAllowed(handle)
+	for _, v := range m.versions {
+		m.setHandlers(v)
+	}
+}
+
+// Lookup returns the MuxHandler associated with the given method and path in the shared mux. Use
+// Version(version).Lookup to lookup the handlers of a version.
+func (m *VersionMux) Lookup(method, path string) MuxHandler {
+	return m.sharedMux().Lookup(method, path)
+}
+
+// Routes returns the routes of the shared mux followed by the routes of each version sorted by
+// version.
+func (m *VersionMux) Routes() []*Route {
+	routes := m.sharedMux().Routes()
+	for _, v := range m.Versions() {
+		routes = append(routes, m.versions[v].Routes()...)
+	}
+	return routes
+}
+
+// ServeHTTP dispatches the request to the mux of the version it targets.
+func (m *VersionMux) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	var version string
+	if m.SelectVersion != nil {
+		version = m.SelectVersion(req)
+	}
+	if version == "" {
+		version = m.DefaultVersion
+	}
+	if v, ok := m.versions[version]; ok {
+		v.ServeHTTP(rw, req)
+		return
+	}
+	m.sharedMux().ServeHTTP(rw, req)
+}
+
+// sharedMux returns the mux that holds the routes that do not belong to a version.
+func (m *VersionMux) sharedMux() ServeMux {
+	if m.shared == nil {
+		m.shared = m.newMux()
+		if m.notFound != nil {
+			m.shared.HandleNotFound(m.notFound)
+		}
+		if m.methodNotAllowed != nil {
+			m.shared.HandleMethodNotAllowed(m.methodNotAllowed)
+		}
+	}
+	return m.shared
+}
+
+// newMux creates a mux using NewMux.
+func (m *VersionMux) newMux() ServeMux {
+	if m.NewMux != nil {
+		return m.NewMux()
+	}
+	return NewMux()
+}
+
+// setHandlers sets the not found and method not allowed handlers of the given version mux:
+// requests that do not match a version route are handled by the shared mux.
+func (m *VersionMux) setHandlers(v *versionedMux) {
+	v.ServeMux.HandleNotFound(func(rw http.ResponseWriter, req *http.Request, _ url.Values) {
+		m.sharedMux().ServeHTTP(rw, req)
+	})
+	if m.methodNotAllowed != nil {
+		v.ServeMux.HandleMethodNotAllowed(m.methodNotAllowed)
+	}
+}
+
+// Handle sets the handler for the given verb and path and records the route version.
+func (v *versionedMux) Handle(method, path string, handle MuxHandler) {
+	v.HandleRoute(&Route{Method: method, Path: path}, handle)
+}
+
+// HandleRoute sets the handler for the route and records the route version.
+func (v *versionedMux) HandleRoute(route *Route, handle MuxHandler) {
+	route.Version = v.version
+	v.ServeMux.HandleRoute(route, handle)
+}
+
+// HeaderSelectVersionFunc returns a SelectVersionFunc that reads the version from the given
+// request header, VersionHeader if empty.
+func HeaderSelectVersionFunc(header string) SelectVersionFunc {
+	if header == "" {
+		header = VersionHeader
+	}
+	return func(req *http.Request) string {
+		return strings.TrimSpace(req.Header.Get(header))
+	}
+}
+
+// MediaTypeSelectVersionFunc returns a SelectVersionFunc that reads the version from the
+// "version" parameter of the media types listed in the Accept header or if there is none of the
+// request Content-Type header, e.g. "application/vnd.goa.bottle+json; version=2".
+func MediaTypeSelectVersionFunc() SelectVersionFunc {
+	return func(req *http.Request) string {
+		for _, h := range []string{"Accept", "Content-Type"} {
+			for _, mt := range strings.Split(req.Header.Get(h), ",") {
+				if _, params, err := mime.ParseMediaType(strings.TrimSpace(mt)); err == nil {
+					if v := params["version"]; v != "" {
+						return v
+					}
+				}
+			}
+		}
+		return ""
+	}
+}
+
+// PathSelectVersionFunc returns a SelectVersionFunc that reads the version from the request path.
+// pattern is the path prefix that contains the version, ":version" marks the position of the
+// version, e.g. "/v:version" or "/api/:version". The routes must include the prefix.
+func PathSelectVersionFunc(pattern string) (SelectVersionFunc, error) {
+	parts := strings.Split(pattern, ":version")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf(`version path pattern %#v must contain ":version" once`, pattern)
+	}
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(parts[0]) + "([^/]+?)" + regexp.QuoteMeta(parts[1]) + "(?:/|$)")
+	return func(req *http.Request) string {
+		if m := re.FindStringSubmatch(req.URL.Path); m != nil {
+			return m[1]
+		}
+		return ""
+	}, nil
+}
+
+// CombineSelectVersionFunc returns a SelectVersionFunc that returns the first version computed by
+// the given functions that is not empty.
+func CombineSelectVersionFunc(funcs ...SelectVersionFunc) SelectVersionFunc {
+	return func(req *http.Request) string {
+		for _, f := range funcs {
+			if v := f(req); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+}
+
+// VersionedMux returns the mux that handles the requests that target the given API version. It
+// returns the service mux if version is empty. Otherwise if the service mux is not a VersionMux
+// VersionedMux makes the service use a VersionMux that reads the version from the VersionHeader
+// header or from the media type "version" parameter, see UseMux. The version muxes are tree muxes
+// if the service mux was a tree mux. This method is mainly intended for use by the generated
+// code.
+func (service *Service) VersionedMux(version string) ServeMux {
+	if version == "" {
+		return service.Mux
+	}
+	vm, ok := service.Mux.(*VersionMux)
+	if !ok {
+		vm = NewVersionMux(CombineSelectVersionFunc(HeaderSelectVersionFunc(VersionHeader), MediaTypeSelectVersionFunc()))
+		if _, ok := service.Mux.(*treeMux); ok {
+			vm.NewMux = NewTreeMux
+		}
+		service.UseMux(vm)
+	}
+	return vm.Version(version)
+}
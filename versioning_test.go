@@ -0,0 +1,130 @@
+package goa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("VersionMux", func() {
+	var service *goa.Service
+	var served string
+
+	mount := func(version, action string) {
+		ctrl := service.NewController("bottle")
+		h := ctrl.MuxHandler(action, func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			served = action
+			rw.WriteHeader(200)
+			return nil
+		}, nil)
+		service.VersionedMux(version).HandleRoute(ctrl.Route("GET", "/bottles", action, nil), h)
+	}
+
+	serve := func(header, value string) *httptest.ResponseRecorder {
+		served = ""
+		req, err := http.NewRequest("GET", "/bottles", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rw := httptest.NewRecorder()
+		service.Mux.ServeHTTP(rw, req)
+		return rw
+	}
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		mount("", "unversioned")
+		mount("1", "v1")
+		mount("2", "v2")
+	})
+
+	It("switches the service to a version mux", func() {
+		Ω(service.Mux).Should(BeAssignableToTypeOf(&goa.VersionMux{}))
+		Ω(service.Mux.(*goa.VersionMux).Versions()).Should(Equal([]string{"1", "2"}))
+	})
+
+	It("dispatches using the version header", func() {
+		serve(goa.VersionHeader, "2")
+		Ω(served).Should(Equal("v2"))
+		serve(goa.VersionHeader, "1")
+		Ω(served).Should(Equal("v1"))
+	})
+
+	It("dispatches using the media type version parameter", func() {
+		serve("Accept", "application/vnd.goa.bottle+json; version=2")
+		Ω(served).Should(Equal("v2"))
+	})
+
+	It("uses the shared mux for unversioned and unknown versions", func() {
+		serve("", "")
+		Ω(served).Should(Equal("unversioned"))
+		serve(goa.VersionHeader, "3")
+		Ω(served).Should(Equal("unversioned"))
+	})
+
+	It("falls back to the shared mux for routes that are not versioned", func() {
+		service.Mux.Handle("GET", "/health", func(rw http.ResponseWriter, req *http.Request, _ url.Values) {
+			served = "health"
+		})
+		req, err := http.NewRequest("GET", "/health", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.Header.Set(goa.VersionHeader, "2")
+		service.Mux.ServeHTTP(httptest.NewRecorder(), req)
+		Ω(served).Should(Equal("health"))
+	})
+
+	It("handles requests that match no route with the service error handler", func() {
+		req, err := http.NewRequest("GET", "/cellars", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.Header.Set(goa.VersionHeader, "2")
+		rw := httptest.NewRecorder()
+		service.Mux.ServeHTTP(rw, req)
+		Ω(rw.Code).Should(Equal(404))
+	})
+
+	It("records the route versions", func() {
+		routes := service.Routes()
+		Ω(routes).Should(HaveLen(3))
+		versions := []string{routes[0].Version, routes[1].Version, routes[2].Version}
+		Ω(versions).Should(ConsistOf("", "1", "2"))
+	})
+
+	Context("with a default version", func() {
+		BeforeEach(func() {
+			service.Mux.(*goa.VersionMux).DefaultVersion = "1"
+		})
+
+		It("uses it for requests that do not target a version", func() {
+			serve("", "")
+			Ω(served).Should(Equal("v1"))
+		})
+	})
+})
+
+var _ = Describe("PathSelectVersionFunc", func() {
+	It("extracts the version from the path", func() {
+		f, err := goa.PathSelectVersionFunc("/api/v:version")
+		Ω(err).ShouldNot(HaveOccurred())
+		for path, version := range map[string]string{
+			"/api/v2/bottles": "2",
+			"/api/v1.1":       "1.1",
+			"/api/bottles":    "",
+			"/bottles/api/v2": "",
+		} {
+			req, err := http.NewRequest("GET", path, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(f(req)).Should(Equal(version), path)
+		}
+	})
+
+	It("requires the version placeholder", func() {
+		_, err := goa.PathSelectVersionFunc("/api")
+		Ω(err).Should(HaveOccurred())
+	})
+})